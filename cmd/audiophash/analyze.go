@@ -0,0 +1,138 @@
+package audiophash
+
+import (
+	"fmt"
+
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// Analysis is the result of running the hashing pipeline on one input.
+type Analysis struct {
+	Hash              string          // hex pHash, as returned by AudioPHashBytes: HashHexLen characters per word
+	Feature           []float64       // aggregated, log-scaled feature vector the hash was computed from
+	Frames            [][]float64     // per-frame spectra before aggregation (only set by AnalyzeFrames)
+	NumBins           int             // Config.NumBins the feature was computed with, per hash word
+	Threshold         float64         // value feature bins were compared against (median by default)
+	Input             audio.AudioInfo // decoded input format and length (zero for AnalyzeSpectrogram); Bits is 0 unless the decoder reports it
+	Checksum          uint64          // audio.SampleChecksum of the decoded mono samples before resampling: equal means identical PCM
+	MonoCompatibility float64         // audio.MonoCompatibility of the decoded channels: share of stereo energy left after a mono sum (1 = mono-safe, 0 = cancels; 0 for AnalyzeSpectrogram)
+	ClippedFraction   float64         // audio.DetectClipping fraction of the decoded samples; above audio.ClipWarnFraction the hash may be unreliable
+	Margins           []float64       // per-bit confidence in [0, 1]: Margins[i] is bit HashBits-1-i's distance from the threshold (see hash.AudioPHashWithMargins)
+}
+
+// WithNumBins returns a copy of a with the feature resampled to n bins (see
+// features.ResampleFeature) and the hash recomputed from it, so analyses made with
+// different NumBins can be compared. A MultiResolution analysis is resampled word by
+// word. Tie dither and stereo bits are not reapplied. Frames are dropped. Returns a
+// unchanged if it already has n bins.
+func (a *Analysis) WithNumBins(n int) *Analysis {
+	if a.NumBins == n {
+		return a
+	}
+	out := &Analysis{
+		NumBins:           n,
+		Input:             a.Input,
+		Checksum:          a.Checksum,
+		MonoCompatibility: a.MonoCompatibility,
+		ClippedFraction:   a.ClippedFraction,
+	}
+	words := max(1, len(a.Hash)/hash.HashHexLen)
+	for w := 0; w < words; w++ {
+		f := features.ResampleFeature(a.Feature[w*len(a.Feature)/words:(w+1)*len(a.Feature)/words], n)
+		h, margins := hash.AudioPHashWithMargins(f, hash.Options{})
+		if w == 0 {
+			out.Threshold = hash.Threshold(f, hash.Options{})
+		}
+		out.Hash += h
+		out.Feature = append(out.Feature, f...)
+		out.Margins = append(out.Margins, margins...)
+	}
+	return out
+}
+
+// Analyze is like AudioPHashBytes but also returns the feature vector, for
+// finer ranking with features.Distance / features.CosineSimilarity.
+func Analyze(b []byte, cfg *config.Config, fileformat string) (*Analysis, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.Analyze(b, fileformat)
+}
+
+// HashFromSpectrogram hashes a precomputed magnitude spectrogram, skipping decode,
+// framing and FFT; cfg follows the AudioPHashBytes conventions. See
+// Pipeline.AnalyzeSpectrogram.
+func HashFromSpectrogram(frameMags [][]float64, cfg *config.Config) (string, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return "", err
+	}
+	a, err := p.AnalyzeSpectrogram(frameMags)
+	if err != nil {
+		return "", err
+	}
+	return a.Hash, nil
+}
+
+// AnalyzeSpectrogram runs bin selection, aggregation, scaling and hashing on a
+// precomputed magnitude spectrogram, one row per frame. Each row must hold the
+// positive-frequency magnitudes of an FFTSize-point FFT at the configured sample
+// rate, bin k at k*SampleRate/FFTSize Hz, as fft.ComputeMagnitude returns them; a
+// trailing Nyquist bin (FFTSize/2+1 bins, as many STFT tools emit) is ignored.
+// Magnitudes are used as given, so the result matches AudioPHashBytes only for
+// spectra of peak-normalized audio windowed like Config.Window (WindowGainCompensation
+// divides them by that window's coherent gain). With UsePowerSpectrum the rows must
+// be power spectra (squared magnitudes, fft.ComputePower). StereoBits, if set,
+// are left zero since a spectrogram carries no channel information. Rows of another
+// length, and MultiResolution configs, wrap ErrInvalidConfig; an empty spectrogram
+// returns ErrEmptyInput. With AlgorithmMelDCT the rows feed the mel filterbank
+// instead of bin selection.
+func (p *Pipeline) AnalyzeSpectrogram(frameMags [][]float64) (*Analysis, error) {
+	if len(frameMags) == 0 {
+		return nil, ErrEmptyInput
+	}
+	if len(p.multi) > 0 {
+		return nil, fmt.Errorf("%w: MultiResolution needs samples, not a single spectrogram", ErrInvalidConfig)
+	}
+	bins := p.cfg.FFTSize / 2
+	selected := make([][]float64, len(frameMags))
+	for i, row := range frameMags {
+		if len(row) != bins && len(row) != bins+1 {
+			return nil, fmt.Errorf("%w: spectrogram frame %d has %d bins, fftSize %d needs %d", ErrInvalidConfig, i, len(row), p.cfg.FFTSize, bins)
+		}
+		row = row[:bins]
+		if p.gainComp != 1 {
+			row = append([]float64(nil), row...)
+			p.compensateGain(row)
+		}
+		row = p.mask(row)
+		if p.mel != nil {
+			selected[i] = row
+			continue
+		}
+		selected[i] = p.selectBins(row)
+		if selected[i] == nil {
+			return nil, fmt.Errorf("%w: spectrogram frame %d: no bins selected", ErrInvalidConfig, i)
+		}
+	}
+	if p.mel != nil {
+		return p.analyzeMelDCT(selected, 0, false)
+	}
+	return p.analyzeSpectra(selected, 0, false)
+}
@@ -0,0 +1,110 @@
+package audiophash
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+// BatchItem is one input to HashBatch. Set Load rather than Data for large batches:
+// it is called by the worker that hashes the item, so only in-flight inputs are
+// held in memory.
+type BatchItem struct {
+	ID     string                 // caller's key, echoed in the result
+	Format string                 // AudioPHashBytes file format
+	Data   []byte                 // input bytes, used when Load is nil
+	Load   func() ([]byte, error) // reads the input bytes on demand
+}
+
+// BatchResult is the outcome of hashing one BatchItem.
+type BatchResult struct {
+	ID   string
+	Hash string
+	Err  error // load or hashing error; Hash is empty when set
+}
+
+// BatchOptions bounds the concurrency and memory of HashBatch.
+type BatchOptions struct {
+	Workers    int // inputs hashed concurrently (0 -> runtime.GOMAXPROCS(0))
+	QueueDepth int // items queued for the workers, and results waiting to be read (0 -> 2*Workers)
+}
+
+// HashBatch hashes a stream of inputs; cfg follows the AudioPHashBytes conventions.
+// See Pipeline.HashBatch.
+func HashBatch(items <-chan BatchItem, cfg *config.Config, opts BatchOptions) (<-chan BatchResult, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.HashBatch(items, opts)
+}
+
+// HashBatch hashes every item received from items with opts.Workers goroutines and
+// sends one BatchResult per item, in completion order, on the returned channel,
+// which is closed after the last result once items is closed.
+//
+// A producer goroutine moves items into a work queue of opts.QueueDepth; when the
+// queue and the result buffer are full it stops receiving, so the caller's sender
+// blocks instead of the batch buffering its input. Memory therefore stays at about
+// Workers + 2*QueueDepth items however many are fed. The caller must keep reading
+// results until the channel is closed, or the workers stall.
+func (p *Pipeline) HashBatch(items <-chan BatchItem, opts BatchOptions) (<-chan BatchResult, error) {
+	if opts.Workers < 0 || opts.QueueDepth < 0 {
+		return nil, fmt.Errorf("%w: batch workers and queue depth must be >= 0 (got %d, %d)", ErrInvalidConfig, opts.Workers, opts.QueueDepth)
+	}
+	if opts.Workers == 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	if opts.QueueDepth == 0 {
+		opts.QueueDepth = 2 * opts.Workers
+	}
+
+	work := make(chan BatchItem, opts.QueueDepth)
+	results := make(chan BatchResult, opts.QueueDepth)
+	go func() {
+		for it := range items {
+			work <- it
+		}
+		close(work)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for it := range work {
+				results <- p.hashItem(it)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results, nil
+}
+
+// hashItem loads and hashes one batch item.
+func (p *Pipeline) hashItem(it BatchItem) BatchResult {
+	b := it.Data
+	if it.Load != nil {
+		var err error
+		if b, err = it.Load(); err != nil {
+			return BatchResult{ID: it.ID, Err: fmt.Errorf("load: %w", err)}
+		}
+	}
+	h, err := p.HashBytes(b, it.Format)
+	if err != nil {
+		return BatchResult{ID: it.ID, Err: err}
+	}
+	return BatchResult{ID: it.ID, Hash: h}
+}
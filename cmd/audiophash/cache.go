@@ -0,0 +1,105 @@
+package audiophash
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// CachedHasher wraps a Pipeline with an in-memory LRU cache of hashes, keyed by the
+// FNV-64a digest and length of the input bytes together with the format. Repeat
+// inputs return the cached hash without decoding. Errors are not cached.
+//
+// The content key is not cryptographic: two different inputs of the same length and
+// format that collide on FNV-64a would share a hash. Re-encodings of the same audio
+// (another container, a different header) miss; Analysis.Checksum identifies those
+// after decoding. A CachedHasher is safe for
+// concurrent use; concurrent misses on the same input may each compute the hash.
+type CachedHasher struct {
+	p    *Pipeline
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // front = most recently used; values are *cacheEntry
+	entries map[cacheKey]*list.Element
+	hits    int
+	misses  int
+}
+
+type cacheKey struct {
+	sum    uint64
+	n      int
+	format string
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	hash string
+}
+
+// NewCachedHasher returns a CachedHasher holding at most size hashes; the least
+// recently used entry is evicted when it is full. size must be > 0.
+func (p *Pipeline) NewCachedHasher(size int) (*CachedHasher, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: cache size must be > 0 (got %d)", ErrInvalidConfig, size)
+	}
+	return &CachedHasher{
+		p:       p,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[cacheKey]*list.Element, size),
+	}, nil
+}
+
+// HashBytes returns the perceptual hash of b like Pipeline.HashBytes, from the cache
+// when the same bytes and format were hashed before.
+func (c *CachedHasher) HashBytes(b []byte, fileformat string) (string, error) {
+	f := fnv.New64a()
+	f.Write(b)
+	key := cacheKey{sum: f.Sum64(), n: len(b), format: fileformat}
+
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		c.hits++
+		h := el.Value.(*cacheEntry).hash
+		c.mu.Unlock()
+		return h, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	h, err := c.p.HashBytes(b, fileformat)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return h, nil
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, hash: h})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return h, nil
+}
+
+// Len returns the number of cached hashes.
+func (c *CachedHasher) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *CachedHasher) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
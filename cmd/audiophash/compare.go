@@ -0,0 +1,177 @@
+package audiophash
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// Compare hashes a and b concurrently and returns their Hamming distance; cfg
+// follows the AudioPHashBytes conventions. See Pipeline.Compare.
+func Compare(a []byte, formatA string, b []byte, formatB string, cfg *config.Config) (int, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return 0, err
+	}
+	return p.Compare(a, formatA, b, formatB)
+}
+
+// Compare hashes a and b in two goroutines, so a pair of long files takes about as
+// long as the slower one, and returns the Hamming distance between the hashes.
+// An error names the input that failed ("input a: ..." or "input b: ...") and still
+// wraps the sentinel errors; if both fail, a's error is returned.
+func (p *Pipeline) Compare(a []byte, formatA string, b []byte, formatB string) (int, error) {
+	aa, ab, err := analyzePair(
+		func() (*Analysis, error) { return p.Analyze(a, formatA) },
+		func() (*Analysis, error) { return p.Analyze(b, formatB) },
+	)
+	if err != nil {
+		return 0, err
+	}
+	return analysisDistance(aa, ab)
+}
+
+// analyzePair runs fa and fb concurrently and waits for both. Errors are prefixed
+// with the input they came from; a's error takes precedence.
+func analyzePair[T any](fa, fb func() (T, error)) (T, T, error) {
+	var (
+		wg   sync.WaitGroup
+		ab   T
+		errB error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ab, errB = fb()
+	}()
+	aa, errA := fa()
+	wg.Wait()
+	var zero T
+	if errA != nil {
+		return zero, zero, fmt.Errorf("input a: %w", errA)
+	}
+	if errB != nil {
+		return zero, zero, fmt.Errorf("input b: %w", errB)
+	}
+	return aa, ab, nil
+}
+
+// analysisDistance returns the Hamming distance between two analyses' hashes.
+func analysisDistance(aa, ab *Analysis) (int, error) {
+	ha, err := hash.FromHex(aa.Hash)
+	if err != nil {
+		return 0, err
+	}
+	hb, err := hash.FromHex(ab.Hash)
+	if err != nil {
+		return 0, err
+	}
+	return ha.Distance(hb), nil
+}
+
+// CompareLevelMatched hashes a and b with b's level matched to a's and returns the
+// Hamming distance; cfg follows the AudioPHashBytes conventions. See
+// Pipeline.CompareLevelMatched.
+func CompareLevelMatched(a []byte, formatA string, b []byte, formatB string, cfg *config.Config) (int, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return 0, err
+	}
+	return p.CompareLevelMatched(a, formatA, b, formatB)
+}
+
+// CompareLevelMatched is for "same song, different master volume" comparisons. It
+// estimates the gain between the two inputs from their aggregated spectra (see
+// features.GainRatio) and applies it to b's feature before scaling and hashing, so a
+// constant gain offset cannot flip borderline bits. Per-file normalization
+// (Normalize "rms" or "peak") cannot do this when the files differ in anything but
+// level: a click or an extra loud passage in one file moves its peak and RMS, while
+// the per-bin median ratio stays put.
+//
+// With the default median threshold the hash is already gain invariant, since
+// log(1+g*x) keeps the bin order; level matching matters for gain-sensitive
+// settings such as ThresholdTrim and TieDither. MultiResolution and AlgorithmMelDCT
+// are rejected with ErrInvalidConfig.
+func (p *Pipeline) CompareLevelMatched(a []byte, formatA string, b []byte, formatB string) (int, error) {
+	aa, ab, err := p.AnalyzeLevelMatched(a, formatA, b, formatB)
+	if err != nil {
+		return 0, err
+	}
+	return analysisDistance(aa, ab)
+}
+
+// AnalyzeLevelMatched returns the level-matched analyses CompareLevelMatched
+// compares, with Input and Checksum filled as by Analyze; b's Feature is the one
+// scaled to a's level. Like Compare it works on both inputs concurrently and names
+// the one that failed.
+func (p *Pipeline) AnalyzeLevelMatched(a []byte, formatA string, b []byte, formatB string) (*Analysis, *Analysis, error) {
+	if len(p.multi) > 0 || p.mel != nil {
+		return nil, nil, fmt.Errorf("%w: level matching needs a single-resolution spectrum algorithm", ErrInvalidConfig)
+	}
+	la, lb, err := analyzePair(
+		func() (*linearAnalysis, error) { return p.analyzeLinear(a, formatA) },
+		func() (*linearAnalysis, error) { return p.analyzeLinear(b, formatB) },
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	g := features.GainRatio(la.feature, lb.feature)
+	for i := range lb.feature {
+		lb.feature[i] *= g
+	}
+	return analyzePair(la.finish(p), lb.finish(p))
+}
+
+// linearAnalysis is one input taken up to the linear aggregated feature, before
+// scaling and hashing.
+type linearAnalysis struct {
+	d       *decoded
+	feature []float64
+	spectra [][]float64
+}
+
+// analyzeLinear decodes one input and aggregates its spectra without scaling them.
+func (p *Pipeline) analyzeLinear(b []byte, fileformat string) (*linearAnalysis, error) {
+	d, err := p.decode(b, fileformat)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := p.frameSpectra(d.samples, p.normalizeMode())
+	if err != nil {
+		return nil, err
+	}
+	feature, err := p.aggregate(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &linearAnalysis{d: d, feature: feature, spectra: spec}, nil
+}
+
+// finish returns a function that scales and hashes l's feature and fills the
+// Analysis fields Analyze fills from the decode.
+func (l *linearAnalysis) finish(p *Pipeline) func() (*Analysis, error) {
+	return func() (*Analysis, error) {
+		a, err := p.hashAggregate(l.feature, l.spectra, l.d.stereoCode, false)
+		if err != nil {
+			return nil, err
+		}
+		a.Input, a.Checksum = l.d.info, l.d.checksum
+		a.MonoCompatibility, a.ClippedFraction = l.d.monoCompat, l.d.clipped
+		return a, nil
+	}
+}
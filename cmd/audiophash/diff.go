@@ -0,0 +1,74 @@
+package audiophash
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// BinDiff compares one feature bin of two analyses.
+type BinDiff struct {
+	Bin     int     // feature bin index (hash bit HashBits-1-Bin)
+	A, B    float64 // feature values
+	BitA    bool    // hash bit of A
+	BitB    bool    // hash bit of B
+	Differs bool    // BitA != BitB
+}
+
+// FeatureDiff is a per-bin breakdown of why two analyses hash differently, e.g. for
+// a heatmap of the frequency regions driving the Hamming distance.
+type FeatureDiff struct {
+	ThresholdA float64   // threshold A's bins were compared against
+	ThresholdB float64   // threshold B's bins were compared against
+	Bins       []BinDiff // one entry per hashed bin (at most HashBits)
+	Distance   int       // number of bins with differing bits
+}
+
+// DiffAnalyses returns the per-bin feature diff of a and b. Bits are read from the
+// hashes themselves, so with StereoBits the lowest bins reflect the stereo code.
+// The analyses must have the same NumBins and one-word hashes: MultiResolution
+// analyses wrap ErrInvalidConfig.
+func DiffAnalyses(a, b *Analysis) (*FeatureDiff, error) {
+	if a == nil || b == nil {
+		return nil, errors.New("diff: nil analysis")
+	}
+	if len(a.Hash) != hash.HashHexLen || len(b.Hash) != hash.HashHexLen {
+		return nil, fmt.Errorf("%w: diff needs one-word hashes, got %d and %d hex digits (MultiResolution?)", ErrInvalidConfig, len(a.Hash), len(b.Hash))
+	}
+	if a.NumBins != b.NumBins || len(a.Feature) != len(b.Feature) {
+		return nil, fmt.Errorf("diff: %d vs %d bins: %w", a.NumBins, b.NumBins, features.ErrLengthMismatch)
+	}
+	ha, err := hash.HexToUint64(a.Hash)
+	if err != nil {
+		return nil, err
+	}
+	hb, err := hash.HexToUint64(b.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	n := min(len(a.Feature), hash.HashBits)
+	d := &FeatureDiff{
+		ThresholdA: a.Threshold,
+		ThresholdB: b.Threshold,
+		Bins:       make([]BinDiff, n),
+	}
+	for i := 0; i < n; i++ {
+		mask := uint64(1) << uint(hash.HashBits-1-i)
+		bd := BinDiff{
+			Bin:  i,
+			A:    a.Feature[i],
+			B:    b.Feature[i],
+			BitA: ha&mask != 0,
+			BitB: hb&mask != 0,
+		}
+		bd.Differs = bd.BitA != bd.BitB
+		if bd.Differs {
+			d.Distance++
+		}
+		d.Bins[i] = bd
+	}
+	return d, nil
+}
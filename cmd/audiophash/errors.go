@@ -0,0 +1,29 @@
+package audiophash
+
+import (
+	"errors"
+
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+// Sentinel errors returned (wrapped) by the hashing API; match them with errors.Is.
+//
+// ErrEmptyInput, ErrUnsupportedFormat, ErrAudioTooShort, ErrInvalidConfig,
+// ErrDecodeFailed, ErrSilentAudio, ErrNoValidFrames, ErrClippedAudio,
+// ErrInvalidFingerprint and ErrIncompatibleFingerprints all describe bad caller
+// input. Any other error is an internal failure.
+var (
+	ErrEmptyInput        = errors.New("input bytes empty")
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	ErrAudioTooShort     = errors.New("audio too short")
+	ErrDecodeFailed      = errors.New("decode failed")
+	ErrSilentAudio       = errors.New("audio is silent")
+	ErrNoValidFrames     = errors.New("no valid frames")
+	ErrClippedAudio      = errors.New("audio is clipped")
+
+	ErrInvalidFingerprint       = errors.New("invalid fingerprint")
+	ErrIncompatibleFingerprints = errors.New("fingerprints have incompatible parameters")
+
+	// ErrInvalidConfig is config.ErrInvalidConfig, re-exported for convenience.
+	ErrInvalidConfig = config.ErrInvalidConfig
+)
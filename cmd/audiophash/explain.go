@@ -0,0 +1,58 @@
+package audiophash
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+)
+
+// BinPeak is one dominant feature bin of an analysis.
+type BinPeak struct {
+	Bin   int     // feature bin index
+	Hz    float64 // centre frequency of the bin, see config.Config.FeatureBinHz
+	Value float64 // scaled feature value (Analysis.Feature[Bin])
+}
+
+// TopBins returns the k feature bins of a with the highest values, loudest first,
+// mapped to frequencies with cfg, which must be the config a was computed with; cfg
+// follows the AudioPHashBytes conventions. See Pipeline.TopBins.
+func TopBins(a *Analysis, cfg *config.Config, k int) ([]BinPeak, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.TopBins(a, k)
+}
+
+// TopBins returns the k feature bins of a with the highest values, loudest first,
+// e.g. to explain that a fingerprint is dominated by energy around 1kHz and 3kHz.
+// Feature scaling is monotonic, so the order matches the aggregated magnitudes.
+// a must come from this pipeline's config (same NumBins); k is clamped to NumBins.
+func (p *Pipeline) TopBins(a *Analysis, k int) ([]BinPeak, error) {
+	if a == nil {
+		return nil, errors.New("top bins: nil analysis")
+	}
+	if len(p.multi) > 0 {
+		return nil, fmt.Errorf("%w: top bins: MultiResolution features join several frame sizes", ErrInvalidConfig)
+	}
+	if p.mel != nil {
+		return nil, fmt.Errorf("%w: top bins: mel-dct features are DCT coefficients, not frequency bins", ErrInvalidConfig)
+	}
+	if a.NumBins != p.cfg.NumBins {
+		return nil, fmt.Errorf("top bins: analysis has %d bins, config %d: %w", a.NumBins, p.cfg.NumBins, features.ErrLengthMismatch)
+	}
+	idx := features.TopK(a.Feature, k)
+	peaks := make([]BinPeak, len(idx))
+	for j, i := range idx {
+		peaks[j] = BinPeak{Bin: i, Hz: p.cfg.FeatureBinHz(i), Value: a.Feature[i]}
+	}
+	return peaks, nil
+}
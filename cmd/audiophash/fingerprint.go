@@ -0,0 +1,140 @@
+package audiophash
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// Fingerprint is a hash bundled with the parameters it was computed with, so a
+// stored value is self-describing: two fingerprints are only compared when those
+// parameters match (see Compatible). It marshals to and from JSON with the field
+// names below.
+//
+// The main framing parameters are recorded as readable fields; every other option
+// that changes the hash (Window, band layout, Normalize, ...) is covered by
+// ConfigDigest.
+type Fingerprint struct {
+	Hash         string  `json:"hash"`       // hex pHash, as returned by AudioPHashBytes
+	Algo         string  `json:"algo"`       // Config.Algorithm
+	SampleRate   int     `json:"sampleRate"` // rate the input was resampled to: Config.CanonicalRate if set, else Config.SampleRate
+	FrameSize    int     `json:"frameSize"`
+	Hop          int     `json:"hop"`
+	NumBins      int     `json:"numBins"`
+	ConfigDigest string  `json:"configDigest"` // config.Config.Digest of the pipeline's config
+	Duration     float64 `json:"duration"`     // input length in seconds (metadata, not compared)
+}
+
+// AudioPHashBytesFingerprint is like AudioPHashBytes but returns a Fingerprint; cfg
+// follows the AudioPHashBytes conventions. See Pipeline.Fingerprint.
+func AudioPHashBytesFingerprint(b []byte, cfg *config.Config, fileformat string) (Fingerprint, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return p.Fingerprint(b, fileformat)
+}
+
+// Fingerprint hashes b like HashBytes and records the pipeline's validated
+// parameters and the input's duration alongside the hash. Raw PCM, which carries
+// no sample rate, is timed at Config.SampleRate as given (before CanonicalRate).
+func (p *Pipeline) Fingerprint(b []byte, fileformat string) (Fingerprint, error) {
+	a, err := p.Analyze(b, fileformat)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	digest, err := p.cfg.Digest()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	duration := a.Input.Duration().Seconds()
+	if a.Input.SampleRate <= 0 {
+		duration = float64(a.Input.DurationSamples) / float64(p.inputRate)
+	}
+	return Fingerprint{
+		Hash:         a.Hash,
+		Algo:         p.cfg.Algorithm,
+		SampleRate:   p.cfg.SampleRate,
+		FrameSize:    p.cfg.FrameSize,
+		Hop:          p.cfg.Hop,
+		NumBins:      p.cfg.NumBins,
+		ConfigDigest: digest,
+		Duration:     duration,
+	}, nil
+}
+
+// ParseFingerprint decodes a Fingerprint from JSON and checks that its hash is valid
+// hex and its parameters are set, so a corrupt record fails here rather than at
+// comparison time. Errors wrap ErrInvalidFingerprint.
+func ParseFingerprint(data []byte) (Fingerprint, error) {
+	var f Fingerprint
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Fingerprint{}, fmt.Errorf("%w: %w", ErrInvalidFingerprint, err)
+	}
+	if _, err := hash.FromHex(f.Hash); err != nil {
+		return Fingerprint{}, fmt.Errorf("%w: hash: %w", ErrInvalidFingerprint, err)
+	}
+	if f.Algo == "" || f.SampleRate <= 0 || f.FrameSize <= 0 || f.Hop <= 0 || f.NumBins <= 0 || f.ConfigDigest == "" {
+		return Fingerprint{}, fmt.Errorf("%w: missing parameters (algo %q, sampleRate %d, frameSize %d, hop %d, numBins %d, configDigest %q)",
+			ErrInvalidFingerprint, f.Algo, f.SampleRate, f.FrameSize, f.Hop, f.NumBins, f.ConfigDigest)
+	}
+	return f, nil
+}
+
+// Compatible returns nil if f and g were computed with the same parameters and
+// hash width, and otherwise an error wrapping ErrIncompatibleFingerprints that names
+// the first mismatch; a ConfigDigest mismatch means an option outside the readable
+// fields differs. Duration is not compared.
+func (f Fingerprint) Compatible(g Fingerprint) error {
+	switch {
+	case f.Algo != g.Algo:
+		return fmt.Errorf("%w: algo %q vs %q", ErrIncompatibleFingerprints, f.Algo, g.Algo)
+	case f.SampleRate != g.SampleRate:
+		return fmt.Errorf("%w: sampleRate %d vs %d", ErrIncompatibleFingerprints, f.SampleRate, g.SampleRate)
+	case f.FrameSize != g.FrameSize:
+		return fmt.Errorf("%w: frameSize %d vs %d", ErrIncompatibleFingerprints, f.FrameSize, g.FrameSize)
+	case f.Hop != g.Hop:
+		return fmt.Errorf("%w: hop %d vs %d", ErrIncompatibleFingerprints, f.Hop, g.Hop)
+	case f.NumBins != g.NumBins:
+		return fmt.Errorf("%w: numBins %d vs %d", ErrIncompatibleFingerprints, f.NumBins, g.NumBins)
+	case f.ConfigDigest != g.ConfigDigest:
+		return fmt.Errorf("%w: configDigest %s vs %s", ErrIncompatibleFingerprints, f.ConfigDigest, g.ConfigDigest)
+	case len(f.Hash) != len(g.Hash):
+		return fmt.Errorf("%w: hash length %d vs %d", ErrIncompatibleFingerprints, len(f.Hash), len(g.Hash))
+	}
+	return nil
+}
+
+// Distance returns the Hamming distance between the hashes of f and g, or an error
+// if they are not Compatible or a hash is not valid hex.
+func (f Fingerprint) Distance(g Fingerprint) (int, error) {
+	if err := f.Compatible(g); err != nil {
+		return 0, err
+	}
+	hf, err := hash.FromHex(f.Hash)
+	if err != nil {
+		return 0, fmt.Errorf("%w: hash: %w", ErrInvalidFingerprint, err)
+	}
+	hg, err := hash.FromHex(g.Hash)
+	if err != nil {
+		return 0, fmt.Errorf("%w: hash: %w", ErrInvalidFingerprint, err)
+	}
+	return hf.Distance(hg), nil
+}
+
+// Match reports whether f and g are Compatible and at most maxDistance bits apart.
+func (f Fingerprint) Match(g Fingerprint, maxDistance int) (bool, error) {
+	d, err := f.Distance(g)
+	if err != nil {
+		return false, err
+	}
+	return d <= maxDistance, nil
+}
@@ -0,0 +1,116 @@
+package audiophash
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// Hasher computes a running hash over mono samples that arrive in chunks, e.g. from
+// a live stream. Frames are aggregated online (see hash.OnlineMedianFeature), so
+// memory stays bounded however long the stream runs.
+//
+// Sum may be called at any time without finalizing; it reflects only the audio
+// written so far, normalized by the peak seen so far. A Hasher is not safe for
+// concurrent use.
+type Hasher struct {
+	p       *Pipeline
+	agg     *hash.OnlineMedianFeature
+	pending []float64
+	frame   []float64
+	peak    float64
+	frames  int
+}
+
+// NewHasher returns a Hasher using the pipeline's config. Options that need the
+// whole signal up front (AdaptiveFraming, Loop, FrameGateDB, FrameTrimPercent,
+// StereoBits, MaxClippedFraction, non-peak Normalize), per-channel input (Channel, Speaker), a WAV
+// layout (PlanarWAV), several frame sizes (MultiResolution) or AlgorithmMelDCT are
+// rejected with ErrInvalidConfig.
+func (p *Pipeline) NewHasher() (*Hasher, error) {
+	if err := p.checkStreamable(); err != nil {
+		return nil, err
+	}
+	return &Hasher{
+		p:       p,
+		agg:     hash.NewOnlineMedianFeature(p.cfg.NumBins),
+		pending: make([]float64, 0, 2*p.cfg.FrameSize),
+		frame:   make([]float64, p.cfg.FrameSize),
+	}, nil
+}
+
+// Write adds mono samples at the configured sample rate (CanonicalRate when set). Every complete frame is
+// analyzed immediately; a partial frame waits for the next Write.
+func (h *Hasher) Write(samples []float64) {
+	for _, v := range samples {
+		if v < 0 {
+			v = -v
+		}
+		if v > h.peak {
+			h.peak = v
+		}
+	}
+	h.pending = append(h.pending, samples...)
+
+	frameSize, hop := h.p.cfg.FrameSize, h.p.cfg.Hop
+	off := 0
+	for off+frameSize <= len(h.pending) {
+		for i := range h.frame {
+			h.frame[i] = h.pending[off+i] * h.p.window[i]
+		}
+		mags, _ := h.p.spectrum(h.frame) // FrameSize <= FFTSize, so this cannot fail
+		off += hop
+		if degenerateSpectrum(mags) {
+			continue
+		}
+		h.agg.AddFrame(mags)
+		h.frames++
+	}
+	h.pending = append(h.pending[:0], h.pending[off:]...)
+}
+
+// Frames returns the number of frames analyzed so far; degenerate frames (non-finite
+// magnitudes) are skipped and not counted.
+func (h *Hasher) Frames() int { return h.frames }
+
+// Sum returns the hash of the audio written so far. Magnitudes are linear in the
+// input gain and the median commutes with scaling, so dividing the aggregated
+// feature by the running peak (its square for UsePowerSpectrum, which is quadratic
+// in the gain) equals peak-normalizing the samples first.
+func (h *Hasher) Sum() (string, error) {
+	if h.frames == 0 {
+		return "", fmt.Errorf("%w: no frames produced", ErrAudioTooShort)
+	}
+	feature := h.agg.Feature()
+	if len(feature) == 0 {
+		return "", errors.New("no global feature produced")
+	}
+	if h.peak > 0 {
+		scale := h.peak
+		if h.p.cfg.UsePowerSpectrum {
+			scale *= h.peak
+		}
+		for i := range feature {
+			feature[i] /= scale
+		}
+	}
+	if features.IsSilent(feature) {
+		return "", ErrSilentAudio
+	}
+	h.p.scaleFeature(feature)
+	hashHex, _ := h.p.hashFeature(feature)
+	if hashHex == "" {
+		return "", errors.New("failed to compute pHash")
+	}
+	return hashHex, nil
+}
+
+// Reset discards all audio written so far.
+func (h *Hasher) Reset() {
+	h.agg = hash.NewOnlineMedianFeature(h.p.cfg.NumBins)
+	h.pending = h.pending[:0]
+	h.peak = 0
+	h.frames = 0
+}
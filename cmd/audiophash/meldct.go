@@ -0,0 +1,91 @@
+package audiophash
+
+import (
+	"errors"
+	"math"
+
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// Log-mel 2D DCT hash (config.AlgorithmMelDCT) geometry: the spectrogram is
+// resampled to melDCTGrid time steps by melDCTGrid mel bands, and the hash keeps
+// the melDCTBlock x melDCTBlock lowest DCT coefficients after the first row and
+// column (the DC terms, which carry only overall level and a constant slope).
+const (
+	melDCTGrid  = 32
+	melDCTBlock = 8
+)
+
+// analyzeMelDCT hashes full FFTSize/2-bin frame spectra with the log-mel 2D DCT
+// algorithm: mel band power per frame, log, time axis resampled to the grid, 2D
+// DCT, then the low-order block thresholded at its median like the spectrum
+// feature. Bin selection, aggregation and feature scaling options do not apply.
+// With keepFrames, Analysis.Frames holds the log-mel frames. With UsePowerSpectrum
+// spec already holds power and is not squared again.
+func (p *Pipeline) analyzeMelDCT(spec [][]float64, stereoCode uint64, keepFrames bool) (*Analysis, error) {
+	logMel := make([][]float64, len(spec))
+	silent := true
+	for i, mags := range spec {
+		power := mags
+		if !p.cfg.UsePowerSpectrum {
+			power = make([]float64, len(mags))
+			for b, m := range mags {
+				power[b] = m * m
+			}
+		}
+		bands := features.ApplyFilterbank(power, p.mel)
+		for k, e := range bands {
+			if e >= features.SilenceEpsilon {
+				silent = false
+			}
+			bands[k] = math.Log(e + features.SilenceEpsilon)
+		}
+		logMel[i] = bands
+	}
+	if silent {
+		return nil, ErrSilentAudio
+	}
+
+	// resample each mel band over time onto the fixed grid
+	grid := make([][]float64, melDCTGrid)
+	for t := range grid {
+		grid[t] = make([]float64, melDCTGrid)
+	}
+	track := make([]float64, len(logMel))
+	for k := 0; k < melDCTGrid; k++ {
+		for i, bands := range logMel {
+			track[i] = bands[k]
+		}
+		for t, v := range features.ResampleFeature(track, melDCTGrid) {
+			grid[t][k] = v
+		}
+	}
+
+	coeffs := features.DCT2D(grid)
+	feature := make([]float64, 0, melDCTBlock*melDCTBlock)
+	for u := 1; u <= melDCTBlock; u++ {
+		feature = append(feature, coeffs[u][1:melDCTBlock+1]...)
+	}
+
+	hashHex, margins := p.hashFeature(feature)
+	if hashHex == "" {
+		return nil, errors.New("failed to compute pHash")
+	}
+	hashHex, err := p.embedStereo(hashHex, margins, stereoCode)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Analysis{
+		Hash:      hashHex,
+		Feature:   feature,
+		NumBins:   len(feature),
+		Threshold: hash.Threshold(feature, p.hashOptions()),
+		Margins:   margins,
+	}
+	if keepFrames {
+		a.Frames = logMel
+	}
+	return a, nil
+}
@@ -0,0 +1,687 @@
+package audiophash
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/fft"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// Pipeline is a validated, reusable hashing setup: config is checked once and
+// the window coefficients and FFT plan are built once and shared across calls.
+// A Pipeline is safe for concurrent use.
+type Pipeline struct {
+	cfg       config.Config
+	inputRate int // rate of input that carries none (raw PCM): Config.SampleRate as given, even with CanonicalRate
+	window    []float64
+	plan      *fft.Plan
+	gainComp  float64     // 1/CoherentGain(window) with WindowGainCompensation (squared with UsePowerSpectrum), else 1
+	multi     []*Pipeline // one pipeline per MultiResolution frame size
+	mel       [][]float64 // mel filterbank for AlgorithmMelDCT
+	debug     bool        // trace each step on stderr, set by AUDIOPHASH_DEBUG=1
+}
+
+// NewPipeline validates cfg and precomputes per-config state. With CanonicalRate
+// set, every input is resampled to it and all options are applied at that rate,
+// while cfg.SampleRate remains the rate of raw PCM input (see Config).
+func NewPipeline(cfg config.Config) (*Pipeline, error) {
+	return NewPipelineWith(cfg, config.Limits{})
+}
+
+// NewPipelineWith is NewPipeline with cfg validated under limits (see
+// config.Config.ValidateAndFillWith), for services that accept configs from
+// untrusted callers and want a different size cap.
+func NewPipelineWith(cfg config.Config, limits config.Limits) (*Pipeline, error) {
+	// the caller keeps its slice: later edits to it must not show up in Config
+	cfg.MultiResolution = append([]int(nil), cfg.MultiResolution...)
+	raw := cfg
+	inputRate := cfg.SampleRate
+	if cfg.CanonicalRate > 0 {
+		if inputRate <= 0 {
+			return nil, fmt.Errorf("%w: sample rate must be > 0", ErrInvalidConfig)
+		}
+		cfg.SampleRate = cfg.CanonicalRate
+	}
+	if err := cfg.ValidateAndFillWith(limits); err != nil {
+		return nil, err
+	}
+	var multi []*Pipeline
+	for _, n := range cfg.MultiResolution {
+		sub := raw
+		sub.MultiResolution = nil
+		sub.FrameSize = n
+		sub.Hop = max(1, n*cfg.Hop/cfg.FrameSize)
+		sub.FFTSize = n * (cfg.FFTSize / cfg.FrameSize)
+		sub.NumBins = cfg.NumBins
+		sp, err := NewPipelineWith(sub, limits)
+		if err != nil {
+			return nil, fmt.Errorf("multiResolution frame size %d: %w", n, err)
+		}
+		multi = append(multi, sp)
+	}
+	if len(multi) > 0 {
+		// the sub-pipelines frame and transform; this one only decodes
+		return &Pipeline{cfg: cfg, inputRate: inputRate, multi: multi, debug: debugEnabled()}, nil
+	}
+	window, err := audio.NewWindow(cfg.Window, cfg.FrameSize, cfg.KaiserBeta)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	gainComp := 1.0
+	if cfg.WindowGainCompensation {
+		gainComp = 1 / audio.CoherentGain(window)
+		if cfg.UsePowerSpectrum {
+			gainComp *= gainComp
+		}
+	}
+	var mel [][]float64
+	if cfg.Algorithm == config.AlgorithmMelDCT {
+		mel = features.MelFilterbank(melDCTGrid, cfg.FFTSize, cfg.SampleRate, 0, float64(cfg.SampleRate)/2)
+	}
+	return &Pipeline{
+		cfg:       cfg,
+		inputRate: inputRate,
+		window:    window,
+		plan:      fft.NewPlanWith(cfg.FFTSize, fft.Backend(cfg.FFTBackend)),
+		gainComp:  gainComp,
+		multi:     multi,
+		mel:       mel,
+		debug:     debugEnabled(),
+	}, nil
+}
+
+// debugEnabled reports whether AUDIOPHASH_DEBUG=1 is set. It is read when a Pipeline
+// is built, not on every call.
+func debugEnabled() bool {
+	return os.Getenv("AUDIOPHASH_DEBUG") == "1"
+}
+
+// Config returns the validated config used by the pipeline. SampleRate is as given,
+// even with CanonicalRate set (hashing then runs at CanonicalRate), so
+// NewPipeline(p.Config()) builds an equivalent pipeline.
+func (p *Pipeline) Config() config.Config {
+	c := p.cfg
+	c.SampleRate = p.inputRate
+	return c
+}
+
+// HashBytes computes the perceptual hash of b, see AudioPHashBytes.
+func (p *Pipeline) HashBytes(b []byte, fileformat string) (string, error) {
+	a, err := p.Analyze(b, fileformat)
+	if err != nil {
+		return "", err
+	}
+	return a.Hash, nil
+}
+
+// Analyze runs the full pipeline on b and returns the hash together with the
+// intermediate feature vector it was computed from.
+func (p *Pipeline) Analyze(b []byte, fileformat string) (*Analysis, error) {
+	return p.analyze(b, fileformat, false)
+}
+
+// AnalyzeFrames is like Analyze but also fills Analysis.Frames with the per-frame
+// spectra (the NumBins bins fed to aggregation). This retains one slice per frame.
+func (p *Pipeline) AnalyzeFrames(b []byte, fileformat string) (*Analysis, error) {
+	return p.analyze(b, fileformat, true)
+}
+
+func (p *Pipeline) analyze(b []byte, fileformat string, keepFrames bool) (*Analysis, error) {
+	d, err := p.decode(b, fileformat)
+	if err != nil {
+		return nil, err
+	}
+	a, err := p.analyzeSamples(d.samples, p.normalizeMode(), d.stereoCode, keepFrames)
+	if err != nil {
+		return nil, err
+	}
+	a.Input = d.info
+	a.MonoCompatibility = d.monoCompat
+	a.ClippedFraction = d.clipped
+	a.Checksum = d.checksum
+	return a, nil
+}
+
+// decoded is mono audio resampled to the configured sample rate.
+type decoded struct {
+	samples    []float64
+	sourceRate int             // decoder sample rate (the pipeline's input rate for raw PCM)
+	stereoCode uint64          // stereo correlation code (0 unless StereoBits > 0)
+	info       audio.AudioInfo // format and length of the input before channel selection and resampling
+	checksum   uint64          // audio.SampleChecksum of the mono samples before resampling
+	monoCompat float64         // audio.MonoCompatibility of the decoded channels (1 for mono input)
+	clipped    float64         // audio.DetectClipping fraction of the mono samples before resampling
+}
+
+// decode turns input bytes into mono samples at the configured sample rate.
+func (p *Pipeline) decode(b []byte, fileformat string) (*decoded, error) {
+	debug := p.debug
+
+	localCfg := p.cfg
+	if len(b) == 0 {
+		return nil, ErrEmptyInput
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "[phash] start: bytes=%d format=%q sampleRate(cfg)=%d frameSize=%d hop=%d numBins=%d\n",
+			len(b), fileformat, localCfg.SampleRate, localCfg.FrameSize, localCfg.Hop, localCfg.NumBins)
+	}
+
+	// ---------------------------
+	// Decode -> []float64 samples (mono)
+	// ---------------------------
+	var (
+		samples    []float64
+		sr         int
+		err        error
+		stereoCode uint64
+		info       audio.AudioInfo
+		monoCompat = 1.0
+	)
+
+	// gunzip "<format>.gz", or WAV that starts with the gzip magic instead of "RIFF"
+	b, fileformat, err = audio.MaybeDecompress(b, fileformat)
+	if err != nil {
+		return nil, fmt.Errorf("%w: gzip: %w", ErrDecodeFailed, err)
+	}
+
+	dec, ok := audio.LookupDecoder(fileformat)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, fileformat)
+	}
+	if localCfg.PlanarWAV {
+		if fileformat != "wav" {
+			return nil, fmt.Errorf("%w: planar layout needs wav input, got %s", ErrUnsupportedFormat, fileformat)
+		}
+		dec = audio.WAVDecoder{Planar: true}
+	}
+	if cd, ok := dec.(audio.ChannelDecoder); ok {
+		var channels [][]float64
+		if id, ok := cd.(audio.InfoDecoder); ok {
+			channels, info, err = id.DecodeChannelsInfo(b)
+			sr = info.SampleRate
+		} else {
+			channels, sr, err = cd.DecodeChannels(b)
+			info = audio.AudioInfo{Channels: len(channels), SampleRate: sr}
+			if len(channels) > 0 {
+				info.DurationSamples = len(channels[0])
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrDecodeFailed, fileformat, err)
+		}
+		monoCompat = audio.MonoCompatibility(channels)
+		if localCfg.StereoBits > 0 {
+			corr, mono := audio.ChannelCorrelation(channels)
+			stereoCode = features.QuantizeCorrelation(corr, mono, localCfg.StereoBits)
+			if debug {
+				fmt.Fprintf(os.Stderr, "[phash] stereo: channels=%d corr=%.6f mono=%v code=%d\n", len(channels), corr, mono, stereoCode)
+			}
+		}
+		sel := localCfg.Channel
+		if localCfg.Speaker != "" {
+			if fileformat != "wav" {
+				return nil, fmt.Errorf("%w: speaker selection needs wav input, got %s", ErrUnsupportedFormat, fileformat)
+			}
+			bit, _ := audio.SpeakerBit(localCfg.Speaker) // checked by ValidateAndFill
+			sel, err = info.SpeakerChannel(bit)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrDecodeFailed, fileformat, err)
+			}
+		}
+		samples, err = audio.SelectChannel(channels, sel, audio.DownmixAverage)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrDecodeFailed, fileformat, err)
+		}
+	} else {
+		samples, sr, err = dec.Decode(b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrDecodeFailed, fileformat, err)
+		}
+		info = audio.AudioInfo{Channels: 1, SampleRate: sr, DurationSamples: len(samples)}
+		if localCfg.Speaker != "" {
+			return nil, fmt.Errorf("%w: speaker selection needs wav input, got %s", ErrUnsupportedFormat, fileformat)
+		}
+		if localCfg.Channel > 1 {
+			return nil, fmt.Errorf("%w: %s: %w: channel %d of 1", ErrDecodeFailed, fileformat, audio.ErrChannelOutOfRange, localCfg.Channel)
+		}
+	}
+
+	if debug {
+		fmt.Fprintf(os.Stderr, "[phash] decoded: samples=%d decoder_sr=%d\n", len(samples), sr)
+		// show a tiny sample window
+		if len(samples) > 0 {
+			end := 8
+			if len(samples) < end {
+				end = len(samples)
+			}
+			fmt.Fprintf(os.Stderr, "[phash] first samples: %v\n", samples[:end])
+		}
+	}
+
+	if len(samples) == 0 {
+		// checked before resampling, so the error does not depend on the target rate
+		return nil, fmt.Errorf("%w: %s: no samples", ErrAudioTooShort, fileformat)
+	}
+
+	checksum := audio.SampleChecksum(samples)
+	_, clipped := audio.DetectClipping(samples)
+	if localCfg.MaxClippedFraction > 0 && clipped > localCfg.MaxClippedFraction {
+		return nil, fmt.Errorf("%w: %.3g%% of samples on flat tops (max %.3g%%)", ErrClippedAudio, 100*clipped, 100*localCfg.MaxClippedFraction)
+	}
+
+	// ---------------------------
+	// Resample if needed (decoder returns sr; raw PCM returns sr==0 and is at the
+	// input rate, which differs from the hashing rate only with CanonicalRate)
+	// ---------------------------
+	if sr == 0 {
+		sr = p.inputRate
+	}
+	if sr != localCfg.SampleRate {
+		if debug {
+			fmt.Fprintf(os.Stderr, "[phash] resampling: from=%d to=%d\n", sr, localCfg.SampleRate)
+		}
+		samples, err = audio.ResampleWith(samples, sr, localCfg.SampleRate, localCfg.ResampleTaps)
+		if err != nil {
+			return nil, fmt.Errorf("resample: %w", err)
+		}
+		if debug {
+			fmt.Fprintf(os.Stderr, "[phash] resampled: samples=%d\n", len(samples))
+		}
+	}
+
+	return &decoded{samples: samples, sourceRate: sr, stereoCode: stereoCode, info: info, checksum: checksum, monoCompat: monoCompat, clipped: clipped}, nil
+}
+
+// analyzeSamples hashes mono samples already at the configured sample rate,
+// normalizing them with norm first.
+func (p *Pipeline) analyzeSamples(samples []float64, norm audio.NormalizeMode, stereoCode uint64, keepFrames bool) (*Analysis, error) {
+	if len(p.multi) > 0 {
+		return p.analyzeMulti(samples, norm, stereoCode)
+	}
+	spec, err := p.frameSpectra(samples, norm)
+	if err != nil {
+		return nil, err
+	}
+	if p.mel != nil {
+		return p.analyzeMelDCT(spec, stereoCode, keepFrames)
+	}
+	return p.analyzeSpectra(spec, stereoCode, keepFrames)
+}
+
+// frameSpectra normalizes samples with norm, frames them and returns one spectrum
+// per non-degenerate frame: bin-selected (see spectrum), or the full masked spectrum
+// for AlgorithmMelDCT.
+func (p *Pipeline) frameSpectra(samples []float64, norm audio.NormalizeMode) ([][]float64, error) {
+	debug := p.debug
+
+	localCfg := p.cfg
+	// ---------------------------
+	// Normalize amplitude
+	// ---------------------------
+	samples = audio.NormalizeWith(samples, norm, localCfg.NormalizePercentile)
+	if debug {
+		fmt.Fprintf(os.Stderr, "[phash] normalized: samples=%d\n", len(samples))
+		// small stats
+		minv, maxv, meanv := statsFloatSlice(samples)
+		fmt.Fprintf(os.Stderr, "[phash] sample stats: min=%.6f max=%.6f mean=%.6f\n", minv, maxv, meanv)
+	}
+
+	// ---------------------------
+	// Framing & windowing
+	// ---------------------------
+	var frames [][]float64
+	switch {
+	case localCfg.Loop:
+		frames = audio.FrameCircular(samples, p.window, localCfg.Hop)
+	case localCfg.AdaptiveFraming:
+		onsets := audio.DetectOnsets(samples, localCfg.FrameSize, localCfg.Hop)
+		starts := audio.AdaptiveFrameStarts(len(samples), localCfg.FrameSize, localCfg.Hop, onsets)
+		frames = audio.FrameAt(samples, p.window, starts)
+		if debug {
+			fmt.Fprintf(os.Stderr, "[phash] adaptive framing: onsets=%d\n", len(onsets))
+		}
+	default:
+		frames = audio.FrameWithWindow(samples, p.window, localCfg.Hop)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%w: no frames produced", ErrAudioTooShort)
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "[phash] framing: frames=%d frameSize=%d hop=%d\n", len(frames), localCfg.FrameSize, localCfg.Hop)
+	}
+
+	// ---------------------------
+	// Energy gating (drop near-silent frames)
+	// ---------------------------
+	if localCfg.FrameGateDB > 0 {
+		frames = audio.GateFrames(frames, localCfg.FrameGateDB)
+		if debug {
+			fmt.Fprintf(os.Stderr, "[phash] gating: frames=%d gate=%.1fdB\n", len(frames), localCfg.FrameGateDB)
+		}
+	}
+
+	if p.mel != nil {
+		spec := make([][]float64, 0, len(frames))
+		for i, f := range frames {
+			mags, err := p.transform(f)
+			if err != nil {
+				return nil, fmt.Errorf("frame %d: %w", i, err)
+			}
+			if degenerateSpectrum(mags) {
+				continue
+			}
+			p.compensateGain(mags)
+			spec = append(spec, p.mask(mags))
+		}
+		if len(spec) == 0 {
+			return nil, fmt.Errorf("%w: all %d frames degenerate", ErrNoValidFrames, len(frames))
+		}
+		return spec, nil
+	}
+
+	// ---------------------------
+	// FFT per frame -> magnitude spectra (degenerate frames are skipped)
+	// ---------------------------
+	frameMags := make([][]float64, 0, len(frames))
+	for i, f := range frames {
+		mags, err := p.spectrum(f)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		if degenerateSpectrum(mags) {
+			if debug {
+				fmt.Fprintf(os.Stderr, "[phash] fft: skipping degenerate frame %d\n", i)
+			}
+			continue
+		}
+		frameMags = append(frameMags, mags)
+	}
+	if len(frameMags) == 0 {
+		return nil, fmt.Errorf("%w: all %d frames degenerate", ErrNoValidFrames, len(frames))
+	}
+	if debug && localCfg.HasBand() {
+		lo, hi := localCfg.BandBins()
+		fmt.Fprintf(os.Stderr, "[phash] band: %.0f-%.0fHz bins=[%d,%d) pooled=%d\n", localCfg.MinHz, localCfg.MaxHz, lo, hi, localCfg.NumBins)
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "[phash] fft: computed magnitude spectra for %d frames (bins per frame=%d)\n", len(frameMags), len(frameMags[0]))
+		// print first frame few bins
+		binsToShow := 8
+		if len(frameMags[0]) < binsToShow {
+			binsToShow = len(frameMags[0])
+		}
+		fmt.Fprintf(os.Stderr, "[phash] first frame magnitudes (first %d bins): %v\n", binsToShow, frameMags[0][:binsToShow])
+	}
+	return frameMags, nil
+}
+
+// analyzeSpectra runs the back half of the pipeline on bin-selected frame spectra:
+// median aggregation, feature scaling and hashing.
+func (p *Pipeline) analyzeSpectra(frameMags [][]float64, stereoCode uint64, keepFrames bool) (*Analysis, error) {
+	globalFeature, err := p.aggregate(frameMags)
+	if err != nil {
+		return nil, err
+	}
+	return p.hashAggregate(globalFeature, frameMags, stereoCode, keepFrames)
+}
+
+// aggregate reduces bin-selected frame spectra to the linear (unscaled) feature.
+func (p *Pipeline) aggregate(frameMags [][]float64) ([]float64, error) {
+	localCfg := p.cfg
+	// ---------------------------
+	// Aggregate to global feature vector (use median aggregation for robustness,
+	// optionally over the middle frames by energy)
+	// ---------------------------
+	globalFeature, err := features.AggregateTrimmedByEnergy(frameMags, localCfg.NumBins, localCfg.FrameTrimPercent)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	if len(globalFeature) == 0 {
+		return nil, errors.New("no global feature produced")
+	}
+	if features.IsSilent(globalFeature) {
+		return nil, ErrSilentAudio
+	}
+	return globalFeature, nil
+}
+
+// hashAggregate scales and hashes the linear feature aggregated from frameMags.
+func (p *Pipeline) hashAggregate(globalFeature []float64, frameMags [][]float64, stereoCode uint64, keepFrames bool) (*Analysis, error) {
+	debug := p.debug
+
+	localCfg := p.cfg
+	var frameFeatures [][]float64
+	if keepFrames {
+		frameFeatures = features.ExtractPerFrame(frameMags, len(globalFeature))
+	}
+	if debug {
+		minv, maxv, meanv := statsFloatSlice(globalFeature)
+		med := features.Median(globalFeature)
+		fmt.Fprintf(os.Stderr, "[phash] aggregated feature: len=%d min=%.6f max=%.6f mean=%.6f median=%.6f\n", len(globalFeature), minv, maxv, meanv, med)
+	}
+
+	p.scaleFeature(globalFeature)
+	if debug {
+		minv, maxv, meanv := statsFloatSlice(globalFeature)
+		med := features.Median(globalFeature)
+		fmt.Fprintf(os.Stderr, "[phash] log-scaled feature: len=%d min=%.6f max=%.6f mean=%.6f median=%.6f\n", len(globalFeature), minv, maxv, meanv, med)
+	}
+
+	// ---------------------------
+	// PHash from feature -> HashHexLen-char hex
+	// ---------------------------
+	hashHex, margins := p.hashFeature(globalFeature)
+	if hashHex == "" {
+		return nil, errors.New("failed to compute pHash")
+	}
+
+	hashHex, err := p.embedStereo(hashHex, margins, stereoCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if debug {
+		u, _ := hash.HexToUint64(hashHex)
+		fmt.Fprintf(os.Stderr, "[phash] result: hex=%s uint64=%016x\n", hashHex, u)
+	}
+
+	return &Analysis{
+		Hash:      hashHex,
+		Feature:   globalFeature,
+		Frames:    frameFeatures,
+		NumBins:   localCfg.NumBins,
+		Threshold: hash.Threshold(globalFeature, p.hashOptions()),
+		Margins:   margins,
+	}, nil
+}
+
+// embedStereo replaces the low StereoBits bits of hashHex with the stereo
+// correlation code and sets their margins to 1, since they are not thresholded;
+// it returns hashHex unchanged when StereoBits is 0.
+func (p *Pipeline) embedStereo(hashHex string, margins []float64, stereoCode uint64) (string, error) {
+	if p.cfg.StereoBits == 0 {
+		return hashHex, nil
+	}
+	u, err := hash.HexToUint64(hashHex)
+	if err != nil {
+		return "", err
+	}
+	for j := 0; j < p.cfg.StereoBits && j < len(margins); j++ {
+		margins[len(margins)-1-j] = 1
+	}
+	return hash.FormatHex(hash.EmbedLowBits(u, stereoCode, p.cfg.StereoBits)), nil
+}
+
+// spectrum computes the magnitude (or, with UsePowerSpectrum, power) spectrum of one
+// windowed frame, restricted to the bins the config hashes (see selectBins). A frame
+// longer than FFTSize returns an error wrapping fft.ErrFrameLength.
+func (p *Pipeline) spectrum(frame []float64) ([]float64, error) {
+	mags, err := p.transform(frame)
+	if err != nil {
+		return nil, err
+	}
+	p.compensateGain(mags)
+	return p.selectBins(p.mask(mags)), nil
+}
+
+// transform returns the full FFTSize/2-bin spectrum of one windowed frame: power
+// with UsePowerSpectrum, magnitude otherwise.
+func (p *Pipeline) transform(frame []float64) ([]float64, error) {
+	if p.cfg.UsePowerSpectrum {
+		return p.plan.ComputePower(frame)
+	}
+	return p.plan.Compute(frame)
+}
+
+// degenerateSpectrum reports whether a frame's spectrum cannot be aggregated: nil,
+// empty, or holding a NaN or infinite magnitude (from non-finite input samples).
+// Such frames are skipped rather than failing the whole hash.
+func degenerateSpectrum(mags []float64) bool {
+	if len(mags) == 0 {
+		return true
+	}
+	for _, m := range mags {
+		if math.IsNaN(m) || math.IsInf(m, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// mask applies features.ApplyMasking (ApplyMaskingPower with UsePowerSpectrum) to a
+// full frame spectrum when PsychoacousticMasking is set, and returns mags unchanged
+// otherwise.
+func (p *Pipeline) mask(mags []float64) []float64 {
+	switch {
+	case !p.cfg.PsychoacousticMasking:
+		return mags
+	case p.cfg.UsePowerSpectrum:
+		return features.ApplyMaskingPower(mags, p.cfg.SampleRate, p.cfg.FFTSize)
+	}
+	return features.ApplyMasking(mags, p.cfg.SampleRate, p.cfg.FFTSize)
+}
+
+// compensateGain divides mags in place by the window's coherent gain when
+// WindowGainCompensation is set.
+func (p *Pipeline) compensateGain(mags []float64) {
+	if p.gainComp == 1 {
+		return
+	}
+	for i := range mags {
+		mags[i] *= p.gainComp
+	}
+}
+
+// selectBins restricts an FFTSize/2-bin magnitude spectrum to the bins the config
+// hashes: NumBins log-spaced bands, the Hz band pooled into NumBins sub-bands, or the
+// spectrum with DC dropped unless KeepDCBin is set.
+func (p *Pipeline) selectBins(mags []float64) []float64 {
+	switch {
+	case p.cfg.LowHigh:
+		lo, _ := p.cfg.BandBins()
+		return features.LowHighBands(mags, p.cfg.SampleRate, p.cfg.FFTSize, lo, p.cfg.NumBins)
+	case p.cfg.LogBands && p.cfg.HasBand():
+		maxHz := p.cfg.MaxHz
+		if maxHz == 0 {
+			maxHz = float64(p.cfg.SampleRate) / 2
+		}
+		minHz := p.cfg.MinHz
+		if minHz == 0 {
+			minHz = float64(p.cfg.SampleRate) / float64(p.cfg.FFTSize)
+		}
+		return features.LogFrequencyBandsRange(mags, p.cfg.SampleRate, p.cfg.FFTSize, p.cfg.NumBins, minHz, maxHz)
+	case p.cfg.LogBands:
+		return features.LogFrequencyBands(mags, p.cfg.SampleRate, p.cfg.FFTSize, p.cfg.NumBins)
+	case p.cfg.HasBand():
+		lo, hi := p.cfg.BandBins()
+		return features.PoolBands(mags, lo, hi, p.cfg.NumBins)
+	case !p.cfg.KeepDCBin:
+		// aggregation then takes NumBins bins starting at bin 1
+		return mags[1:]
+	}
+	return mags
+}
+
+// normalizeMode maps Config.Normalize to the audio package mode.
+func (p *Pipeline) normalizeMode() audio.NormalizeMode {
+	switch p.cfg.Normalize {
+	case "rms":
+		return audio.NormalizeRMS
+	case "percentile":
+		return audio.NormalizePercentile
+	}
+	return audio.NormalizePeak
+}
+
+// scaleFeature applies the configured energy normalization, magnitude floor, log
+// scaling and spectral tilt removal in place.
+func (p *Pipeline) scaleFeature(feature []float64) {
+	if p.cfg.NormalizeFeature {
+		features.NormalizeL2(feature)
+	}
+	floor := p.cfg.MagnitudeFloor
+	if p.cfg.UsePowerSpectrum {
+		floor *= floor // the floor is a magnitude; the feature holds power
+	}
+	features.ApplyMagnitudeFloor(feature, floor)
+	switch {
+	case p.cfg.DBScale && p.cfg.UsePowerSpectrum:
+		copy(feature, features.PowerToDB(feature, p.cfg.DBRef))
+	case p.cfg.DBScale:
+		copy(feature, features.ToDB(feature, p.cfg.DBRef))
+	default:
+		features.LogScaleFeatureWith(feature, p.cfg.LogOffset, p.cfg.LogBase)
+	}
+	if p.cfg.RemoveSpectralTilt {
+		features.RemoveTrend(feature, spectralTiltDegree)
+	}
+}
+
+// spectralTiltDegree is the polynomial degree RemoveSpectralTilt fits: a quadratic
+// follows the broad shelving and roll-off of real microphones and codecs while
+// leaving peaks a few bins wide intact.
+const spectralTiltDegree = 2
+
+// hashOptions maps the config to the hash thresholding options.
+func (p *Pipeline) hashOptions() hash.Options {
+	return hash.Options{
+		TieDither:    p.cfg.TieDither,
+		TrimFraction: p.cfg.ThresholdTrim,
+	}
+}
+
+// hashFeature thresholds a scaled feature into a one-word (HashHexLen-char) hash and returns
+// the per-bin margins (see hash.AudioPHashWithMargins).
+func (p *Pipeline) hashFeature(feature []float64) (string, []float64) {
+	return hash.AudioPHashWithMargins(feature, p.hashOptions())
+}
+
+// analyzeMulti runs analyzeSamples at every MultiResolution frame size and
+// concatenates the results: the hash is one HashHexLen word per frame size, in
+// config order (a multi-word hash.Hash), and the feature is the features joined.
+// Margins are joined the same way, HashBits per word. NumBins is per word, the
+// shared Config.NumBins.
+// Threshold is that of the first frame size; per-frame spectra are not kept.
+func (p *Pipeline) analyzeMulti(samples []float64, norm audio.NormalizeMode, stereoCode uint64) (*Analysis, error) {
+	out := &Analysis{}
+	for i, sp := range p.multi {
+		a, err := sp.analyzeSamples(samples, norm, stereoCode, false)
+		if err != nil {
+			return nil, fmt.Errorf("frame size %d: %w", sp.cfg.FrameSize, err)
+		}
+		if i == 0 {
+			out.Threshold = a.Threshold
+		}
+		out.Hash += a.Hash
+		out.Feature = append(out.Feature, a.Feature...)
+		out.Margins = append(out.Margins, a.Margins...)
+		out.NumBins = a.NumBins
+	}
+	return out, nil
+}
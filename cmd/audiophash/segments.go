@@ -0,0 +1,239 @@
+package audiophash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// SegmentHash is the hash of one segment of a longer input.
+type SegmentHash struct {
+	Start int // first sample of the segment, at the configured sample rate
+	End   int // one past the last sample, at the configured sample rate
+
+	SourceStart int // Start mapped to the decoder's sample rate (equal to Start for raw PCM)
+	SourceEnd   int // End mapped to the decoder's sample rate
+
+	StartSec float64 // Start in seconds
+	EndSec   float64 // End in seconds
+
+	Hash string // hex pHash of the segment, as returned by AudioPHashBytes
+}
+
+// HashSegments hashes every segmentLen-sample window of b, advancing stride samples
+// each time; cfg follows the AudioPHashBytes conventions. See Pipeline.HashSegments.
+func HashSegments(b []byte, cfg *config.Config, fileformat string, segmentLen, stride int) ([]SegmentHash, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.HashSegments(b, fileformat, segmentLen, stride)
+}
+
+// HashSegments decodes b once and hashes each window [k*stride, k*stride+segmentLen)
+// that fits in the signal. Lengths are in samples at the configured sample rate.
+// stride < segmentLen gives overlapping segments for fine alignment; stride >
+// segmentLen samples sparsely. Each segment is normalized and hashed as a standalone
+// clip; silent segments are skipped.
+func (p *Pipeline) HashSegments(b []byte, fileformat string, segmentLen, stride int) ([]SegmentHash, error) {
+	if stride <= 0 {
+		return nil, fmt.Errorf("%w: segment stride must be > 0 (got %d)", ErrInvalidConfig, stride)
+	}
+	if segmentLen < p.cfg.FrameSize {
+		return nil, fmt.Errorf("%w: segment length %d shorter than frame size %d", ErrInvalidConfig, segmentLen, p.cfg.FrameSize)
+	}
+
+	d, err := p.decode(b, fileformat)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.samples) < segmentLen {
+		return nil, fmt.Errorf("%w: %d samples, segment needs %d", ErrAudioTooShort, len(d.samples), segmentLen)
+	}
+
+	sr := float64(p.cfg.SampleRate)
+	toSource := func(i int) int {
+		if d.sourceRate == 0 || d.sourceRate == p.cfg.SampleRate {
+			return i
+		}
+		return int(float64(i) * float64(d.sourceRate) / sr)
+	}
+
+	var segs []SegmentHash
+	for start := 0; start+segmentLen <= len(d.samples); start += stride {
+		end := start + segmentLen
+		a, err := p.analyzeSamples(d.samples[start:end], p.normalizeMode(), d.stereoCode, false)
+		if errors.Is(err, ErrSilentAudio) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("segment at %d: %w", start, err)
+		}
+		segs = append(segs, SegmentHash{
+			Start:       start,
+			End:         end,
+			SourceStart: toSource(start),
+			SourceEnd:   toSource(end),
+			StartSec:    float64(start) / sr,
+			EndSec:      float64(end) / sr,
+			Hash:        a.Hash,
+		})
+	}
+	return segs, nil
+}
+
+// FingerprintSequence returns a time-ordered sequence of sub-fingerprints, one per
+// consecutive windowMs window of b, for matching by subsequence alignment (see
+// hash.SequenceMatch); cfg follows the AudioPHashBytes conventions. Silent windows
+// yield 0 so positions stay aligned with time; a trailing partial window is dropped.
+// windowMs must cover at least one frame.
+func FingerprintSequence(b []byte, cfg *config.Config, format string, windowMs int) ([]uint64, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.FingerprintSequence(b, format, windowMs)
+}
+
+// FingerprintSequence is the Pipeline form of FingerprintSequence.
+func (p *Pipeline) FingerprintSequence(b []byte, fileformat string, windowMs int) ([]uint64, error) {
+	if windowMs <= 0 {
+		return nil, fmt.Errorf("%w: windowMs must be > 0 (got %d)", ErrInvalidConfig, windowMs)
+	}
+	if len(p.multi) > 0 {
+		return nil, fmt.Errorf("%w: a fingerprint sequence holds one-word hashes, not MultiResolution", ErrInvalidConfig)
+	}
+	window := windowMs * p.cfg.SampleRate / 1000
+	if window < p.cfg.FrameSize {
+		return nil, fmt.Errorf("%w: %dms window (%d samples) shorter than frame size %d", ErrInvalidConfig, windowMs, window, p.cfg.FrameSize)
+	}
+
+	d, err := p.decode(b, fileformat)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.samples) < window {
+		return nil, fmt.Errorf("%w: %d samples, window needs %d", ErrAudioTooShort, len(d.samples), window)
+	}
+
+	seq := make([]uint64, 0, len(d.samples)/window)
+	for start := 0; start+window <= len(d.samples); start += window {
+		a, err := p.analyzeSamples(d.samples[start:start+window], p.normalizeMode(), d.stereoCode, false)
+		if errors.Is(err, ErrSilentAudio) {
+			seq = append(seq, 0)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("window at %d: %w", start, err)
+		}
+		h, err := hash.HexToUint64(a.Hash)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, h)
+	}
+	return seq, nil
+}
+
+// HashCueSegments hashes each region of a WAV file delimited by its cue markers (see
+// audio.WAVCuePoints); cfg follows the AudioPHashBytes conventions. See
+// Pipeline.HashCueSegments.
+func HashCueSegments(b []byte, cfg *config.Config, fileformat string) ([]SegmentHash, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.HashCueSegments(b, fileformat)
+}
+
+// HashCueSegments decodes a WAV file once and hashes every region between consecutive
+// boundaries, where the boundaries are the start of the audio, each in-range cue
+// marker and the end of the audio. A file without markers yields a single segment
+// covering the whole file. Regions shorter than one frame and silent regions are
+// skipped. fileformat must be "wav" (optionally with a ".gz" suffix).
+func (p *Pipeline) HashCueSegments(b []byte, fileformat string) ([]SegmentHash, error) {
+	if strings.TrimSuffix(fileformat, ".gz") != "wav" {
+		return nil, fmt.Errorf("%w: cue markers need wav input, got %s", ErrUnsupportedFormat, fileformat)
+	}
+	d, err := p.decode(b, fileformat)
+	if err != nil {
+		return nil, err
+	}
+	raw, _, err := audio.MaybeDecompress(b, fileformat)
+	if err != nil {
+		return nil, fmt.Errorf("%w: gzip: %w", ErrDecodeFailed, err)
+	}
+	cues, err := audio.WAVCuePoints(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cue: %w", ErrDecodeFailed, err)
+	}
+
+	sr := float64(p.cfg.SampleRate)
+	toTarget := func(src int) int {
+		if d.sourceRate == 0 || d.sourceRate == p.cfg.SampleRate {
+			return src
+		}
+		return int(float64(src) * sr / float64(d.sourceRate))
+	}
+	sourceEnd := len(d.samples)
+	if d.sourceRate != 0 && d.sourceRate != p.cfg.SampleRate {
+		sourceEnd = int(float64(len(d.samples)) * float64(d.sourceRate) / sr)
+	}
+
+	bounds := make([]int, 0, len(cues)+2)
+	if len(cues) == 0 || cues[0] > 0 {
+		bounds = append(bounds, 0)
+	}
+	bounds = append(bounds, cues...)
+	bounds = append(bounds, sourceEnd)
+
+	var segs []SegmentHash
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := toTarget(bounds[i]), toTarget(bounds[i+1])
+		if end > len(d.samples) {
+			end = len(d.samples)
+		}
+		if end-start < p.cfg.FrameSize {
+			continue
+		}
+		a, err := p.analyzeSamples(d.samples[start:end], p.normalizeMode(), d.stereoCode, false)
+		if errors.Is(err, ErrSilentAudio) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cue segment at %d: %w", bounds[i], err)
+		}
+		segs = append(segs, SegmentHash{
+			Start:       start,
+			End:         end,
+			SourceStart: bounds[i],
+			SourceEnd:   bounds[i+1],
+			StartSec:    float64(start) / sr,
+			EndSec:      float64(end) / sr,
+			Hash:        a.Hash,
+		})
+	}
+	return segs, nil
+}
@@ -0,0 +1,43 @@
+package audiophash
+
+import (
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// SimilarityScore blends hash and feature similarity into a single score in [0, 1].
+//
+// Weighting: with n hash bits and Hamming distance d, the score is
+//
+//	((n - d) + c) / (n + 1)
+//
+// where c is the feature cosine similarity clamped to [0, 1]. One hash bit is worth
+// exactly as much as the whole cosine range, so the Hamming distance always decides
+// the ranking and the cosine only orders candidates at the same distance.
+// Returns 0 if either analysis is nil or the hashes cannot be compared, including
+// analyses made with different NumBins (reconcile them with Analysis.WithNumBins).
+func SimilarityScore(a, b *Analysis) float64 {
+	if a == nil || b == nil || a.NumBins != b.NumBins {
+		return 0
+	}
+	ha, err := hash.FromHex(a.Hash)
+	if err != nil {
+		return 0
+	}
+	hb, err := hash.FromHex(b.Hash)
+	if err != nil || ha.Bits() != hb.Bits() {
+		return 0
+	}
+
+	n := float64(ha.Bits())
+	d := float64(ha.Distance(hb))
+
+	c, err := features.CosineSimilarity(a.Feature, b.Feature)
+	if err != nil || c < 0 {
+		c = 0
+	}
+	if c > 1 {
+		c = 1
+	}
+	return ((n - d) + c) / (n + 1)
+}
@@ -0,0 +1,204 @@
+package audiophash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+// streamChunk is the number of samples decoded per read on the streaming path.
+const streamChunk = 1 << 16
+
+// AudioPHashFileStreaming hashes the WAV file at path in bounded memory: samples are
+// decoded, resampled and framed in chunks instead of materialising the whole signal.
+// cfg follows the AudioPHashBytes conventions (nil -> config.DefaultConfig(44100)).
+//
+// The file is read once through a Hasher. The per-bin median is estimated online
+// (see hash.P2Quantile), so the result can differ from AudioPHashBytes in a few bits
+// that sit right at the hash threshold.
+// AdaptiveFraming, Loop, FrameGateDB, FrameTrimPercent, StereoBits, Channel,
+// Speaker, PlanarWAV, MultiResolution, AlgorithmMelDCT, MaxClippedFraction and
+// non-peak Normalize are not supported here.
+func AudioPHashFileStreaming(path string, cfg *config.Config) (string, error) {
+	var localCfg config.Config
+	if cfg == nil {
+		localCfg = config.DefaultConfig(44100)
+	} else {
+		localCfg = *cfg
+	}
+	p, err := NewPipeline(localCfg)
+	if err != nil {
+		return "", err
+	}
+	return p.HashFileStreaming(path)
+}
+
+// HashFileStreaming is the Pipeline form of AudioPHashFileStreaming.
+func (p *Pipeline) HashFileStreaming(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return p.HashReaderStreaming(f, "wav")
+}
+
+// HashReaderStreaming hashes audio read from r in bounded memory, like
+// HashFileStreaming. fileformat is "wav", which needs r to be an io.ReadSeeker to
+// locate its data chunks, or raw "pcm16"/"pcm16le"/"pcm16be", read in fixed blocks
+// and taken to be at the configured sample rate. With CanonicalRate set, raw input
+// is resampled too, which needs its length: r must then be an io.Seeker. Raw input
+// ending on an odd byte fails with ErrDecodeFailed wrapping audio.ErrTruncatedSample.
+func (p *Pipeline) HashReaderStreaming(r io.Reader, fileformat string) (string, error) {
+	h, err := p.NewHasher()
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		st        monoReader
+		sr, total int
+	)
+	switch fileformat {
+	case "wav":
+		rs, ok := r.(io.ReadSeeker)
+		if !ok {
+			return "", fmt.Errorf("%w: streaming wav needs an io.ReadSeeker", ErrUnsupportedFormat)
+		}
+		ws, err := audio.NewWAVStream(rs, audio.DownmixAverage)
+		if err != nil {
+			return "", fmt.Errorf("%w: WAV: %w", ErrDecodeFailed, err)
+		}
+		if ws.Info().NumSamples() == 0 {
+			return "", ErrEmptyInput
+		}
+		st, sr, total = ws, ws.Info().SampleRate, ws.Info().NumSamples()
+	case "pcm16", "pcm16le", "pcm16be":
+		var order binary.ByteOrder = binary.LittleEndian
+		if fileformat == "pcm16be" {
+			order = binary.BigEndian
+		}
+		st = audio.NewPCM16Stream(r, order)
+		if p.inputRate != p.cfg.SampleRate {
+			n, err := remainingBytes(r)
+			if err != nil {
+				return "", err
+			}
+			if n == 0 {
+				return "", ErrEmptyInput
+			}
+			if total = n / 2; total > 0 {
+				sr = p.inputRate
+			}
+		}
+	default:
+		return "", fmt.Errorf("%w: %s not supported when streaming", ErrUnsupportedFormat, fileformat)
+	}
+
+	n, err := p.streamSamples(st, sr, total, fileformat, h.Write)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", ErrEmptyInput
+	}
+	return h.Sum()
+}
+
+// remainingBytes returns how many bytes r holds past its current position, leaving
+// the position unchanged. Raw PCM carries no length, and the stream resampler needs one.
+func remainingBytes(r io.Reader) (int, error) {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return 0, fmt.Errorf("%w: streaming raw PCM at a CanonicalRate needs an io.Seeker", ErrUnsupportedFormat)
+	}
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("%w: seek: %w", ErrDecodeFailed, err)
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("%w: seek: %w", ErrDecodeFailed, err)
+	}
+	if _, err := s.Seek(cur, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("%w: seek: %w", ErrDecodeFailed, err)
+	}
+	return int(end - cur), nil
+}
+
+// checkStreamable rejects options that need the whole signal before framing.
+func (p *Pipeline) checkStreamable() error {
+	switch {
+	case p.cfg.AdaptiveFraming:
+		return fmt.Errorf("%w: AdaptiveFraming not supported when streaming", ErrInvalidConfig)
+	case p.cfg.Loop:
+		return fmt.Errorf("%w: Loop not supported when streaming", ErrInvalidConfig)
+	case p.cfg.FrameGateDB > 0:
+		return fmt.Errorf("%w: FrameGateDB not supported when streaming", ErrInvalidConfig)
+	case p.cfg.FrameTrimPercent > 0:
+		return fmt.Errorf("%w: FrameTrimPercent not supported when streaming", ErrInvalidConfig)
+	case p.cfg.StereoBits > 0:
+		return fmt.Errorf("%w: StereoBits not supported when streaming", ErrInvalidConfig)
+	case p.cfg.Channel > 0:
+		return fmt.Errorf("%w: Channel not supported when streaming", ErrInvalidConfig)
+	case p.cfg.Speaker != "":
+		return fmt.Errorf("%w: Speaker not supported when streaming", ErrInvalidConfig)
+	case p.cfg.PlanarWAV:
+		return fmt.Errorf("%w: PlanarWAV not supported when streaming", ErrInvalidConfig)
+	case p.cfg.Algorithm != config.AlgorithmSpectrum:
+		return fmt.Errorf("%w: Algorithm %q not supported when streaming", ErrInvalidConfig, p.cfg.Algorithm)
+	case len(p.cfg.MultiResolution) > 0:
+		return fmt.Errorf("%w: MultiResolution not supported when streaming", ErrInvalidConfig)
+	case p.cfg.MaxClippedFraction > 0:
+		return fmt.Errorf("%w: MaxClippedFraction not supported when streaming", ErrInvalidConfig)
+	case p.cfg.Normalize != "peak":
+		return fmt.Errorf("%w: Normalize %q not supported when streaming", ErrInvalidConfig, p.cfg.Normalize)
+	}
+	return nil
+}
+
+// monoReader is a chunked mono sample source: audio.WAVStream or audio.PCM16Stream.
+type monoReader interface {
+	ReadMono(dst []float64) (int, error)
+}
+
+// streamSamples decodes st from its current position in chunks, resamples from sr
+// (0 = already at the configured rate; total is the input length in samples) if
+// needed, and passes each chunk to fn. It returns the number of samples decoded;
+// errors name fileformat.
+func (p *Pipeline) streamSamples(st monoReader, sr, total int, fileformat string, fn func([]float64)) (int, error) {
+	var rs *audio.StreamResampler
+	if sr != 0 && sr != p.cfg.SampleRate {
+		var err error
+		rs, err = audio.NewStreamResamplerWith(sr, p.cfg.SampleRate, total, p.cfg.ResampleTaps)
+		if err != nil {
+			return 0, fmt.Errorf("resample: %w", err)
+		}
+	}
+
+	buf := make([]float64, streamChunk)
+	var out []float64
+	decoded := 0
+	for {
+		n, err := st.ReadMono(buf)
+		if n > 0 {
+			decoded += n
+			if rs != nil {
+				out = rs.Process(buf[:n], out[:0])
+				fn(out)
+			} else {
+				fn(buf[:n])
+			}
+		}
+		if err == io.EOF {
+			return decoded, nil
+		}
+		if err != nil {
+			return decoded, fmt.Errorf("%w: %s: %w", ErrDecodeFailed, fileformat, err)
+		}
+	}
+}
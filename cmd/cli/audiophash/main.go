@@ -0,0 +1,433 @@
+// Command audiophash computes and compares perceptual audio hashes.
+//
+// Usage:
+//
+//	audiophash hash [-format f] <file>
+//	audiophash compare [-format f] [-match-level] [-sample-rate hz] <file1> <file2>
+//	audiophash features [-format f] [-frames] [-json] <file>
+//	audiophash manifest [-workers n] <dir>
+//	audiophash verify [-threshold n] <manifest.json>
+//	audiophash formats
+//
+// Without -format the format is taken from the file extension.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "hash":
+		err = runHash(os.Args[2:])
+	case "compare":
+		err = runCompare(os.Args[2:])
+	case "features":
+		err = runFeatures(os.Args[2:])
+	case "manifest":
+		err = runManifest(os.Args[2:])
+	case "verify":
+		err = runVerify(os.Args[2:])
+	case "formats":
+		for _, f := range audiophash.SupportedFormats() {
+			fmt.Println(f)
+		}
+	case "-h", "-help", "--help", "help":
+		usage(os.Stdout)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "audiophash:", err)
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `usage:
+  audiophash hash [-format f] <file>
+  audiophash compare [-format f] [-match-level] [-sample-rate hz] <file1> <file2>
+  audiophash features [-format f] [-frames] [-json] <file>
+  audiophash manifest [-workers n] <dir>
+  audiophash verify [-threshold n] <manifest.json>
+  audiophash formats`)
+	fmt.Fprintf(w, "formats (optionally with .gz): %s\n", strings.Join(audiophash.SupportedFormats(), ", "))
+}
+
+// formatFlag registers -format on fs.
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "", "input format, one of: "+strings.Join(audiophash.SupportedFormats(), ", ")+" (default: from file extension)")
+}
+
+// checkFormat returns an error unless format is empty or a supported format,
+// optionally with a ".gz" suffix.
+func checkFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	name := strings.TrimSuffix(format, ".gz")
+	for _, f := range audiophash.SupportedFormats() {
+		if f == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported format %q (supported: %s)", format, strings.Join(audiophash.SupportedFormats(), ", "))
+}
+
+// formatFromPath maps a file extension to an AudioPHashBytes format, keeping a ".gz" suffix.
+func formatFromPath(p string) string {
+	lower := strings.ToLower(p)
+	gz := ""
+	if strings.HasSuffix(lower, ".gz") {
+		lower = strings.TrimSuffix(lower, ".gz")
+		gz = ".gz"
+	}
+	switch filepath.Ext(lower) {
+	case ".raw", ".pcm":
+		return "pcm16le" + gz
+	case ".f32":
+		return "f32le" + gz
+	default:
+		return "wav" + gz
+	}
+}
+
+// analyzeFile analyzes the file at path as format, or as formatFromPath(path) if
+// format is empty.
+func analyzeFile(p *audiophash.Pipeline, path, format string, frames bool) (*audiophash.Analysis, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = formatFromPath(path)
+	}
+	if frames {
+		return p.AnalyzeFrames(b, format)
+	}
+	return p.Analyze(b, format)
+}
+
+// defaultSampleRate is the rate inputs are resampled to before hashing.
+const defaultSampleRate = 44100
+
+func newPipeline() (*audiophash.Pipeline, error) {
+	return newPipelineAt(defaultSampleRate)
+}
+
+// newPipelineAt returns a default pipeline hashing at sampleRate.
+func newPipelineAt(sampleRate int) (*audiophash.Pipeline, error) {
+	return audiophash.NewPipeline(config.DefaultConfig(sampleRate))
+}
+
+func runHash(args []string) error {
+	fs := flag.NewFlagSet("hash", flag.ExitOnError)
+	format := formatFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("hash: expected 1 file, got %d", fs.NArg())
+	}
+	if err := checkFormat(*format); err != nil {
+		return fmt.Errorf("hash: %w", err)
+	}
+	p, err := newPipeline()
+	if err != nil {
+		return err
+	}
+	a, err := analyzeFile(p, fs.Arg(0), *format, false)
+	if err != nil {
+		return err
+	}
+	warnClipped(fs.Arg(0), a)
+	fmt.Println(a.Hash)
+	return nil
+}
+
+// warnClipped prints a warning on stderr when the input at path is clipped enough
+// that its hash may be unreliable.
+func warnClipped(path string, a *audiophash.Analysis) {
+	if a.ClippedFraction > audio.ClipWarnFraction {
+		fmt.Fprintf(os.Stderr, "warning: %s: %.2g%% of samples clipped; the hash may be unreliable\n", path, 100*a.ClippedFraction)
+	}
+}
+
+// runCompare hashes both files at one target rate (-sample-rate), so inputs with
+// different native rates are resampled to a common grid before hashing, and prints
+// the Hamming distance. The native rates are reported on stderr.
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	format := formatFlag(fs)
+	matchLevel := fs.Bool("match-level", false, "match the second file's level to the first before hashing")
+	sampleRate := fs.Int("sample-rate", defaultSampleRate, "rate in Hz both files are resampled to before hashing")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("compare: expected 2 files, got %d", fs.NArg())
+	}
+	if err := checkFormat(*format); err != nil {
+		return fmt.Errorf("compare: %w", err)
+	}
+	if *sampleRate <= 0 {
+		return fmt.Errorf("compare: -sample-rate must be > 0 (got %d)", *sampleRate)
+	}
+	format1, format2 := *format, *format
+	if *format == "" {
+		format1, format2 = formatFromPath(fs.Arg(0)), formatFromPath(fs.Arg(1))
+	}
+	p, err := newPipelineAt(*sampleRate)
+	if err != nil {
+		return err
+	}
+
+	var a1, a2 *audiophash.Analysis
+	if *matchLevel {
+		b1, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		b2, err := os.ReadFile(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		a1, a2, err = p.AnalyzeLevelMatched(b1, format1, b2, format2)
+		if err != nil {
+			return err
+		}
+	} else {
+		if a1, err = analyzeFile(p, fs.Arg(0), format1, false); err != nil {
+			return err
+		}
+		if a2, err = analyzeFile(p, fs.Arg(1), format2, false); err != nil {
+			return err
+		}
+	}
+	h1, err := hash.FromHex(a1.Hash)
+	if err != nil {
+		return err
+	}
+	h2, err := hash.FromHex(a2.Hash)
+	if err != nil {
+		return err
+	}
+	warnClipped(fs.Arg(0), a1)
+	warnClipped(fs.Arg(1), a2)
+	fmt.Fprintf(os.Stderr, "%s: %s, %s: %s, hashed at %d Hz\n",
+		fs.Arg(0), nativeRate(a1), fs.Arg(1), nativeRate(a2), *sampleRate)
+	fmt.Println(h1.Distance(h2))
+	return nil
+}
+
+// nativeRate describes the sample rate an input was decoded at.
+func nativeRate(a *audiophash.Analysis) string {
+	if a.Input.SampleRate == 0 {
+		return "raw PCM (no rate, taken as the target)"
+	}
+	return fmt.Sprintf("%d Hz", a.Input.SampleRate)
+}
+
+func runFeatures(args []string) error {
+	fs := flag.NewFlagSet("features", flag.ExitOnError)
+	frames := fs.Bool("frames", false, "also print per-frame spectra")
+	asJSON := fs.Bool("json", false, "print JSON instead of CSV")
+	format := formatFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("features: expected 1 file, got %d", fs.NArg())
+	}
+	if err := checkFormat(*format); err != nil {
+		return fmt.Errorf("features: %w", err)
+	}
+	p, err := newPipeline()
+	if err != nil {
+		return err
+	}
+	a, err := analyzeFile(p, fs.Arg(0), *format, *frames)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Hash    string      `json:"hash"`
+			Feature []float64   `json:"feature"`
+			Frames  [][]float64 `json:"frames,omitempty"`
+		}{a.Hash, a.Feature, a.Frames})
+	}
+
+	// CSV: one row per vector, first column labels it ("feature" or the frame index)
+	w := csv.NewWriter(os.Stdout)
+	w.Write(csvRow("feature", a.Feature))
+	for i, f := range a.Frames {
+		w.Write(csvRow(strconv.Itoa(i), f))
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// isAudioPath reports whether path has an extension formatFromPath understands.
+func isAudioPath(path string) bool {
+	lower := strings.TrimSuffix(strings.ToLower(path), ".gz")
+	switch filepath.Ext(lower) {
+	case ".wav", ".raw", ".pcm", ".f32":
+		return true
+	}
+	return false
+}
+
+// runManifest walks dir and prints a JSON manifest mapping each audio file's path,
+// relative to dir, to its hash. Files are hashed by -workers goroutines while the
+// walk continues; files that fail to hash are reported and skipped.
+func runManifest(args []string) error {
+	fs := flag.NewFlagSet("manifest", flag.ExitOnError)
+	workers := fs.Int("workers", 0, "files hashed concurrently (0 = number of CPUs)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("manifest: expected 1 directory, got %d", fs.NArg())
+	}
+	root := fs.Arg(0)
+	p, err := newPipeline()
+	if err != nil {
+		return err
+	}
+
+	items := make(chan audiophash.BatchItem)
+	results, err := p.HashBatch(items, audiophash.BatchOptions{Workers: *workers})
+	if err != nil {
+		return err
+	}
+	var walkErr error
+	go func() {
+		defer close(items)
+		walkErr = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !isAudioPath(path) {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			items <- audiophash.BatchItem{
+				ID:     filepath.ToSlash(rel),
+				Format: formatFromPath(path),
+				Load:   func() ([]byte, error) { return os.ReadFile(path) },
+			}
+			return nil
+		})
+	}()
+
+	manifest := map[string]string{}
+	for r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filepath.Join(root, filepath.FromSlash(r.ID)), r.Err)
+			continue
+		}
+		manifest[r.ID] = r.Hash
+	}
+	if walkErr != nil {
+		return walkErr
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(manifest)
+}
+
+// runVerify re-hashes every file in a manifest written by runManifest (paths relative
+// to the manifest's directory) and reports files further than -threshold bits from
+// their stored hash, or that can no longer be hashed.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	threshold := fs.Int("threshold", 0, "maximum Hamming distance still counted as unchanged")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("verify: expected 1 manifest, got %d", fs.NArg())
+	}
+	b, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return fmt.Errorf("verify: %s: %w", fs.Arg(0), err)
+	}
+	p, err := newPipeline()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(manifest))
+	for path := range manifest {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	dir := filepath.Dir(fs.Arg(0))
+	failed := 0
+	for _, path := range paths {
+		full := filepath.FromSlash(path)
+		if !filepath.IsAbs(full) {
+			full = filepath.Join(dir, full)
+		}
+		want, err := hash.FromHex(manifest[path])
+		if err != nil {
+			fmt.Printf("FAIL %s: stored hash: %v\n", path, err)
+			failed++
+			continue
+		}
+		a, err := analyzeFile(p, full, "", false)
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		got, err := hash.FromHex(a.Hash)
+		if err != nil {
+			return err
+		}
+		if d := want.Distance(got); d > *threshold {
+			fmt.Printf("FAIL %s: distance %d (stored %s, now %s)\n", path, d, manifest[path], a.Hash)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("verify: %d of %d files failed", failed, len(paths))
+	}
+	fmt.Printf("ok %d files\n", len(paths))
+	return nil
+}
+
+func csvRow(label string, v []float64) []string {
+	row := make([]string, 0, len(v)+1)
+	row = append(row, label)
+	for _, x := range v {
+		row = append(row, strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	return row
+}
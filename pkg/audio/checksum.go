@@ -0,0 +1,27 @@
+package audio
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// checksumScale quantizes samples to 24-bit integers: every 16- and 24-bit PCM
+// value maps to a distinct integer, so the checksum is exact for integer input.
+const checksumScale = 1 << 23
+
+// SampleChecksum returns the FNV-64a digest of samples quantized to 24-bit
+// integers (round(x*2^23), clamped to [-2^23, 2^23-1]) in little-endian order.
+// Equal checksums mean the same decoded PCM, an exact-duplicate signal to pair with
+// the perceptual hash. It ignores sample rate and any difference below 2^-23.
+func SampleChecksum(samples []float64) uint64 {
+	f := fnv.New64a()
+	var buf [4]byte
+	for _, x := range samples {
+		q := math.Round(x * checksumScale)
+		q = math.Max(-checksumScale, math.Min(checksumScale-1, q))
+		binary.LittleEndian.PutUint32(buf[:], uint32(int32(q)))
+		f.Write(buf[:])
+	}
+	return f.Sum64()
+}
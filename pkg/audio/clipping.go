@@ -0,0 +1,54 @@
+package audio
+
+import "math"
+
+// Clipping detection parameters used by DetectClipping.
+const (
+	// ClipMinRun is the shortest run of consecutive samples at the peak that counts
+	// as a flat top. A clean waveform touches its peak in isolated samples.
+	ClipMinRun = 3
+	// ClipTolerance is how close to the peak magnitude, relative to it, a sample
+	// must be to count as sitting on the clip level.
+	ClipTolerance = 1e-6
+	// ClipWarnFraction is the clipped fraction above which the spectrum carries
+	// enough clipping harmonics that a hash may be unreliable.
+	ClipWarnFraction = 0.001
+)
+
+// DetectClipping counts samples lying on flat tops: runs of at least ClipMinRun
+// same-sign samples within ClipTolerance of the signal's peak magnitude. The clip
+// level is taken from the signal itself, so a clipped master that was later turned
+// down is still detected. It returns the clipped sample count and its fraction of
+// len(samples); silence and empty input give 0, 0.
+//
+// Run it on decoded samples before resampling, which smooths flat tops away.
+func DetectClipping(samples []float64) (clippedSamples int, fraction float64) {
+	var peak float64
+	for _, s := range samples {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	if peak == 0 {
+		return 0, 0
+	}
+	level := peak * (1 - ClipTolerance)
+
+	run, sign := 0, 0.0
+	for _, s := range samples {
+		if math.Abs(s) >= level && (run == 0 || math.Signbit(s) == math.Signbit(sign)) {
+			run++
+			sign = s
+			continue
+		}
+		if run >= ClipMinRun {
+			clippedSamples += run
+		}
+		run = 0
+		if math.Abs(s) >= level {
+			run, sign = 1, s
+		}
+	}
+	if run >= ClipMinRun {
+		clippedSamples += run
+	}
+	return clippedSamples, float64(clippedSamples) / float64(len(samples))
+}
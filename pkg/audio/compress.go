@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxDecompressedSize caps the gunzipped size accepted by Decompress, so a small
+// gzip bomb cannot exhaust memory. 1 GiB holds about 100 minutes of 48kHz stereo
+// 24-bit WAV.
+const MaxDecompressedSize = 1 << 30
+
+// ErrDecompressedTooLarge is returned by Decompress when the gunzipped data exceeds
+// its limit.
+var ErrDecompressedTooLarge = errors.New("decompressed data too large")
+
+// gzipMagic is the two-byte header of a gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// sniffedFormats are the formats whose own magic bytes can never look like gzip, so
+// gzip input is recognised without a ".gz" suffix. Headerless PCM is not among them:
+// any sample may start with 1f 8b.
+var sniffedFormats = map[string]bool{"wav": true}
+
+// IsGzip reports whether b starts with the gzip magic bytes.
+func IsGzip(b []byte) bool {
+	return bytes.HasPrefix(b, gzipMagic)
+}
+
+// MaybeDecompress returns the gunzipped contents of b and fileformat without its
+// ".gz" suffix. b is gunzipped when fileformat has the suffix, or when it starts
+// with the gzip magic and fileformat is a container (WAV) whose own magic differs;
+// otherwise it is returned unchanged. The output is capped at MaxDecompressedSize.
+func MaybeDecompress(b []byte, fileformat string) ([]byte, string, error) {
+	format, gz := strings.CutSuffix(fileformat, ".gz")
+	if !gz && !(sniffedFormats[format] && IsGzip(b)) {
+		return b, format, nil
+	}
+	out, err := Decompress(b, MaxDecompressedSize)
+	return out, format, err
+}
+
+// Decompress gunzips b, failing with ErrDecompressedTooLarge if the result would be
+// longer than limit bytes.
+func Decompress(b []byte, limit int64) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrDecompressedTooLarge, limit)
+	}
+	return out, nil
+}
@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"sort"
+	"time"
 )
 
 // DecodePCM16LEToFloat64 converts raw 16-bit PCM little-endian bytes to float64 samples in [-1.0, +1.0].
@@ -18,11 +22,22 @@ import (
 //	int            : sample rate (0 for raw PCM, since PCM16LE raw bytes do not include SR info)
 //	error          : non-nil if decoding fails
 func DecodePCM16LEToFloat64(b []byte) ([]float64, int, error) {
+	return DecodePCM16ToFloat64(b, binary.LittleEndian)
+}
+
+// DecodePCM16BEToFloat64 is DecodePCM16LEToFloat64 for big-endian PCM.
+func DecodePCM16BEToFloat64(b []byte) ([]float64, int, error) {
+	return DecodePCM16ToFloat64(b, binary.BigEndian)
+}
+
+// DecodePCM16ToFloat64 converts raw 16-bit PCM bytes in the given byte order to
+// float64 samples in [-1.0, +1.0]. See DecodePCM16LEToFloat64.
+func DecodePCM16ToFloat64(b []byte, order binary.ByteOrder) ([]float64, int, error) {
 	if len(b) == 0 {
 		return nil, 0, errors.New("input byte slice is empty")
 	}
 	if len(b)%2 != 0 {
-		return nil, 0, errors.New("byte length is not multiple of 2, invalid PCM16LE")
+		return nil, 0, errors.New("byte length is not multiple of 2, invalid PCM16")
 	}
 
 	numSamples := len(b) / 2
@@ -30,159 +45,596 @@ func DecodePCM16LEToFloat64(b []byte) ([]float64, int, error) {
 
 	for i := 0; i < numSamples; i++ {
 		offset := i * 2
-		raw := int16(binary.LittleEndian.Uint16(b[offset : offset+2]))
+		raw := int16(order.Uint16(b[offset : offset+2]))
 		samples[i] = float64(raw) / 32768.0
 	}
 
 	return samples, 0, nil
 }
 
-// DecodeWAVToFloat64 decodes a WAV file (16, 24, or 32-bit PCM) into float64 samples in [-1.0, +1.0].
+// DecodePCM24LEToFloat64 converts raw packed 24-bit PCM little-endian bytes (3 bytes
+// per sample, no header) to float64 samples in [-1.0, +1.0]. Like raw PCM16 the input
+// is mono and carries no sample rate, so the returned rate is 0.
+func DecodePCM24LEToFloat64(b []byte) ([]float64, int, error) {
+	if len(b) == 0 {
+		return nil, 0, errors.New("input byte slice is empty")
+	}
+	if len(b)%3 != 0 {
+		return nil, 0, errors.New("byte length is not multiple of 3, invalid PCM24")
+	}
+
+	samples := make([]float64, len(b)/3)
+	for i := range samples {
+		samples[i] = float64(int24LE(b[i*3:])) / (1 << 23)
+	}
+	return samples, 0, nil
+}
+
+// int24LE sign-extends the packed little-endian 24-bit integer in b[0:3].
+func int24LE(b []byte) int32 {
+	raw := int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16
+	if raw&0x800000 != 0 {
+		raw |= ^0xffffff
+	}
+	return raw
+}
+
+// DecodeFloat32LEToFloat64 converts raw 32-bit IEEE float little-endian bytes to float64
+// samples. Values are taken as already normalized to [-1.0, +1.0]; like raw PCM16 the
+// input is mono and carries no sample rate, so the returned rate is 0.
+func DecodeFloat32LEToFloat64(b []byte) ([]float64, int, error) {
+	if len(b) == 0 {
+		return nil, 0, errors.New("input byte slice is empty")
+	}
+	if len(b)%4 != 0 {
+		return nil, 0, errors.New("byte length is not multiple of 4, invalid float32 PCM")
+	}
+
+	samples := make([]float64, len(b)/4)
+	for i := range samples {
+		samples[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:])))
+	}
+	return samples, 0, nil
+}
+
+// WAV fmt chunk audio format codes.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE // real format is in the first two bytes of the SubFormat GUID
+)
+
+// ErrUnsupportedBitDepth is returned for a WAV whose bitsPerSample is not supported
+// for its format; the error names the bit depth and format code found.
+var ErrUnsupportedBitDepth = errors.New("unsupported WAV bit depth")
+
+// ErrUnsupportedWAVFormat is returned for a WAV format code other than integer PCM
+// or IEEE float (after resolving WAVE_FORMAT_EXTENSIBLE); the error names the code.
+var ErrUnsupportedWAVFormat = errors.New("unsupported WAV format")
+
+// DecodeWAVToFloat64 decodes a WAV file (16, 24, or 32-bit PCM, or 32/64-bit float) into float64 samples in [-1.0, +1.0].
+// WAVE_FORMAT_EXTENSIBLE files are accepted, with their MSB-aligned validBitsPerSample (see readSamples).
 // Mono output is returned by averaging all channels.
 func DecodeWAVToFloat64(b []byte) ([]float64, int, error) {
+	channels, sr, err := DecodeWAVChannels(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	return Downmix(channels), sr, nil
+}
+
+// DownmixMode selects how channels are combined into mono.
+type DownmixMode int
+
+const (
+	// DownmixAverage averages channels: (L+R)/N. A fully correlated signal keeps its level.
+	DownmixAverage DownmixMode = iota
+	// DownmixEnergy sums channels and divides by sqrt(N): uncorrelated channels of
+	// equal level keep their per-channel RMS. It is DownmixAverage scaled by sqrt(N),
+	// so it only changes the output level; the hashing pipeline normalizes that away
+	// and always downmixes with DownmixAverage. It is for callers of
+	// DecodeWAVToFloat64Mode that use the samples directly.
+	DownmixEnergy
+)
+
+// DecodeWAVToFloat64Mode is like DecodeWAVToFloat64 but downmixes with the given mode.
+func DecodeWAVToFloat64Mode(b []byte, mode DownmixMode) ([]float64, int, error) {
+	channels, sr, err := DecodeWAVChannels(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	return DownmixWith(channels, mode), sr, nil
+}
+
+// ChannelDownmix selects the downmix of all channels in SelectChannel and
+// DecodeWAVChannel. Channels are numbered from 1 (1 = left, 2 = right, ...), the
+// same numbering as Config.Channel, so 0 is free to mean the downmix.
+const ChannelDownmix = 0
+
+// ErrChannelOutOfRange is returned when a selected channel does not exist in the input.
+var ErrChannelOutOfRange = errors.New("channel index out of range")
+
+// DecodeWAVChannel is like DecodeWAVToFloat64 but returns only channel ch
+// (1 = left, 2 = right, ...), or the average downmix for ChannelDownmix.
+func DecodeWAVChannel(b []byte, ch int) ([]float64, int, error) {
+	channels, sr, err := DecodeWAVChannels(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	mono, err := SelectChannel(channels, ch, DownmixAverage)
+	if err != nil {
+		return nil, 0, err
+	}
+	return mono, sr, nil
+}
+
+// SelectChannel returns channel ch (1-based) of channels, or their downmix with
+// mode when ch is ChannelDownmix. It fails with ErrChannelOutOfRange if there is no
+// channel ch.
+func SelectChannel(channels [][]float64, ch int, mode DownmixMode) ([]float64, error) {
+	if ch == ChannelDownmix {
+		return DownmixWith(channels, mode), nil
+	}
+	if ch < 1 || ch > len(channels) {
+		return nil, fmt.Errorf("%w: channel %d of %d", ErrChannelOutOfRange, ch, len(channels))
+	}
+	return channels[ch-1], nil
+}
+
+// Downmix averages per-channel samples into a single mono slice.
+func Downmix(channels [][]float64) []float64 {
+	return DownmixWith(channels, DownmixAverage)
+}
+
+// DownmixWith combines per-channel samples into a single mono slice using mode.
+func DownmixWith(channels [][]float64, mode DownmixMode) []float64 {
+	if len(channels) == 0 {
+		return nil
+	}
+	if len(channels) == 1 {
+		return channels[0]
+	}
+	div := float64(len(channels))
+	if mode == DownmixEnergy {
+		div = math.Sqrt(div)
+	}
+	mono := make([]float64, len(channels[0]))
+	for i := range mono {
+		var sum float64
+		for _, ch := range channels {
+			sum += ch[i]
+		}
+		mono[i] = sum / div
+	}
+	return mono
+}
+
+// DecodeWAVChannels decodes a WAV file like DecodeWAVToFloat64 but keeps channels separate.
+// Output:
+//
+//	[][]float64    : one slice of samples per channel, all the same length
+//	int            : sample rate
+//	error          : non-nil if decoding fails
+func DecodeWAVChannels(b []byte) ([][]float64, int, error) {
+	channels, info, err := decodeWAV(b, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return channels, info.SampleRate, nil
+}
+
+// DecodeWAVPlanarChannels is like DecodeWAVChannels for files whose data chunks are
+// planar (non-interleaved): each chunk holds all of channel 1's samples, then all of
+// channel 2's, and so on. The RIFF format has no flag for this layout, so the caller
+// must know it; read as interleaved, such a file scrambles the channels together.
+func DecodeWAVPlanarChannels(b []byte) ([][]float64, int, error) {
+	channels, info, err := decodeWAV(b, true)
+	if err != nil {
+		return nil, 0, err
+	}
+	return channels, info.SampleRate, nil
+}
+
+// DecodeWAVWithInfo is like DecodeWAVToFloat64 but also returns the input's format
+// and length, so callers can log it without parsing the header again.
+func DecodeWAVWithInfo(b []byte) ([]float64, AudioInfo, error) {
+	channels, info, err := decodeWAV(b, false)
+	if err != nil {
+		return nil, AudioInfo{}, err
+	}
+	return Downmix(channels), info.AudioInfo(), nil
+}
+
+// decodeWAV scans b and reads every data chunk into one slice per channel,
+// interleaved or planar.
+func decodeWAV(b []byte, planar bool) ([][]float64, *WAVInfo, error) {
 	if len(b) < 44 {
-		return nil, 0, errors.New("WAV too short to contain header")
+		return nil, nil, errors.New("WAV too short to contain header")
 	}
 
 	r := bytes.NewReader(b)
+	info, err := ScanWAV(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	channels := make([][]float64, info.NumChannels)
+	sampleBytes := int64(info.BitsPerSample / 8)
+	for _, c := range info.DataChunks {
+		n := info.chunkSamples(c)
+		if !planar {
+			if _, err := r.Seek(c.Offset, io.SeekStart); err != nil {
+				return nil, nil, err
+			}
+			if err := readSamples(r, channels, n, info); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		for ch := range channels {
+			if _, err := r.Seek(c.Offset+int64(ch)*int64(n)*sampleBytes, io.SeekStart); err != nil {
+				return nil, nil, err
+			}
+			if err := readSamples(r, channels[ch:ch+1], n, info); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return channels, info, nil
+}
+
+// AudioInfo summarizes a decoded input: enough to log "3m42s stereo 16-bit"
+// without parsing the header again.
+type AudioInfo struct {
+	Channels        int    // channels in the input, before any downmix or channel selection
+	Bits            int    // significant bits per sample (0 = unknown)
+	SampleRate      int    // native sample rate in Hz (0 = not carried by the format)
+	DurationSamples int    // sample frames per channel at SampleRate
+	ChannelMask     uint32 // WAV speaker positions (0 = not given or not WAV), see SpeakerChannel
+}
+
+// Duration returns the input's length, or 0 if the sample rate is unknown.
+func (a AudioInfo) Duration() time.Duration {
+	if a.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(a.DurationSamples) * time.Second / time.Duration(a.SampleRate)
+}
+
+// WAVInfo describes a WAV file's format and where its sample data lives.
+type WAVInfo struct {
+	AudioFormat   uint16 // 1 = integer PCM, 3 = IEEE float (resolved from the SubFormat of extensible files)
+	NumChannels   int
+	SampleRate    int
+	BitsPerSample uint16      // container size of one sample
+	ValidBits     uint16      // significant bits per sample from an extensible fmt chunk (0 = BitsPerSample)
+	ChannelMask   uint32      // speaker positions from an extensible fmt chunk (0 = not given), see SpeakerChannel
+	DataChunks    []DataChunk // every "data" chunk, in file order
+	CuePoints     []int       // sample-frame offsets of the "cue " chunk's markers, in file order, unvalidated
+}
+
+// DataChunk locates one "data" chunk's payload.
+type DataChunk struct {
+	Offset int64 // byte offset of the first sample
+	Size   int64 // payload size in bytes
+}
+
+// frameBytes returns the size in bytes of one interleaved sample frame.
+func (w *WAVInfo) frameBytes() int64 {
+	return int64(w.BitsPerSample/8) * int64(w.NumChannels)
+}
+
+// chunkSamples returns the number of whole sample frames in c.
+func (w *WAVInfo) chunkSamples(c DataChunk) int {
+	return int(c.Size / w.frameBytes())
+}
+
+// NumSamples returns the total number of sample frames (per channel) across all data chunks.
+func (w *WAVInfo) NumSamples() int {
+	n := 0
+	for _, c := range w.DataChunks {
+		n += w.chunkSamples(c)
+	}
+	return n
+}
 
+// AudioInfo returns the format and length of the file described by w. Bits is
+// ValidBits when the fmt chunk gives it, else BitsPerSample.
+func (w *WAVInfo) AudioInfo() AudioInfo {
+	bits := int(w.BitsPerSample)
+	if w.ValidBits != 0 {
+		bits = int(w.ValidBits)
+	}
+	return AudioInfo{
+		Channels:        w.NumChannels,
+		Bits:            bits,
+		SampleRate:      w.SampleRate,
+		DurationSamples: w.NumSamples(),
+		ChannelMask:     w.ChannelMask,
+	}
+}
+
+// ScanWAV parses the RIFF/WAVE header and fmt chunk of r and records the location of
+// every data chunk without reading sample data. Chunks are scanned in a single pass,
+// so a fmt chunk that (non-compliantly) follows the data chunk is accepted.
+func ScanWAV(r io.ReadSeeker) (*WAVInfo, error) {
 	// --- RIFF header ---
 	var riff [4]byte
 	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	if string(riff[:]) != "RIFF" {
-		return nil, 0, errors.New("not a RIFF file")
+		return nil, errors.New("not a RIFF file")
 	}
 
 	var _chunkSize uint32
 	if err := binary.Read(r, binary.LittleEndian, &_chunkSize); err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
 	var wave [4]byte
 	if err := binary.Read(r, binary.LittleEndian, &wave); err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	if string(wave[:]) != "WAVE" {
-		return nil, 0, errors.New("not a WAVE file")
+		return nil, errors.New("not a WAVE file")
 	}
 
-	// --- scan for "fmt " chunk ---
-	var audioFormat uint16
-	var numChannels uint16
-	var sampleRate uint32
-	var bitsPerSample uint16
-
+	// --- scan all chunks until EOF, recording "fmt " and every "data" chunk ---
+	info := &WAVInfo{}
+	foundFmt := false
 	for {
 		var chunkHeader [4]byte
 		var chunkSize uint32
-
 		if err := binary.Read(r, binary.LittleEndian, &chunkHeader); err != nil {
-			return nil, 0, err
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, err
 		}
 		if err := binary.Read(r, binary.LittleEndian, &chunkSize); err != nil {
-			return nil, 0, err
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, err
 		}
 
+		skip := int64(chunkSize)
 		switch string(chunkHeader[:]) {
 		case "fmt ":
-			// read fmt chunk
-			if err := binary.Read(r, binary.LittleEndian, &audioFormat); err != nil {
-				return nil, 0, err
-			}
-			if err := binary.Read(r, binary.LittleEndian, &numChannels); err != nil {
-				return nil, 0, err
-			}
-			if err := binary.Read(r, binary.LittleEndian, &sampleRate); err != nil {
-				return nil, 0, err
-			}
-			var _byteRate uint32
-			if err := binary.Read(r, binary.LittleEndian, &_byteRate); err != nil {
-				return nil, 0, err
-			}
-			var _blockAlign uint16
-			if err := binary.Read(r, binary.LittleEndian, &_blockAlign); err != nil {
-				return nil, 0, err
+			if foundFmt {
+				return nil, errors.New("WAV has more than one fmt chunk")
 			}
-			if err := binary.Read(r, binary.LittleEndian, &bitsPerSample); err != nil {
-				return nil, 0, err
+			n, err := readFmt(r, chunkSize, info)
+			if err != nil {
+				return nil, err
 			}
-			if audioFormat != 1 {
-				return nil, 0, errors.New("only PCM format supported")
+			foundFmt = true
+			skip -= n
+		case "data":
+			off, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
 			}
-			if bitsPerSample != 16 && bitsPerSample != 24 && bitsPerSample != 32 {
-				return nil, 0, errors.New("only 16, 24, or 32-bit WAV supported")
+			info.DataChunks = append(info.DataChunks, DataChunk{Offset: off, Size: int64(chunkSize)})
+		case "cue ":
+			n, err := readCue(r, chunkSize, info)
+			if err != nil {
+				return nil, err
 			}
-			// skip extra fmt bytes
-			if extra := int64(chunkSize) - 16; extra > 0 {
-				if _, err := r.Seek(extra, io.SeekCurrent); err != nil {
-					return nil, 0, err
-				}
+			skip -= n
+		}
+		if skip > 0 {
+			if _, err := r.Seek(skip, io.SeekCurrent); err != nil {
+				return nil, err
 			}
-			goto foundFmt
-		default:
-			// skip unknown chunk
-			if _, err := r.Seek(int64(chunkSize), io.SeekCurrent); err != nil {
-				return nil, 0, err
+		}
+		// RIFF chunks are word-aligned
+		if chunkSize%2 == 1 {
+			if _, err := r.Seek(1, io.SeekCurrent); err != nil {
+				return nil, err
 			}
 		}
 	}
-foundFmt:
+	if !foundFmt {
+		return nil, errors.New("WAV has no fmt chunk")
+	}
+	if len(info.DataChunks) == 0 {
+		return nil, errors.New("WAV has no data chunk")
+	}
+
+	return info, nil
+}
+
+// readFmt reads a fmt chunk of the given size into info and validates the sample format.
+// A chunk of 18 bytes or more has its cbSize read and checked against the chunk size.
+// For WAVE_FORMAT_EXTENSIBLE it also reads validBitsPerSample and the SubFormat code.
+// It returns the number of chunk bytes consumed.
+func readFmt(r io.Reader, size uint32, info *WAVInfo) (int64, error) {
+	var fmtChunk struct {
+		AudioFormat   uint16
+		NumChannels   uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+	}
+	if err := binary.Read(r, binary.LittleEndian, &fmtChunk); err != nil {
+		return 0, err
+	}
+	consumed := int64(16)
+
+	// cbSize counts the extension bytes after it. Some encoders write an 18-byte
+	// fmt chunk with cbSize 0 for plain PCM; read it rather than assume, so an
+	// extension that does not fit the chunk is rejected instead of misaligning
+	// the chunk scan.
+	var cbSize uint16
+	if size >= 18 {
+		if err := binary.Read(r, binary.LittleEndian, &cbSize); err != nil {
+			return 0, err
+		}
+		consumed += 2
+		if int64(cbSize) > int64(size)-consumed {
+			return 0, fmt.Errorf("fmt chunk cbSize %d exceeds the %d bytes left in the chunk", cbSize, int64(size)-consumed)
+		}
+	}
 
-	// --- scan for "data" chunk ---
-	var dataSize uint32
-	for {
-		var chunkHeader [4]byte
-		if err := binary.Read(r, binary.LittleEndian, &chunkHeader); err != nil {
-			return nil, 0, err
+	format := fmtChunk.AudioFormat
+	var validBits uint16
+	var channelMask uint32
+	if format == wavFormatExtensible {
+		if size < 40 || cbSize < 22 {
+			return 0, errors.New("WAVE_FORMAT_EXTENSIBLE fmt chunk too short")
+		}
+		var ext struct {
+			ValidBits   uint16
+			ChannelMask uint32
+			SubFormat   [16]byte
 		}
-		if err := binary.Read(r, binary.LittleEndian, &dataSize); err != nil {
-			return nil, 0, err
+		if err := binary.Read(r, binary.LittleEndian, &ext); err != nil {
+			return 0, err
 		}
-		if string(chunkHeader[:]) == "data" {
-			break
+		consumed += 22
+		format = binary.LittleEndian.Uint16(ext.SubFormat[:2])
+		validBits = ext.ValidBits
+		channelMask = ext.ChannelMask
+		if validBits > fmtChunk.BitsPerSample {
+			return 0, errors.New("validBitsPerSample exceeds container size")
 		}
-		if _, err := r.Seek(int64(dataSize), io.SeekCurrent); err != nil {
-			return nil, 0, err
+		if validBits == fmtChunk.BitsPerSample {
+			validBits = 0
 		}
 	}
 
-	numSamples := dataSize / uint32(bitsPerSample/8) / uint32(numChannels)
-	samples := make([]float64, numSamples)
+	switch format {
+	case wavFormatPCM:
+		if b := fmtChunk.BitsPerSample; b != 16 && b != 24 && b != 32 {
+			return 0, fmt.Errorf("%w: bitsPerSample %d, audioFormat %d (PCM supports 16, 24 or 32)", ErrUnsupportedBitDepth, b, format)
+		}
+	case wavFormatFloat:
+		if b := fmtChunk.BitsPerSample; b != 32 && b != 64 {
+			return 0, fmt.Errorf("%w: bitsPerSample %d, audioFormat %d (float supports 32 or 64)", ErrUnsupportedBitDepth, b, format)
+		}
+		validBits = 0
+	default:
+		return 0, fmt.Errorf("%w: audioFormat %#x (only PCM 1 or IEEE float 3)", ErrUnsupportedWAVFormat, format)
+	}
+	if fmtChunk.NumChannels == 0 {
+		return 0, errors.New("WAV has zero channels")
+	}
+	info.AudioFormat = format
+	info.NumChannels = int(fmtChunk.NumChannels)
+	info.SampleRate = int(fmtChunk.SampleRate)
+	info.BitsPerSample = fmtChunk.BitsPerSample
+	info.ValidBits = validBits
+	info.ChannelMask = channelMask
+	return consumed, nil
+}
 
-	for i := 0; i < int(numSamples); i++ {
-		var sum float64
-		for ch := 0; ch < int(numChannels); ch++ {
+// readCue reads a "cue " chunk of the given size into info.CuePoints, taking each
+// point's dwSampleOffset. A point count larger than the chunk can hold is clamped to
+// the points actually present. It returns the number of chunk bytes consumed.
+func readCue(r io.Reader, size uint32, info *WAVInfo) (int64, error) {
+	if size < 4 {
+		return 0, nil
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return 0, err
+	}
+	consumed := int64(4)
+	if max := (size - 4) / 24; count > max {
+		count = max
+	}
+	for i := uint32(0); i < count; i++ {
+		var point struct {
+			ID           uint32
+			Position     uint32
+			DataChunkID  [4]byte
+			ChunkStart   uint32
+			BlockStart   uint32
+			SampleOffset uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &point); err != nil {
+			return 0, err
+		}
+		consumed += 24
+		info.CuePoints = append(info.CuePoints, int(point.SampleOffset))
+	}
+	return consumed, nil
+}
+
+// WAVCuePoints returns the cue marker positions of a WAV file in sample frames at its
+// own sample rate, sorted and de-duplicated. Markers at or past the end of the audio
+// data are dropped. A file without a "cue " chunk yields no markers and no error.
+func WAVCuePoints(b []byte) ([]int, error) {
+	info, err := ScanWAV(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	n := info.NumSamples()
+	points := make([]int, 0, len(info.CuePoints))
+	for _, p := range info.CuePoints {
+		if p < n {
+			points = append(points, p)
+		}
+	}
+	sort.Ints(points)
+	out := points[:0]
+	for _, p := range points {
+		if len(out) == 0 || p != out[len(out)-1] {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// readSamples reads numSamples interleaved frames from r and appends them per channel.
+// info.AudioFormat is wavFormatPCM (16/24/32-bit integer) or wavFormatFloat (32/64-bit
+// IEEE float). Integer samples are scaled by the container's full scale even when
+// ValidBits is set: WAVE_FORMAT_EXTENSIBLE stores the valid bits MSB-aligned, with
+// zero padding below them, so e.g. 20-bit audio in 24-bit containers is already at
+// full 24-bit scale.
+func readSamples(r io.Reader, channels [][]float64, numSamples int, info *WAVInfo) error {
+	audioFormat, bitsPerSample := info.AudioFormat, info.BitsPerSample
+	fullScale := float64(uint64(1) << (bitsPerSample - 1))
+	for i := 0; i < numSamples; i++ {
+		for ch := range channels {
 			var val float64
-			switch bitsPerSample {
-			case 16:
+			switch {
+			case audioFormat == wavFormatFloat && bitsPerSample == 32:
+				var raw float32
+				if err := binary.Read(r, binary.LittleEndian, &raw); err != nil {
+					return err
+				}
+				val = float64(raw)
+			case audioFormat == wavFormatFloat && bitsPerSample == 64:
+				// already in [-1, +1]
+				if err := binary.Read(r, binary.LittleEndian, &val); err != nil {
+					return err
+				}
+			case bitsPerSample == 16:
 				var raw int16
 				if err := binary.Read(r, binary.LittleEndian, &raw); err != nil {
-					return nil, 0, err
+					return err
 				}
-				val = float64(raw) / 32768.0
-			case 24:
+				val = float64(raw) / fullScale
+			case bitsPerSample == 24:
 				buf := make([]byte, 3)
-				if _, err := r.Read(buf); err != nil {
-					return nil, 0, err
+				if _, err := io.ReadFull(r, buf); err != nil {
+					return err
 				}
-				raw := int32(buf[0]) | int32(buf[1])<<8 | int32(buf[2])<<16
-				if raw&0x800000 != 0 {
-					raw |= ^0xffffff
-				}
-				val = float64(raw) / 8388608.0
-			case 32:
+				val = float64(int24LE(buf)) / fullScale
+			case bitsPerSample == 32:
 				var raw int32
 				if err := binary.Read(r, binary.LittleEndian, &raw); err != nil {
-					return nil, 0, err
+					return err
 				}
-				val = float64(raw) / 2147483648.0
+				val = float64(raw) / fullScale
 			}
-			sum += val
+			channels[ch] = append(channels[ch], val)
 		}
-		samples[i] = sum / float64(numChannels)
 	}
-
-	return samples, int(sampleRate), nil
+	return nil
 }
@@ -0,0 +1,84 @@
+package audio
+
+import (
+	"math"
+	"sort"
+)
+
+// NormalizeMode selects how NormalizeWith scales a signal.
+type NormalizeMode int
+
+const (
+	// NormalizePeak scales the absolute peak to 1 (see Normalize).
+	NormalizePeak NormalizeMode = iota
+	// NormalizeRMS scales the signal to the RMS of a full-scale sine (1/sqrt(2)).
+	// Samples are not clamped and may exceed ±1.
+	NormalizeRMS
+	// NormalizePercentile scales the given percentile of absolute amplitude to 1 and
+	// clamps the few louder samples to ±1, so isolated clicks do not set the gain.
+	NormalizePercentile
+)
+
+// DefaultNormalizePercentile is the percentile used by NormalizePercentile when none is given.
+const DefaultNormalizePercentile = 99.5
+
+// NormalizeWith returns a scaled copy of samples. pct is the percentile (0 < pct <= 100)
+// used by NormalizePercentile and is ignored by the other modes; pct <= 0 selects
+// DefaultNormalizePercentile. An all-zero signal is returned unchanged.
+func NormalizeWith(samples []float64, mode NormalizeMode, pct float64) []float64 {
+	switch mode {
+	case NormalizeRMS:
+		var sum float64
+		for _, s := range samples {
+			sum += s * s
+		}
+		if sum == 0 {
+			return samples
+		}
+		rms := math.Sqrt(sum / float64(len(samples)))
+		return scaleClamped(samples, 1/(math.Sqrt2*rms), false)
+	case NormalizePercentile:
+		if pct <= 0 {
+			pct = DefaultNormalizePercentile
+		}
+		ref := AbsPercentile(samples, pct)
+		if ref == 0 {
+			return Normalize(samples)
+		}
+		return scaleClamped(samples, 1/ref, true)
+	}
+	return Normalize(samples)
+}
+
+// AbsPercentile returns the pct-th percentile (0..100, nearest rank) of |samples|.
+func AbsPercentile(samples []float64, pct float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	abs := make([]float64, len(samples))
+	for i, s := range samples {
+		abs[i] = math.Abs(s)
+	}
+	sort.Float64s(abs)
+	rank := int(math.Ceil(pct/100*float64(len(abs)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(abs) {
+		rank = len(abs) - 1
+	}
+	return abs[rank]
+}
+
+// scaleClamped returns samples*scale, optionally clamped to [-1, 1].
+func scaleClamped(samples []float64, scale float64, clamp bool) []float64 {
+	out := make([]float64, len(samples))
+	for i, s := range samples {
+		v := s * scale
+		if clamp {
+			v = math.Max(-1, math.Min(1, v))
+		}
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package audio
+
+import (
+	"math"
+
+	"github.com/ast-jean/audiophash/pkg/fft"
+)
+
+// onsetBlock is the block size (samples) used to refine onset positions in the time domain.
+const onsetBlock = 64
+
+// onsetContext is the number of flux values on each side used for the local threshold.
+const onsetContext = 8
+
+// DetectOnsets finds note/percussive onsets with spectral flux peak picking.
+// Inputs:
+//
+//	samples   []float64 : mono audio samples
+//	frameSize int       : analysis frame size (power of two)
+//	hop       int       : analysis hop size
+//
+// Output:
+//
+//	[]int : ascending onset positions in samples, refined to onsetBlock resolution
+func DetectOnsets(samples []float64, frameSize, hop int) []int {
+	frames := Frame(samples, frameSize, hop)
+	if len(frames) < 3 {
+		return nil
+	}
+
+	plan := fft.NewPlan(frameSize)
+	flux := make([]float64, len(frames))
+	prev := plan.Magnitude(frames[0])
+	for i := 1; i < len(frames); i++ {
+		cur := plan.Magnitude(frames[i])
+		var sum float64
+		for k := range cur {
+			if d := cur[k] - prev[k]; d > 0 {
+				sum += d
+			}
+		}
+		flux[i] = sum
+		prev = cur
+	}
+
+	// global spread guards against picking noise peaks in steady passages
+	var mean, sq float64
+	for _, v := range flux {
+		mean += v
+	}
+	mean /= float64(len(flux))
+	for _, v := range flux {
+		sq += (v - mean) * (v - mean)
+	}
+	delta := 0.5 * math.Sqrt(sq/float64(len(flux)))
+
+	var onsets []int
+	for i := 1; i < len(flux)-1; i++ {
+		if flux[i] <= flux[i-1] || flux[i] < flux[i+1] {
+			continue
+		}
+		lo, hi := i-onsetContext, i+onsetContext+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(flux) {
+			hi = len(flux)
+		}
+		var local float64
+		for _, v := range flux[lo:hi] {
+			local += v
+		}
+		local /= float64(hi - lo)
+		if flux[i] <= local+delta {
+			continue
+		}
+		onsets = append(onsets, refineOnset(samples, i*hop, frameSize))
+	}
+	return onsets
+}
+
+// refineOnset returns the start of the block with the largest energy increase in
+// samples[start:start+n], falling back to the frame centre.
+func refineOnset(samples []float64, start, n int) int {
+	end := start + n
+	if end > len(samples) {
+		end = len(samples)
+	}
+	best, bestPos := 0.0, start+n/2
+	prevE := -1.0
+	for b := start; b+onsetBlock <= end; b += onsetBlock {
+		var e float64
+		for _, v := range samples[b : b+onsetBlock] {
+			e += v * v
+		}
+		if prevE >= 0 && e-prevE > best {
+			best, bestPos = e-prevE, b
+		}
+		prevE = e
+	}
+	return bestPos
+}
+
+// AdaptiveFrameStarts returns frame start positions centred on each onset, with regular
+// fill frames every hop samples in between. Fill frames closer than hop/2 to the next
+// onset-aligned frame are dropped. All frames fit inside numSamples.
+func AdaptiveFrameStarts(numSamples, frameSize, hop int, onsets []int) []int {
+	if frameSize <= 0 || hop <= 0 || numSamples < frameSize {
+		return nil
+	}
+	last := numSamples - frameSize
+
+	// anchors: beginning of audio, then one frame centred on each onset
+	anchors := []int{0}
+	for _, o := range onsets {
+		s := o - frameSize/2
+		if s < 0 {
+			s = 0
+		}
+		if s > last {
+			s = last
+		}
+		if s > anchors[len(anchors)-1] {
+			anchors = append(anchors, s)
+		}
+	}
+
+	var starts []int
+	for i, a := range anchors {
+		next := last + hop/2 + 1 // past the end: fill up to the last full frame
+		if i+1 < len(anchors) {
+			next = anchors[i+1]
+		}
+		for s := a; s <= last && s <= next-hop/2; s += hop {
+			starts = append(starts, s)
+		}
+	}
+	return starts
+}
+
+// FrameAt extracts windowed frames at the given start positions; starts that would
+// run past the end of samples are skipped. The frame size is len(window).
+func FrameAt(samples []float64, window []float64, starts []int) [][]float64 {
+	frameSize := len(window)
+	frames := make([][]float64, 0, len(starts))
+	for _, start := range starts {
+		if start < 0 || start+frameSize > len(samples) {
+			continue
+		}
+		frame := make([]float64, frameSize)
+		for i := 0; i < frameSize; i++ {
+			frame[i] = samples[start+i] * window[i]
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
@@ -0,0 +1,61 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTruncatedSample is returned by PCM16Stream when the input ends partway through
+// a sample.
+var ErrTruncatedSample = errors.New("input ends inside a sample")
+
+// PCM16Stream decodes raw mono 16-bit PCM from an io.Reader in blocks, the streaming
+// counterpart of DecodePCM16ToFloat64: memory stays bounded by the caller's buffer
+// regardless of input length.
+type PCM16Stream struct {
+	r     io.Reader
+	order binary.ByteOrder
+	buf   []byte // raw block, reused across reads
+	eof   bool
+}
+
+// NewPCM16Stream returns a stream reading PCM16 samples in the given byte order from r.
+func NewPCM16Stream(r io.Reader, order binary.ByteOrder) *PCM16Stream {
+	return &PCM16Stream{r: r, order: order}
+}
+
+// ReadMono fills dst with up to len(dst) samples in [-1.0, +1.0] and returns how many
+// were written. Short reads from the underlying reader are retried, so only the last
+// block is ever partial. It returns io.EOF once the input is exhausted, and an error
+// wrapping ErrTruncatedSample, after the complete samples, if it ends on an odd byte.
+func (s *PCM16Stream) ReadMono(dst []float64) (int, error) {
+	if s.eof {
+		return 0, io.EOF
+	}
+	need := 2 * len(dst)
+	if cap(s.buf) < need {
+		s.buf = make([]byte, need)
+	}
+	m, err := io.ReadFull(s.r, s.buf[:need])
+	n := m / 2
+	for i := 0; i < n; i++ {
+		dst[i] = float64(int16(s.order.Uint16(s.buf[2*i:]))) / 32768.0
+	}
+	switch {
+	case err == nil:
+		return n, nil
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		s.eof = true
+		if m%2 != 0 {
+			return n, fmt.Errorf("%w: odd trailing byte at end of PCM16 input", ErrTruncatedSample)
+		}
+		if n == 0 {
+			return 0, io.EOF
+		}
+		return n, nil
+	default:
+		return n, err
+	}
+}
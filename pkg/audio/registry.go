@@ -0,0 +1,119 @@
+package audio
+
+import (
+	"sort"
+	"sync"
+)
+
+// Decoder turns encoded bytes into mono float64 samples in [-1.0, +1.0].
+// The returned sample rate is 0 when the format does not carry one (raw PCM).
+// Decoders are shared by all callers and must be safe for concurrent use.
+type Decoder interface {
+	Decode(b []byte) ([]float64, int, error)
+}
+
+// ChannelDecoder is implemented by decoders that can return channels separately.
+// The pipeline prefers it so stereo features and the configured downmix mode apply.
+type ChannelDecoder interface {
+	Decoder
+	DecodeChannels(b []byte) ([][]float64, int, error)
+}
+
+// InfoDecoder is implemented by decoders that can report the input's format
+// alongside its channels. The pipeline prefers it over ChannelDecoder so
+// Analysis.Input carries the bit depth; other decoders get an AudioInfo derived
+// from their output, with Bits left 0.
+type InfoDecoder interface {
+	ChannelDecoder
+	DecodeChannelsInfo(b []byte) ([][]float64, AudioInfo, error)
+}
+
+// DecoderFunc adapts a plain function to the Decoder interface.
+type DecoderFunc func(b []byte) ([]float64, int, error)
+
+// Decode calls f(b).
+func (f DecoderFunc) Decode(b []byte) ([]float64, int, error) {
+	return f(b)
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{}
+)
+
+// RegisterDecoder makes d available under the format name. Registering an existing
+// name, including a built-in one, replaces it. It panics if format is empty or d is nil.
+func RegisterDecoder(format string, d Decoder) {
+	if format == "" {
+		panic("audio: RegisterDecoder with empty format")
+	}
+	if d == nil {
+		panic("audio: RegisterDecoder decoder is nil for " + format)
+	}
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[format] = d
+}
+
+// LookupDecoder returns the decoder registered for format.
+func LookupDecoder(format string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	d, ok := decoders[format]
+	return d, ok
+}
+
+// Formats returns the registered format names in sorted order.
+func Formats() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	names := make([]string, 0, len(decoders))
+	for name := range decoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WAVDecoder is the built-in WAV decoder, registered as "wav" with Planar unset.
+type WAVDecoder struct {
+	// Planar reads each data chunk as non-interleaved: all samples of channel 1,
+	// then all of channel 2, and so on (see DecodeWAVPlanarChannels).
+	Planar bool
+}
+
+// Decode decodes and averages the channels to mono.
+func (d WAVDecoder) Decode(b []byte) ([]float64, int, error) {
+	channels, sr, err := d.DecodeChannels(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	return Downmix(channels), sr, nil
+}
+
+// DecodeChannels decodes every channel separately.
+func (d WAVDecoder) DecodeChannels(b []byte) ([][]float64, int, error) {
+	if d.Planar {
+		return DecodeWAVPlanarChannels(b)
+	}
+	return DecodeWAVChannels(b)
+}
+
+// DecodeChannelsInfo is like DecodeChannels but also returns the file's format
+// and length.
+func (d WAVDecoder) DecodeChannelsInfo(b []byte) ([][]float64, AudioInfo, error) {
+	channels, info, err := decodeWAV(b, d.Planar)
+	if err != nil {
+		return nil, AudioInfo{}, err
+	}
+	return channels, info.AudioInfo(), nil
+}
+
+func init() {
+	RegisterDecoder("pcm16", DecoderFunc(DecodePCM16LEToFloat64))
+	RegisterDecoder("pcm16le", DecoderFunc(DecodePCM16LEToFloat64))
+	RegisterDecoder("pcm16be", DecoderFunc(DecodePCM16BEToFloat64))
+	RegisterDecoder("pcm24le", DecoderFunc(DecodePCM24LEToFloat64))
+	RegisterDecoder("f32le", DecoderFunc(DecodeFloat32LEToFloat64))
+	RegisterDecoder("wav", WAVDecoder{})
+}
@@ -0,0 +1,85 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+)
+
+// Speaker position bits of the WAVE_FORMAT_EXTENSIBLE dwChannelMask. Channels are
+// stored in the file in increasing bit order of the positions present in the mask.
+const (
+	SpeakerFrontLeft          uint32 = 0x1
+	SpeakerFrontRight         uint32 = 0x2
+	SpeakerFrontCenter        uint32 = 0x4
+	SpeakerLowFrequency       uint32 = 0x8
+	SpeakerBackLeft           uint32 = 0x10
+	SpeakerBackRight          uint32 = 0x20
+	SpeakerFrontLeftOfCenter  uint32 = 0x40
+	SpeakerFrontRightOfCenter uint32 = 0x80
+	SpeakerBackCenter         uint32 = 0x100
+	SpeakerSideLeft           uint32 = 0x200
+	SpeakerSideRight          uint32 = 0x400
+)
+
+// speakerNames maps the short names accepted by SpeakerBit to position bits.
+var speakerNames = map[string]uint32{
+	"FL":  SpeakerFrontLeft,
+	"FR":  SpeakerFrontRight,
+	"FC":  SpeakerFrontCenter,
+	"LFE": SpeakerLowFrequency,
+	"BL":  SpeakerBackLeft,
+	"BR":  SpeakerBackRight,
+	"FLC": SpeakerFrontLeftOfCenter,
+	"FRC": SpeakerFrontRightOfCenter,
+	"BC":  SpeakerBackCenter,
+	"SL":  SpeakerSideLeft,
+	"SR":  SpeakerSideRight,
+}
+
+// SpeakerBit returns the position bit for a short speaker name ("FL", "FR", "FC",
+// "LFE", "BL", "BR", "FLC", "FRC", "BC", "SL", "SR").
+func SpeakerBit(name string) (uint32, bool) {
+	bit, ok := speakerNames[name]
+	return bit, ok
+}
+
+// SpeakerChannel returns the channel (1-based, as for SelectChannel) carrying
+// speaker position bit in a file described by w. Files without a channel mask (plain
+// PCM fmt chunks) are taken to use the default order FL, FR, FC, LFE, BL, BR, ...,
+// so channel 3 of a 6-channel file is the centre. It fails with ErrChannelOutOfRange
+// if the position is not present.
+func (w *WAVInfo) SpeakerChannel(bit uint32) (int, error) {
+	return w.AudioInfo().SpeakerChannel(bit)
+}
+
+// SpeakerChannel is WAVInfo.SpeakerChannel for decoded input, so callers holding
+// the AudioInfo of a decode need not scan the file again.
+func (a AudioInfo) SpeakerChannel(bit uint32) (int, error) {
+	mask := a.ChannelMask
+	if mask == 0 && a.Channels < 32 {
+		mask = uint32(1)<<uint(a.Channels) - 1
+	}
+	if bits.OnesCount32(bit) != 1 || mask&bit == 0 {
+		return 0, fmt.Errorf("%w: speaker %#x not in channel mask %#x", ErrChannelOutOfRange, bit, mask)
+	}
+	ch := bits.OnesCount32(mask&(bit-1)) + 1
+	if ch > a.Channels {
+		return 0, fmt.Errorf("%w: speaker %#x maps to channel %d of %d", ErrChannelOutOfRange, bit, ch, a.Channels)
+	}
+	return ch, nil
+}
+
+// WAVSpeakerChannel returns the channel (1-based) of the named speaker (see
+// SpeakerBit) in the WAV file b, for use with SelectChannel.
+func WAVSpeakerChannel(b []byte, name string) (int, error) {
+	bit, ok := SpeakerBit(name)
+	if !ok {
+		return 0, fmt.Errorf("unknown speaker %q", name)
+	}
+	info, err := ScanWAV(bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	return info.SpeakerChannel(bit)
+}
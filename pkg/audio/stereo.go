@@ -0,0 +1,79 @@
+package audio
+
+import "math"
+
+// ChannelCorrelation returns the Pearson correlation between the first two channels.
+// Output:
+//
+//	float64 : correlation in [-1.0, +1.0] (1.0 for fewer than two channels)
+//	bool    : true if the input is effectively mono (one channel, or identical channels)
+func ChannelCorrelation(channels [][]float64) (float64, bool) {
+	if len(channels) < 2 {
+		return 1, true
+	}
+	left, right := channels[0], channels[1]
+	n := len(left)
+	if len(right) < n {
+		n = len(right)
+	}
+	if n == 0 {
+		return 1, true
+	}
+
+	identical := true
+	var meanL, meanR float64
+	for i := 0; i < n; i++ {
+		if left[i] != right[i] {
+			identical = false
+		}
+		meanL += left[i]
+		meanR += right[i]
+	}
+	if identical {
+		return 1, true
+	}
+	meanL /= float64(n)
+	meanR /= float64(n)
+
+	var cov, varL, varR float64
+	for i := 0; i < n; i++ {
+		dl := left[i] - meanL
+		dr := right[i] - meanR
+		cov += dl * dr
+		varL += dl * dl
+		varR += dr * dr
+	}
+	if varL == 0 || varR == 0 {
+		// one side silent/constant: no shared content
+		return 0, false
+	}
+	return cov / math.Sqrt(varL*varR), false
+}
+
+// MonoCompatibility returns how much of the first two channels' energy survives
+// summing them to mono: sum((L+R)^2) / (2*sum(L^2+R^2)), in [0, 1]. It is 1 for
+// identical channels, about 0.5 for unrelated ones and 0 when one channel is the
+// other inverted, which cancels completely. Unlike ChannelCorrelation it is
+// energy-weighted and not mean-removed, so it measures what a listener on a mono
+// speaker actually loses; 10*log10 of it is that loss in dB. Fewer than two
+// channels, or silence, give 1.
+func MonoCompatibility(channels [][]float64) float64 {
+	if len(channels) < 2 {
+		return 1
+	}
+	left, right := channels[0], channels[1]
+	n := len(left)
+	if len(right) < n {
+		n = len(right)
+	}
+	var sum, total float64
+	for i := 0; i < n; i++ {
+		s := left[i] + right[i]
+		sum += s * s
+		total += left[i]*left[i] + right[i]*right[i]
+	}
+	if total == 0 {
+		return 1
+	}
+	return sum / (2 * total)
+}
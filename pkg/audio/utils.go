@@ -5,7 +5,9 @@ import (
 	"math"
 )
 
-// Resample linearly resamples audio from `fromHz` to `toHz`.
+// Resample linearly resamples audio from `fromHz` to `toHz`. When fromHz is an exact
+// integer multiple of toHz (44100->22050, 48000->16000) it dispatches to Decimate,
+// which filters out the content above the new Nyquist frequency instead of aliasing it.
 // Input:
 //
 //	samples []float64 : original audio samples
@@ -17,6 +19,12 @@ import (
 //	[]float64 : resampled audio
 //	error     : non-nil if input invalid
 func Resample(samples []float64, fromHz, toHz int) ([]float64, error) {
+	return ResampleWith(samples, fromHz, toHz, 0)
+}
+
+// ResampleWith is Resample with the decimation FIR length for integer ratios (see
+// DecimateWith; 0 = default). Linear interpolation ignores taps.
+func ResampleWith(samples []float64, fromHz, toHz, taps int) ([]float64, error) {
 	if fromHz <= 0 || toHz <= 0 {
 		return nil, errors.New("invalid sample rate")
 	}
@@ -30,16 +38,16 @@ func Resample(samples []float64, fromHz, toHz int) ([]float64, error) {
 		copy(out, samples)
 		return out, nil
 	}
+	if fromHz > toHz && fromHz%toHz == 0 {
+		return DecimateWith(samples, fromHz/toHz, taps), nil
+	}
 
-	ratio := float64(toHz) / float64(fromHz)
-	newLen := int(float64(len(samples)) * ratio)
+	newLen := resampledLen(len(samples), fromHz, toHz)
 	out := make([]float64, newLen)
 
 	for i := 0; i < newLen; i++ {
-		// Map output sample index -> input float index
-		pos := float64(i) / ratio
-		idx := int(pos)
-		frac := pos - float64(idx)
+		// Map output sample index -> input index and fraction
+		idx, frac := sourcePos(i, fromHz, toHz)
 
 		if idx+1 < len(samples) {
 			// Linear interpolation
@@ -81,3 +89,130 @@ func Normalize(samples []float64) []float64 {
 
 	return normalized
 }
+
+// resampledLen returns the number of output samples for n input samples,
+// floor(n*toHz/fromHz), computed exactly.
+func resampledLen(n, fromHz, toHz int) int {
+	return int(int64(n) * int64(toHz) / int64(fromHz))
+}
+
+// sourcePos maps output sample i to input position i*fromHz/toHz, split into the
+// integer index and the fractional remainder. Integer arithmetic keeps the position
+// exact however long the signal, where accumulating i/ratio in floating point would
+// drift between rates with no clean ratio such as 44100 and 48000.
+func sourcePos(i, fromHz, toHz int) (int, float64) {
+	num := int64(i) * int64(fromHz)
+	return int(num / int64(toHz)), float64(num%int64(toHz)) / float64(toHz)
+}
+
+// StreamResampler applies the same linear interpolation or integer decimation as
+// Resample to a signal that arrives in chunks, given its total length up front. Only
+// the samples still needed for interpolation or filtering are retained between calls.
+type StreamResampler struct {
+	factor int       // integer decimation factor (0 = linear interpolation)
+	fir    []float64 // decimation filter when factor > 0
+	fromHz int
+	toHz   int
+	inLen  int
+	outLen int
+	outPos int       // next output index
+	base   int       // input index of buf[0]
+	buf    []float64 // retained input samples
+}
+
+// NewStreamResampler returns a resampler from fromHz to toHz for inLen input samples.
+func NewStreamResampler(fromHz, toHz, inLen int) (*StreamResampler, error) {
+	return NewStreamResamplerWith(fromHz, toHz, inLen, 0)
+}
+
+// NewStreamResamplerWith is NewStreamResampler with the decimation FIR length, as in ResampleWith.
+func NewStreamResamplerWith(fromHz, toHz, inLen, taps int) (*StreamResampler, error) {
+	if fromHz <= 0 || toHz <= 0 {
+		return nil, errors.New("invalid sample rate")
+	}
+	if inLen <= 0 {
+		return nil, errors.New("no samples to resample")
+	}
+	if fromHz > toHz && fromHz%toHz == 0 {
+		factor := fromHz / toHz
+		return &StreamResampler{
+			factor: factor,
+			fir:    decimationFilter(factor, taps),
+			inLen:  inLen,
+			outLen: inLen / factor,
+		}, nil
+	}
+	return &StreamResampler{
+		fromHz: fromHz,
+		toHz:   toHz,
+		inLen:  inLen,
+		outLen: resampledLen(inLen, fromHz, toHz),
+	}, nil
+}
+
+// Process consumes the next chunk of input and appends every output sample that can
+// now be computed to dst, returning the extended slice.
+func (r *StreamResampler) Process(in []float64, dst []float64) []float64 {
+	r.buf = append(r.buf, in...)
+	avail := r.base + len(r.buf) // input samples seen so far
+	if r.factor > 0 {
+		return r.decimate(avail, dst)
+	}
+
+	for r.outPos < r.outLen {
+		// Map output sample index -> input index and fraction (same as Resample)
+		idx, frac := sourcePos(r.outPos, r.fromHz, r.toHz)
+
+		if idx+1 < r.inLen {
+			if idx+1 >= avail {
+				break
+			}
+			dst = append(dst, r.buf[idx-r.base]*(1-frac)+r.buf[idx+1-r.base]*frac)
+		} else {
+			if r.inLen > avail {
+				break
+			}
+			dst = append(dst, r.buf[r.inLen-1-r.base])
+		}
+		r.outPos++
+	}
+
+	// drop input no longer needed by the next output sample
+	next, _ := sourcePos(r.outPos, r.fromHz, r.toHz)
+	if drop := next - r.base; drop > 0 {
+		if drop > len(r.buf) {
+			drop = len(r.buf)
+		}
+		r.buf = append(r.buf[:0], r.buf[drop:]...)
+		r.base += drop
+	}
+	return dst
+}
+
+// decimate is Process for integer-factor decimation.
+func (r *StreamResampler) decimate(avail int, dst []float64) []float64 {
+	half := len(r.fir) / 2
+	for r.outPos < r.outLen {
+		center := r.outPos * r.factor
+		need := center + half
+		if need > r.inLen-1 {
+			need = r.inLen - 1
+		}
+		if need >= avail {
+			break
+		}
+		dst = append(dst, firAt(r.buf, r.base, r.inLen, center, r.fir))
+		r.outPos++
+	}
+
+	// drop input left of the next output's filter span
+	next := r.outPos*r.factor - half
+	if drop := next - r.base; drop > 0 {
+		if drop > len(r.buf) {
+			drop = len(r.buf)
+		}
+		r.buf = append(r.buf[:0], r.buf[drop:]...)
+		r.base += drop
+	}
+	return dst
+}
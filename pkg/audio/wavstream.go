@@ -0,0 +1,95 @@
+package audio
+
+import (
+	"bufio"
+	"io"
+)
+
+// wavStreamBuffer is the read buffer size used by WAVStream.
+const wavStreamBuffer = 64 * 1024
+
+// WAVStream decodes a WAV file incrementally, so memory stays bounded by the
+// caller's buffer regardless of file length.
+type WAVStream struct {
+	r     io.ReadSeeker
+	info  *WAVInfo
+	mode  DownmixMode
+	chunk int           // index of the data chunk being read
+	left  int           // sample frames left in the current chunk
+	br    *bufio.Reader // buffered reader positioned inside the current chunk
+	tmp   [][]float64   // per-channel scratch reused across reads
+}
+
+// NewWAVStream scans the header of r and prepares to read samples, downmixed with mode.
+func NewWAVStream(r io.ReadSeeker, mode DownmixMode) (*WAVStream, error) {
+	info, err := ScanWAV(r)
+	if err != nil {
+		return nil, err
+	}
+	return &WAVStream{r: r, info: info, mode: mode, chunk: -1}, nil
+}
+
+// Info returns the parsed header.
+func (s *WAVStream) Info() *WAVInfo { return s.info }
+
+// Rewind restarts reading from the first sample.
+func (s *WAVStream) Rewind() {
+	s.chunk = -1
+	s.left = 0
+}
+
+// ReadMono fills dst with up to len(dst) downmixed samples and returns how many were
+// written. It returns io.EOF once every data chunk has been consumed.
+func (s *WAVStream) ReadMono(dst []float64) (int, error) {
+	n := 0
+	for n < len(dst) {
+		if s.left == 0 {
+			if err := s.nextChunk(); err != nil {
+				if err == io.EOF && n > 0 {
+					return n, nil
+				}
+				return n, err
+			}
+			continue
+		}
+
+		want := len(dst) - n
+		if want > s.left {
+			want = s.left
+		}
+		if s.tmp == nil {
+			s.tmp = make([][]float64, s.info.NumChannels)
+		}
+		for ch := range s.tmp {
+			s.tmp[ch] = s.tmp[ch][:0]
+		}
+		if err := readSamples(s.br, s.tmp, want, s.info); err != nil {
+			return n, err
+		}
+		n += copy(dst[n:], DownmixWith(s.tmp, s.mode))
+		s.left -= want
+	}
+	return n, nil
+}
+
+// nextChunk positions the reader at the start of the next non-empty data chunk.
+func (s *WAVStream) nextChunk() error {
+	for {
+		s.chunk++
+		if s.chunk >= len(s.info.DataChunks) {
+			return io.EOF
+		}
+		c := s.info.DataChunks[s.chunk]
+		if _, err := s.r.Seek(c.Offset, io.SeekStart); err != nil {
+			return err
+		}
+		if s.br == nil {
+			s.br = bufio.NewReaderSize(s.r, wavStreamBuffer)
+		} else {
+			s.br.Reset(s.r)
+		}
+		if s.left = s.info.chunkSamples(c); s.left > 0 {
+			return nil
+		}
+	}
+}
@@ -1,37 +1,200 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"os"
+
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// ErrInvalidConfig is wrapped by every error returned from ValidateAndFill.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// DefaultMaxFrameSize is the Limits.MaxFrameSize ValidateAndFill applies.
+const DefaultMaxFrameSize = 65536
+
+// Limits bounds what a Config may request, so a config from an untrusted caller
+// cannot ask for a window and FFT large enough to exhaust memory. It is separate
+// from Config for the same reason: the service embedding the library sets it, not
+// the caller supplying the config. See ValidateAndFillWith.
+type Limits struct {
+	MaxFrameSize int // cap on FrameSize, FFTSize, MultiResolution sizes and ResampleTaps (0 -> DefaultMaxFrameSize)
+}
+
+// maxFrameSize returns MaxFrameSize, or DefaultMaxFrameSize if it is not set.
+func (l Limits) maxFrameSize() int {
+	if l.MaxFrameSize > 0 {
+		return l.MaxFrameSize
+	}
+	return DefaultMaxFrameSize
+}
+
+// MaxMultiResolution caps the number of MultiResolution frame sizes, and with it the
+// hash length (one 64-bit word per size).
+const MaxMultiResolution = 8
+
+// Hashing algorithms selectable with Config.Algorithm.
+const (
+	// AlgorithmSpectrum thresholds the median magnitude spectrum, one bit per bin (default).
+	AlgorithmSpectrum = "spectrum"
+	// AlgorithmMelDCT thresholds the low-order 2D DCT coefficients of a fixed-size
+	// log-mel spectrogram, like an image pHash of the spectrogram.
+	AlgorithmMelDCT = "mel-dct"
 )
 
 // Config holds framing and sample parameters.
 type Config struct {
-	SampleRate int // sample rate in Hz (required)
-	FrameSize  int // N: samples per frame (if 0 -> default 2048)
-	Hop        int // H: hop size in samples (if 0 -> default FrameSize/2)
-	NumBins    int // number of FFT bins to use per frame for pHash (default 32)
+	SampleRate    int     `json:"sampleRate"`    // sample rate in Hz (required)
+	CanonicalRate int     `json:"canonicalRate"` // hash at this rate whatever SampleRate is, which then only gives the rate of raw PCM input (0 = hash at SampleRate; see CanonicalConfig)
+	Algorithm     string  `json:"algorithm"`     // hashing algorithm: AlgorithmSpectrum (default) or AlgorithmMelDCT
+	FrameSize     int     `json:"frameSize"`     // N: samples per frame (if 0 -> default 2048)
+	Hop           int     `json:"hop"`           // H: hop size in samples (if 0 -> default FrameSize/2)
+	FFTSize       int     `json:"fftSize"`       // FFT length; frames are zero-padded to it, giving FFTSize/2 bins (if 0 -> FrameSize)
+	FFTBackend    string  `json:"fftBackend"`    // FFT implementation: "gonum" (default) or "purego", see fft.NewPlanWith; purego builds always use "purego"
+	NumBins       int     `json:"numBins"`       // number of FFT bins to use per frame for pHash (if 0 -> DefaultNumBins)
+	KeepDCBin     bool    `json:"keepDCBin"`     // start features at bin 0, giving DC a hash bit (false = start at bin 1)
+	Window        string  `json:"window"`        // analysis window: "hann" (default), "hamming", "blackman-harris" or "kaiser"
+	KaiserBeta    float64 `json:"kaiserBeta"`    // Kaiser window shape, >= 0 (if 0 -> audio.DefaultKaiserBeta); used when Window is "kaiser"
+	LogBands      bool    `json:"logBands"`      // group bins into NumBins log-spaced bands (within MinHz/MaxHz if set) instead of linear bins
+	LowHigh       bool    `json:"lowHigh"`       // NumBins/2 low linear bins plus NumBins/2 log-spaced bands up to Nyquist (excludes LogBands and MinHz/MaxHz)
+	MinHz         float64 `json:"minHz"`         // lower edge of the hashed band in Hz (0 with MaxHz 0 -> low NumBins bins, no band)
+	MaxHz         float64 `json:"maxHz"`         // upper edge of the hashed band in Hz (0 -> Nyquist when MinHz > 0)
+	Channel       int     `json:"channel"`       // hash only this channel, 1-based (1 = left, 2 = right, ...) instead of the downmix (0 = downmix, audio.ChannelDownmix); numbered as in audio.SelectChannel
+	Speaker       string  `json:"speaker"`       // hash only this WAV speaker position, e.g. "FC" for the 5.1 centre (see audio.SpeakerBit); excludes Channel
+
+	PlanarWAV    bool `json:"planarWAV"`    // WAV data chunks are planar (the first channel's samples, then the second's, ...) rather than interleaved
+	ResampleTaps int  `json:"resampleTaps"` // FIR length for integer-ratio downsampling, odd; fewer is faster, more is cleaner (0 -> audio.DefaultDecimationTaps, see audio.DecimateWith)
+
+	Normalize           string  `json:"normalize"`           // sample normalization: "peak" (default), "rms" or "percentile"
+	NormalizePercentile float64 `json:"normalizePercentile"` // percentile of |x| scaled to 1 by "percentile", 0 < p <= 100 (if 0 -> default 99.5)
+
+	WindowGainCompensation bool    `json:"windowGainCompensation"` // divide each frame spectrum by the window's coherent gain (sum of coefficients)
+	NormalizeFeature       bool    `json:"normalizeFeature"`       // scale the aggregated feature to unit L2 norm before log scaling
+	MagnitudeFloor         float64 `json:"magnitudeFloor"`         // clamp feature magnitudes below this to it before log scaling, in magnitude units (squared for UsePowerSpectrum; 0 = disabled)
+	RemoveSpectralTilt     bool    `json:"removeSpectralTilt"`     // subtract a quadratic fit from the log-scaled feature, so a smooth mic/codec tilt does not move bits
+	PsychoacousticMasking  bool    `json:"psychoacousticMasking"`  // attenuate bins masked by louder neighbours in each frame spectrum (see features.ApplyMasking)
+	UsePowerSpectrum       bool    `json:"usePowerSpectrum"`       // hash the power spectrum |X|^2 (fft.ComputePower) instead of the magnitude |X|; DBScale then uses 10*log10 (features.PowerToDB)
+
+	LogOffset float64 `json:"logOffset"` // offset added before log scaling, must be > 0 (if 0 -> default 1)
+	LogBase   float64 `json:"logBase"`   // base of the log scaling (if 0 -> default e)
+	LogDB     bool    `json:"logDB"`     // Deprecated: use DBScale; ValidateAndFill turns LogDB into DBScale
+	DBScale   bool    `json:"dbScale"`   // use features.ToDB, 20*log10(max(x, features.DBEpsilon)/DBRef), instead of log_LogBase(x+LogOffset)
+	DBRef     float64 `json:"dbRef"`     // reference magnitude for DBScale (if 0 -> default 1)
+
+	Loop            bool `json:"loop"`            // treat the signal as a seamless loop: frames wrap the tail into the head (overrides AdaptiveFraming)
+	AdaptiveFraming bool `json:"adaptiveFraming"` // align frames to detected onsets, with fixed-hop fill frames in between
+
+	FrameGateDB      float64 `json:"frameGateDB"`      // drop frames more than this many dB below the loudest frame (0 = disabled)
+	FrameTrimPercent float64 `json:"frameTrimPercent"` // aggregate without the quietest and loudest this-many percent of frames by energy, in [0, 50) (0 = all frames)
+
+	MaxClippedFraction float64 `json:"maxClippedFraction"` // reject input whose audio.DetectClipping fraction exceeds this, in [0, 1] (0 = only report it in Analysis.ClippedFraction)
+
+	TieDither     float64 `json:"tieDither"`     // deterministic tie-breaking dither, as a fraction of the feature range (0 = disabled)
+	ThresholdTrim float64 `json:"thresholdTrim"` // threshold bits at the mean after trimming this fraction from each end, < 0.5 (0 = median)
+
+	StereoBits int `json:"stereoBits"` // low hash bits replaced by a stereo correlation code; mono and pcm input get code 0 (0 = disabled)
+
+	MultiResolution []int `json:"multiResolution,omitempty"` // frame sizes to hash at and concatenate, e.g. [512, 2048, 8192]; Hop and FFTSize scale with each, NumBins is shared (empty = FrameSize only)
+}
+
+// DefaultCanonicalRate is the Config.CanonicalRate used by CanonicalConfig. At
+// 22050Hz content up to 11kHz is kept, well above the band the default hash covers,
+// and 44.1kHz input decimates to it by an integer factor.
+const DefaultCanonicalRate = 22050
+
+// CanonicalConfig returns the defaults for hashing at DefaultCanonicalRate, with raw
+// PCM input read at sr. Framing and bins are those of DefaultConfig(DefaultCanonicalRate)
+// whatever sr is, so all hashes made with it are comparable; content above the
+// canonical Nyquist (11025Hz) is ignored.
+func CanonicalConfig(sr int) Config {
+	c := DefaultConfig(DefaultCanonicalRate)
+	if sr > 0 {
+		c.SampleRate = sr
+	} else {
+		c.SampleRate = 44100
+	}
+	c.CanonicalRate = DefaultCanonicalRate
+	return c
+}
+
+// DefaultBandHz is the upper edge of the frequency band covered by the default NumBins.
+// It is the band 64 bins span at 44.1kHz with 2048-sample frames (64 * 44100/2048 Hz),
+// so the classic default is unchanged while other sample rates cover the same 0–1378Hz.
+//
+// A wider default such as 0–8kHz does not fit one linear bin per hash bit: at 44.1kHz
+// with 2048-sample frames it spans 371 bins. It needs the bins pooled into bands,
+// which MaxHz already does (MaxHz 8000 pools 0–8kHz into NumBins bands); making that
+// the default would change every existing default hash.
+const DefaultBandHz = 64 * 44100.0 / 2048
+
+// maxDefaultBins caps the default bin count at the hash width.
+const maxDefaultBins = hash.HashBits
+
+// DefaultNumBins returns the number of FFT bins covering 0..DefaultBandHz for the given
+// sample rate and frame size, clamped to 1..hash.HashBits and frameSize/2.
+// At low sample rates with large frames the cap wins and the covered band is narrower.
+func DefaultNumBins(sr, frameSize int) int {
+	if sr <= 0 || frameSize <= 0 {
+		return maxDefaultBins
+	}
+	bins := int(math.Round(DefaultBandHz * float64(frameSize) / float64(sr)))
+	if bins > maxDefaultBins {
+		bins = maxDefaultBins
+	}
+	if bins > frameSize/2 {
+		bins = frameSize / 2
+	}
+	if bins < 1 {
+		bins = 1
+	}
+	return bins
 }
 
 // DefaultConfig returns common defaults.
 func DefaultConfig(sr int) Config {
 	const defaultFrame = 2048
-	const defaultBins = 64
 	if sr <= 0 {
 		sr = 44100
 	}
 	return Config{
 		SampleRate: sr,
+		Algorithm:  AlgorithmSpectrum,
 		FrameSize:  defaultFrame,
 		Hop:        defaultFrame / 2,
-		NumBins:    defaultBins,
+		NumBins:    DefaultNumBins(sr, defaultFrame),
+		Window:     "hann",
+		LogOffset:  1,
+		LogBase:    math.E,
 	}
 }
 
-// ValidateAndFill normalizes zero values and checks constraints.
+// ValidateAndFill normalizes zero values and checks constraints under the default
+// Limits.
 func (c *Config) ValidateAndFill() error {
+	return c.ValidateAndFillWith(Limits{})
+}
+
+// ValidateAndFillWith is ValidateAndFill with sizes capped by limits.
+func (c *Config) ValidateAndFillWith(limits Limits) error {
+	maxFrameSize := limits.maxFrameSize()
 	if c.SampleRate <= 0 {
-		return errors.New("sample rate must be > 0")
+		return fmt.Errorf("%w: sample rate must be > 0", ErrInvalidConfig)
+	}
+	if c.CanonicalRate < 0 {
+		return fmt.Errorf("%w: canonical rate must be >= 0 (got %d)", ErrInvalidConfig, c.CanonicalRate)
+	}
+	switch c.Algorithm {
+	case "":
+		c.Algorithm = AlgorithmSpectrum
+	case AlgorithmSpectrum, AlgorithmMelDCT:
+	default:
+		return fmt.Errorf("%w: unknown algorithm %q (want %q or %q)", ErrInvalidConfig, c.Algorithm, AlgorithmSpectrum, AlgorithmMelDCT)
 	}
 	if c.FrameSize <= 0 {
 		c.FrameSize = 2048
@@ -40,15 +203,298 @@ func (c *Config) ValidateAndFill() error {
 		c.Hop = c.FrameSize / 2
 	}
 	if c.Hop <= 0 || c.Hop > c.FrameSize {
-		return errors.New("invalid hop: must be 1..FrameSize")
+		return fmt.Errorf("%w: invalid hop: must be 1..FrameSize", ErrInvalidConfig)
 	}
 	if !isPowerOfTwo(c.FrameSize) {
-		return fmt.Errorf("frameSize must be a power of two (got %d)", c.FrameSize)
+		return fmt.Errorf("%w: frameSize must be a power of two (got %d)", ErrInvalidConfig, c.FrameSize)
+	}
+	if c.FrameSize > maxFrameSize {
+		return fmt.Errorf("%w: frameSize %d exceeds MaxFrameSize %d", ErrInvalidConfig, c.FrameSize, maxFrameSize)
+	}
+	if c.FFTSize == 0 {
+		c.FFTSize = c.FrameSize
+	}
+	if !isPowerOfTwo(c.FFTSize) || c.FFTSize < c.FrameSize {
+		return fmt.Errorf("%w: fftSize must be a power of two >= frameSize %d (got %d)", ErrInvalidConfig, c.FrameSize, c.FFTSize)
+	}
+	if c.FFTSize > maxFrameSize {
+		return fmt.Errorf("%w: fftSize %d exceeds MaxFrameSize %d", ErrInvalidConfig, c.FFTSize, maxFrameSize)
+	}
+	if c.NumBins == 0 {
+		c.NumBins = DefaultNumBins(c.SampleRate, c.FFTSize)
+	}
+	if c.NumBins < 0 {
+		return fmt.Errorf("%w: numBins must be >= 0 (got %d)", ErrInvalidConfig, c.NumBins)
+	}
+	switch c.Window {
+	case "":
+		c.Window = "hann"
+	case "hann", "hamming", "blackman-harris", "kaiser":
+	default:
+		return fmt.Errorf("%w: unknown window %q (want \"hann\", \"hamming\", \"blackman-harris\" or \"kaiser\")", ErrInvalidConfig, c.Window)
+	}
+	switch c.FFTBackend {
+	case "":
+		c.FFTBackend = "gonum"
+	case "gonum", "purego":
+	default:
+		return fmt.Errorf("%w: unknown fftBackend %q (want \"gonum\" or \"purego\")", ErrInvalidConfig, c.FFTBackend)
+	}
+	if c.KaiserBeta < 0 || math.IsNaN(c.KaiserBeta) {
+		return fmt.Errorf("%w: kaiserBeta must be >= 0 (got %g)", ErrInvalidConfig, c.KaiserBeta)
+	}
+	if c.KaiserBeta == 0 {
+		c.KaiserBeta = audio.DefaultKaiserBeta
+	}
+	if !(c.MinHz >= 0) || !(c.MaxHz >= 0) || math.IsInf(c.MinHz, 0) || math.IsInf(c.MaxHz, 0) {
+		return fmt.Errorf("%w: minHz/maxHz must be finite and >= 0 (got %g, %g)", ErrInvalidConfig, c.MinHz, c.MaxHz)
+	}
+	if c.HasBand() {
+		nyquist := float64(c.SampleRate) / 2
+		if c.MaxHz > nyquist {
+			return fmt.Errorf("%w: maxHz %g above Nyquist %g", ErrInvalidConfig, c.MaxHz, nyquist)
+		}
+		if c.MaxHz > 0 && c.MinHz >= c.MaxHz {
+			return fmt.Errorf("%w: minHz must be < maxHz (got %g, %g)", ErrInvalidConfig, c.MinHz, c.MaxHz)
+		}
+		if lo, hi := c.BandBins(); hi-lo < 1 {
+			return fmt.Errorf("%w: band %g-%gHz contains no FFT bins at %dHz/%d", ErrInvalidConfig, c.MinHz, c.MaxHz, c.SampleRate, c.FFTSize)
+		}
+	}
+	if c.LowHigh {
+		if c.LogBands || c.HasBand() {
+			return fmt.Errorf("%w: lowHigh cannot be combined with logBands or minHz/maxHz", ErrInvalidConfig)
+		}
+		lo, _ := c.BandBins()
+		if c.NumBins < 2 || lo+c.NumBins/2 >= c.FFTSize/2 {
+			return fmt.Errorf("%w: lowHigh needs 2 <= numBins with the low half below Nyquist (got %d)", ErrInvalidConfig, c.NumBins)
+		}
+	}
+	switch c.Normalize {
+	case "":
+		c.Normalize = "peak"
+	case "peak", "rms", "percentile":
+	default:
+		return fmt.Errorf("%w: unknown normalize %q (want \"peak\", \"rms\" or \"percentile\")", ErrInvalidConfig, c.Normalize)
+	}
+	if c.NormalizePercentile == 0 {
+		c.NormalizePercentile = 99.5
+	}
+	if !(c.NormalizePercentile > 0 && c.NormalizePercentile <= 100) {
+		return fmt.Errorf("%w: normalizePercentile must be in (0, 100] (got %g)", ErrInvalidConfig, c.NormalizePercentile)
+	}
+	if c.Channel < 0 {
+		return fmt.Errorf("%w: channel must be >= 0 (got %d)", ErrInvalidConfig, c.Channel)
+	}
+	if c.ResampleTaps != 0 && (c.ResampleTaps < 0 || c.ResampleTaps%2 == 0 || c.ResampleTaps > maxFrameSize) {
+		return fmt.Errorf("%w: resampleTaps must be odd, positive and <= MaxFrameSize (got %d)", ErrInvalidConfig, c.ResampleTaps)
+	}
+	if c.Speaker != "" {
+		if _, ok := audio.SpeakerBit(c.Speaker); !ok {
+			return fmt.Errorf("%w: unknown speaker %q", ErrInvalidConfig, c.Speaker)
+		}
+		if c.Channel > 0 {
+			return fmt.Errorf("%w: speaker and channel are mutually exclusive", ErrInvalidConfig)
+		}
+	}
+	if !(c.MagnitudeFloor >= 0) || math.IsInf(c.MagnitudeFloor, 0) {
+		return fmt.Errorf("%w: magnitudeFloor must be finite and >= 0 (got %g)", ErrInvalidConfig, c.MagnitudeFloor)
+	}
+	if c.LogOffset == 0 {
+		c.LogOffset = 1
+	}
+	if !(c.LogOffset > 0) || math.IsInf(c.LogOffset, 0) {
+		return fmt.Errorf("%w: logOffset must be finite and > 0 (got %g)", ErrInvalidConfig, c.LogOffset)
+	}
+	if c.LogBase == 0 {
+		c.LogBase = math.E
+	}
+	if !(c.LogBase > 0) || c.LogBase == 1 || math.IsInf(c.LogBase, 0) {
+		return fmt.Errorf("%w: logBase must be finite, > 0 and != 1 (got %g)", ErrInvalidConfig, c.LogBase)
+	}
+	if c.LogDB {
+		// the single dB path; LogOffset and LogBase do not apply to it
+		c.DBScale, c.LogDB = true, false
+	}
+	if c.DBRef == 0 {
+		c.DBRef = 1
+	}
+	if !(c.DBRef > 0) || math.IsInf(c.DBRef, 0) {
+		return fmt.Errorf("%w: dbRef must be finite and > 0 (got %g)", ErrInvalidConfig, c.DBRef)
+	}
+	if !(c.FrameGateDB >= 0) || math.IsInf(c.FrameGateDB, 0) {
+		return fmt.Errorf("%w: frameGateDB must be finite and >= 0 (got %g)", ErrInvalidConfig, c.FrameGateDB)
+	}
+	if !(c.TieDither >= 0) || math.IsInf(c.TieDither, 0) {
+		return fmt.Errorf("%w: tieDither must be finite and >= 0 (got %g)", ErrInvalidConfig, c.TieDither)
+	}
+	if c.MaxClippedFraction < 0 || c.MaxClippedFraction > 1 || math.IsNaN(c.MaxClippedFraction) {
+		return fmt.Errorf("%w: maxClippedFraction must be in [0, 1] (got %g)", ErrInvalidConfig, c.MaxClippedFraction)
+	}
+	if c.FrameTrimPercent < 0 || c.FrameTrimPercent >= 50 || math.IsNaN(c.FrameTrimPercent) {
+		return fmt.Errorf("%w: frameTrimPercent must be in [0, 50) (got %g)", ErrInvalidConfig, c.FrameTrimPercent)
+	}
+	if c.ThresholdTrim < 0 || c.ThresholdTrim >= 0.5 || math.IsNaN(c.ThresholdTrim) {
+		return fmt.Errorf("%w: thresholdTrim must be in [0, 0.5) (got %g)", ErrInvalidConfig, c.ThresholdTrim)
+	}
+	if len(c.MultiResolution) > MaxMultiResolution {
+		return fmt.Errorf("%w: multiResolution allows at most %d frame sizes (got %d)", ErrInvalidConfig, MaxMultiResolution, len(c.MultiResolution))
+	}
+	for _, n := range c.MultiResolution {
+		if !isPowerOfTwo(n) || n > maxFrameSize {
+			return fmt.Errorf("%w: multiResolution frame size %d must be a power of two <= MaxFrameSize %d", ErrInvalidConfig, n, maxFrameSize)
+		}
+	}
+	if c.StereoBits < 0 || c.StereoBits > 8 {
+		return fmt.Errorf("%w: stereoBits must be 0..8 (got %d)", ErrInvalidConfig, c.StereoBits)
 	}
 	return nil
 }
 
+// HasBand reports whether a Hz band restricts feature extraction.
+func (c *Config) HasBand() bool {
+	return c.MinHz > 0 || c.MaxHz > 0
+}
+
+// BandBins converts MinHz/MaxHz to the half-open FFT bin range [lo, hi) for the
+// configured sample rate and FFT size. Without a band it returns [0, NumBins),
+// shifted up by one unless KeepDCBin is set. lo is never 0 without KeepDCBin.
+func (c *Config) BandBins() (lo, hi int) {
+	if !c.HasBand() {
+		if c.KeepDCBin {
+			return 0, c.NumBins
+		}
+		return 1, c.NumBins + 1
+	}
+	n := c.FFTLen()
+	binHz := float64(c.SampleRate) / float64(n)
+	maxHz := c.MaxHz
+	if maxHz == 0 {
+		maxHz = float64(c.SampleRate) / 2
+	}
+	lo = int(math.Ceil(c.MinHz / binHz))
+	hi = int(math.Floor(maxHz/binHz)) + 1
+	if !c.KeepDCBin && lo < 1 {
+		lo = 1
+	}
+	if hi > n/2 {
+		hi = n / 2
+	}
+	return lo, hi
+}
+
+// FeatureBinHz returns the centre frequency in Hz of feature bin i (0 <= i < NumBins)
+// for the configured bin selection: FFT bin centres for linear bins, the middle of
+// the pooled bins for a Hz band, and the geometric centre of log-spaced bands.
+func (c *Config) FeatureBinHz(i int) float64 {
+	binHz := float64(c.SampleRate) / float64(c.FFTLen())
+	nyquist := float64(c.SampleRate) / 2
+	logCentre := func(k, n int, minHz, maxHz float64) float64 {
+		return minHz * math.Pow(maxHz/minHz, (float64(k)+0.5)/float64(n))
+	}
+	lo, hi := c.BandBins()
+	switch {
+	case c.LowHigh:
+		nLow := c.NumBins / 2
+		if i < nLow {
+			return float64(lo+i) * binHz
+		}
+		return logCentre(i-nLow, c.NumBins-nLow, float64(lo+nLow)*binHz, nyquist)
+	case c.LogBands:
+		minHz, maxHz := binHz, nyquist
+		if c.MinHz > 0 {
+			minHz = c.MinHz
+		}
+		if c.MaxHz > 0 {
+			maxHz = c.MaxHz
+		}
+		return logCentre(i, c.NumBins, minHz, maxHz)
+	case c.HasBand():
+		width := float64(hi-lo) / float64(c.NumBins)
+		return (float64(lo) + (float64(i)+0.5)*width - 0.5) * binHz
+	}
+	return float64(lo+i) * binHz
+}
+
+// FFTLen returns the FFT length: FFTSize, or FrameSize before ValidateAndFill has filled it.
+func (c *Config) FFTLen() int {
+	if c.FFTSize > 0 {
+		return c.FFTSize
+	}
+	return c.FrameSize
+}
+
+// Digest returns 16 hex digits identifying the options that change the hash: c is
+// validated and filled (on a copy), SampleRate is replaced by CanonicalRate when that
+// is set, and the JSON encoding is hashed without the fields that only describe or
+// screen the input (PlanarWAV, MaxClippedFraction), the deprecated LogDB, and
+// FFTBackend, whose backends agree up to float rounding. Two configs with equal
+// digests hash the same audio to the same value.
+func (c Config) Digest() (string, error) {
+	if c.CanonicalRate > 0 {
+		c.SampleRate = c.CanonicalRate
+		c.CanonicalRate = 0
+	}
+	// nothing is allocated here, so any size the pipeline accepted is digested
+	if err := c.ValidateAndFillWith(Limits{MaxFrameSize: math.MaxInt}); err != nil {
+		return "", err
+	}
+	c.PlanarWAV = false
+	c.MaxClippedFraction = 0
+	c.LogDB = false
+	c.FFTBackend = ""
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// PresetTelephony returns a config for narrowband telephone audio: features come
+// only from the 300–3400Hz speech band, pooled into 64 sub-bands.
+func PresetTelephony(sr int) Config {
+	if sr <= 0 {
+		sr = 8000
+	}
+	c := DefaultConfig(sr)
+	c.FrameSize = 512
+	c.Hop = 256
+	c.NumBins = 64
+	c.MinHz = 300
+	c.MaxHz = 3400
+	return c
+}
+
 // isPowerOfTwo returns true if x is power-of-two.
 func isPowerOfTwo(x int) bool {
 	return x > 0 && (x&(x-1)) == 0
 }
+
+// Save validates c, fills defaults, and writes the fully-resolved config to path as JSON.
+func Save(c Config, path string) error {
+	if err := c.ValidateAndFill(); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Load reads a JSON config written by Save and re-validates it.
+func Load(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Config{}, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, path, err)
+	}
+	if err := c.ValidateAndFill(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,52 @@
+package features
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrLengthMismatch is returned when two feature vectors have different lengths.
+var ErrLengthMismatch = errors.New("feature vectors have different lengths")
+
+// Distance returns the Euclidean (L2) distance between two feature vectors.
+func Distance(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, ErrLengthMismatch
+	}
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum), nil
+}
+
+// ManhattanDistance returns the L1 distance between two feature vectors.
+func ManhattanDistance(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, ErrLengthMismatch
+	}
+	sum := 0.0
+	for i := range a {
+		sum += math.Abs(a[i] - b[i])
+	}
+	return sum, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between two feature vectors in [-1, +1].
+// Returns 0 if either vector has zero norm.
+func CosineSimilarity(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, ErrLengthMismatch
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0, nil
+	}
+	return dot / math.Sqrt(na*nb), nil
+}
@@ -1,6 +1,7 @@
 package features
 
 import (
+	"fmt"
 	"math"
 	"sort"
 )
@@ -30,6 +31,82 @@ func ExtractGlobalFeature(frameMags [][]float64, numBins int) []float64 {
 	return globalFeature
 }
 
+// ExtractPerFrame returns one feature vector per frame: a copy of the first numBins
+// magnitudes of each frame, clamped like ExtractGlobalFeature. It is the per-frame
+// counterpart of the global aggregation, for fingerprint sequences and spectrogram
+// views. Returns nil if there are no frames or numBins <= 0.
+func ExtractPerFrame(frameMags [][]float64, numBins int) [][]float64 {
+	if len(frameMags) == 0 || numBins <= 0 {
+		return nil
+	}
+	if numBins > len(frameMags[0]) {
+		numBins = len(frameMags[0])
+	}
+
+	out := make([][]float64, len(frameMags))
+	for i, f := range frameMags {
+		out[i] = append([]float64(nil), f[:numBins]...)
+	}
+	return out
+}
+
+// ExtractPerFrameLog is ExtractPerFrame with each vector log-scaled by LogScaleFeature.
+func ExtractPerFrameLog(frameMags [][]float64, numBins int) [][]float64 {
+	out := ExtractPerFrame(frameMags, numBins)
+	for _, f := range out {
+		LogScaleFeature(f)
+	}
+	return out
+}
+
+// SilenceEpsilon is the magnitude below which every feature value counts as silence.
+const SilenceEpsilon = 1e-9
+
+// IsSilent reports whether every value of an (unscaled) feature is below SilenceEpsilon,
+// i.e. the median threshold would degenerate into an all-zero hash.
+func IsSilent(feature []float64) bool {
+	for _, v := range feature {
+		if math.Abs(v) >= SilenceEpsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// GainRatio estimates the gain that brings feature x to the level of ref: the median
+// of ref[i]/x[i] over the bins where both are at least SilenceEpsilon. A median is
+// used so that a few bins the two inputs do not share (a click, an extra instrument)
+// do not move the estimate. It returns 1 when no bin qualifies.
+func GainRatio(ref, x []float64) float64 {
+	n := min(len(ref), len(x))
+	ratios := make([]float64, 0, n)
+	for i := 0; i < n; i++ {
+		if ref[i] >= SilenceEpsilon && x[i] >= SilenceEpsilon {
+			ratios = append(ratios, ref[i]/x[i])
+		}
+	}
+	if len(ratios) == 0 {
+		return 1
+	}
+	return Median(ratios)
+}
+
+// NormalizeL2 scales feature in place to unit L2 norm (Parseval: proportional to total
+// spectral energy), so the vector is independent of overall level. Zero vectors are left unchanged.
+func NormalizeL2(feature []float64) {
+	var sum float64
+	for _, v := range feature {
+		sum += v * v
+	}
+	if sum == 0 {
+		return
+	}
+	scale := 1 / math.Sqrt(sum)
+	for i := range feature {
+		feature[i] *= scale
+	}
+}
+
 // Optional: apply log scaling for perceptual robustness
 func LogScaleFeature(feature []float64) {
 	for i := range feature {
@@ -37,6 +114,129 @@ func LogScaleFeature(feature []float64) {
 	}
 }
 
+// ApplyMagnitudeFloor raises every value below floor to floor, in place, so bins in
+// near-silent bands tie at the floor instead of ordering by numerical noise.
+// floor <= 0 leaves the feature unchanged.
+func ApplyMagnitudeFloor(feature []float64, floor float64) {
+	if floor <= 0 {
+		return
+	}
+	for i, v := range feature {
+		if v < floor {
+			feature[i] = floor
+		}
+	}
+}
+
+// RemoveTrend fits a least-squares polynomial of the given degree to feature over
+// its bin index and subtracts it in place, leaving only the detail around the
+// fitted curve. On a log-scaled feature this removes a smooth spectral tilt, such
+// as a microphone or codec response, before thresholding. Features with no more
+// than degree+1 values, or degree < 0, are left unchanged.
+func RemoveTrend(feature []float64, degree int) {
+	n := len(feature)
+	if degree < 0 || n <= degree+1 {
+		return
+	}
+	m := degree + 1
+	// normal equations on x in [-1, 1], which keeps them well conditioned
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = 2*float64(i)/float64(n-1) - 1
+	}
+	a := make([][]float64, m)
+	for r := range a {
+		a[r] = make([]float64, m+1)
+	}
+	for i, y := range feature {
+		pr := 1.0
+		for r := 0; r < m; r++ {
+			pc := 1.0
+			for c := 0; c < m; c++ {
+				a[r][c] += pr * pc
+				pc *= x[i]
+			}
+			a[r][m] += pr * y
+			pr *= x[i]
+		}
+	}
+	// Gauss-Jordan elimination with partial pivoting
+	for c := 0; c < m; c++ {
+		p := c
+		for r := c + 1; r < m; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		a[c], a[p] = a[p], a[c]
+		if a[c][c] == 0 {
+			return
+		}
+		for r := 0; r < m; r++ {
+			if r == c {
+				continue
+			}
+			f := a[r][c] / a[c][c]
+			for k := c; k <= m; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+		}
+	}
+	for i := range feature {
+		fit, pw := 0.0, 1.0
+		for c := 0; c < m; c++ {
+			fit += a[c][m] / a[c][c] * pw
+			pw *= x[i]
+		}
+		feature[i] -= fit
+	}
+}
+
+// LogScaleFeatureWith applies log_base(offset + x) in place.
+// offset must be > 0 and base must be > 0 and != 1 (see config.ValidateAndFill).
+func LogScaleFeatureWith(feature []float64, offset, base float64) {
+	lb := math.Log(base)
+	for i := range feature {
+		feature[i] = math.Log(offset+feature[i]) / lb
+	}
+}
+
+// DBScaleFeature converts magnitudes to decibels in place: 20*log10(x + eps).
+// eps must be > 0 to avoid log(0).
+//
+// Deprecated: use ToDB, the dB scale the pipeline applies. With an offset eps of
+// 1 this is not a level in dB: every bin is at least 0.
+func DBScaleFeature(feature []float64, eps float64) {
+	for i := range feature {
+		feature[i] = 20 * math.Log10(feature[i]+eps)
+	}
+}
+
+// DBEpsilon is the magnitude floor used by ToDB, so silent bins map to a finite
+// level (-180 dB re 1) instead of -Inf. It matches SilenceEpsilon.
+const DBEpsilon = SilenceEpsilon
+
+// ToDB converts magnitudes to decibels relative to ref: 20*log10(max(x, DBEpsilon)/ref).
+// With ref = 1 and samples in [-1, 1] the result is in dBFS. ref must be > 0.
+func ToDB(mags []float64, ref float64) []float64 {
+	out := make([]float64, len(mags))
+	for i, x := range mags {
+		out[i] = 20 * math.Log10(math.Max(x, DBEpsilon)/ref)
+	}
+	return out
+}
+
+// PowerToDB is ToDB for a power spectrum (squared magnitudes): 10*log10(max(x,
+// DBEpsilon^2)/ref^2), so the levels equal ToDB of the magnitudes. ref is still a
+// magnitude and must be > 0.
+func PowerToDB(power []float64, ref float64) []float64 {
+	out := make([]float64, len(power))
+	for i, x := range power {
+		out[i] = 10 * math.Log10(math.Max(x, DBEpsilon*DBEpsilon)/(ref*ref))
+	}
+	return out
+}
+
 // AggregateGlobalFeature aggregates per-frame magnitude spectra into a single global feature vector.
 // Uses mean across frames per bin. Optionally clamp to NumBins.
 func AggregateGlobalFeature(frameMags [][]float64, numBins int) []float64 {
@@ -78,17 +278,62 @@ func AggregateGlobalFeatureMedian(frameMags [][]float64, numBins int) []float64
 		for i, f := range frameMags {
 			values[i] = f[bin]
 		}
-		globalFeature[bin] = median(values)
+		globalFeature[bin] = Median(values)
 	}
 
 	return globalFeature
 }
 
-// median computes median of float64 slice
-func median(arr []float64) float64 {
+// AggregateTrimmedByEnergy is AggregateGlobalFeatureMedian over the "middle" frames
+// by energy: frames are ranked by the sum of their squared magnitudes, and the
+// quietest and loudest trimPercent of them are dropped before taking the per-bin
+// median. One pass rejects both near-silent frames and clipped transients. Since
+// trimPercent < 50 drops fewer than half of the frames from each end, at least one
+// frame is always kept. trimPercent must be in [0, 50); 0 is the plain median.
+func AggregateTrimmedByEnergy(frameMags [][]float64, numBins int, trimPercent float64) ([]float64, error) {
+	if !(trimPercent >= 0 && trimPercent < 50) {
+		return nil, fmt.Errorf("trimPercent must be in [0, 50) (got %g)", trimPercent)
+	}
+	n := len(frameMags)
+	drop := int(float64(n) * trimPercent / 100)
+	if drop == 0 {
+		return AggregateGlobalFeatureMedian(frameMags, numBins), nil
+	}
+
+	energy := make([]float64, n)
+	idx := make([]int, n)
+	for i, f := range frameMags {
+		for _, v := range f {
+			energy[i] += v * v
+		}
+		idx[i] = i
+	}
+	// stable, so frames of equal energy are trimmed in time order
+	sort.SliceStable(idx, func(a, b int) bool { return energy[idx[a]] < energy[idx[b]] })
+
+	kept := idx[drop : n-drop]
+	sort.Ints(kept)
+	middle := make([][]float64, len(kept))
+	for j, i := range kept {
+		middle[j] = frameMags[i]
+	}
+	return AggregateGlobalFeatureMedian(middle, numBins), nil
+}
+
+// EmptyMedian is what Median returns for an empty slice. Thresholding a feature
+// against it sets a bit for every positive value, the same as an all-zero feature
+// padded out to the hash length would.
+const EmptyMedian = 0.0
+
+// Median returns the median of arr without modifying it: the middle value of the
+// sorted slice, or for an even length the mean of the two middle values, so ties
+// resolve the same way in every caller. An empty slice yields EmptyMedian. A feature
+// whose values all equal the median has no bin strictly above it and therefore
+// thresholds to an all-zero hash; callers reject that case first (see IsSilent).
+func Median(arr []float64) float64 {
 	n := len(arr)
 	if n == 0 {
-		return 0
+		return EmptyMedian
 	}
 
 	sorted := make([]float64, n)
@@ -100,3 +345,193 @@ func median(arr []float64) float64 {
 	}
 	return sorted[n/2]
 }
+
+// QuantizeCorrelation maps an inter-channel correlation to a bits-wide code.
+// Code 0 is reserved for mono input; stereo correlations in [-1, +1] map to 1..2^bits-1,
+// so a genuine mono file and a highly correlated stereo file get different codes.
+func QuantizeCorrelation(corr float64, mono bool, bits int) uint64 {
+	if bits <= 0 || mono {
+		return 0
+	}
+	levels := uint64(1)<<uint(bits) - 1
+	if levels == 1 {
+		return 1
+	}
+	x := (corr + 1) / 2
+	if x < 0 {
+		x = 0
+	}
+	if x > 1 {
+		x = 1
+	}
+	return 1 + uint64(math.Round(x*float64(levels-1)))
+}
+
+// PoolBands splits bins [lo, hi) of mags into n equal-width sub-bands and returns the
+// mean magnitude of each. Sub-bands narrower than one bin reuse the nearest bin, so the
+// output always has n values. Returns nil if the range is empty or n <= 0.
+func PoolBands(mags []float64, lo, hi, n int) []float64 {
+	if hi > len(mags) {
+		hi = len(mags)
+	}
+	if lo < 0 {
+		lo = 0
+	}
+	if n <= 0 || hi <= lo {
+		return nil
+	}
+
+	out := make([]float64, n)
+	width := float64(hi-lo) / float64(n)
+	for k := 0; k < n; k++ {
+		start := lo + int(float64(k)*width)
+		end := lo + int(float64(k+1)*width)
+		if end <= start {
+			end = start + 1
+		}
+		if end > hi {
+			end = hi
+		}
+		if start >= end {
+			start = end - 1
+		}
+		sum := 0.0
+		for _, v := range mags[start:end] {
+			sum += v
+		}
+		out[k] = sum / float64(end-start)
+	}
+	return out
+}
+
+// LogFrequencyBands groups FFT magnitudes into numBands logarithmically spaced bands
+// from the first non-DC bin (sampleRate/frameSize Hz) up to Nyquist, returning the
+// mean magnitude per band. See LogFrequencyBandsRange.
+func LogFrequencyBands(mags []float64, sampleRate, frameSize, numBands int) []float64 {
+	if sampleRate <= 0 || frameSize <= 0 {
+		return nil
+	}
+	binHz := float64(sampleRate) / float64(frameSize)
+	return LogFrequencyBandsRange(mags, sampleRate, frameSize, numBands, binHz, float64(sampleRate)/2)
+}
+
+// LogFrequencyBandsRange is LogFrequencyBands over [minHz, maxHz]. Band k spans
+// minHz*(maxHz/minHz)^(k/numBands) to the next edge. Low bands narrower than one FFT
+// bin contain no bin centre; they take the bin nearest the band's geometric centre,
+// so neighbouring low bands may repeat the same value. Returns nil on invalid input.
+func LogFrequencyBandsRange(mags []float64, sampleRate, frameSize, numBands int, minHz, maxHz float64) []float64 {
+	if len(mags) == 0 || sampleRate <= 0 || frameSize <= 0 || numBands <= 0 || minHz <= 0 || maxHz <= minHz {
+		return nil
+	}
+	binHz := float64(sampleRate) / float64(frameSize)
+	ratio := maxHz / minHz
+
+	out := make([]float64, numBands)
+	for k := 0; k < numBands; k++ {
+		lo := minHz * math.Pow(ratio, float64(k)/float64(numBands))
+		hi := minHz * math.Pow(ratio, float64(k+1)/float64(numBands))
+
+		first := int(math.Ceil(lo / binHz))
+		last := int(math.Ceil(hi/binHz)) - 1 // bin centres in [lo, hi)
+		if last >= len(mags) {
+			last = len(mags) - 1
+		}
+		if first > last {
+			// band narrower than a bin: nearest bin to the geometric centre
+			c := int(math.Round(math.Sqrt(lo*hi) / binHz))
+			if c >= len(mags) {
+				c = len(mags) - 1
+			}
+			out[k] = mags[c]
+			continue
+		}
+		sum := 0.0
+		for _, v := range mags[first : last+1] {
+			sum += v
+		}
+		out[k] = sum / float64(last-first+1)
+	}
+	return out
+}
+
+// LowHighBands builds a full-spectrum descriptor of numBins values: the first numBins/2
+// are linear FFT bins starting at bin lo, the rest are log-spaced bands from the end of
+// that low region up to Nyquist. High-frequency content (cymbals, sibilance) then
+// contributes without growing the hash. Returns nil on invalid input or when the low
+// region leaves no room below Nyquist.
+func LowHighBands(mags []float64, sampleRate, frameSize, lo, numBins int) []float64 {
+	nLow := numBins / 2
+	nHigh := numBins - nLow
+	if lo < 0 || nLow <= 0 || lo+nLow >= len(mags) {
+		return nil
+	}
+	binHz := float64(sampleRate) / float64(frameSize)
+	high := LogFrequencyBandsRange(mags, sampleRate, frameSize, nHigh, float64(lo+nLow)*binHz, float64(sampleRate)/2)
+	if high == nil {
+		return nil
+	}
+	out := make([]float64, 0, numBins)
+	out = append(out, mags[lo:lo+nLow]...)
+	return append(out, high...)
+}
+
+// ResampleFeature maps a feature computed with len(feature) bins onto n bins spanning
+// the same frequency range, so features from configs that differ only in NumBins can
+// be compared. Upsampling interpolates linearly between bin centres; downsampling
+// averages the input bins each output bin covers. Returns nil if feature is empty or n <= 0.
+//
+// This only reconciles bins that split the same band (LogBands, MinHz/MaxHz pooling).
+// Plain FFT bins are not a band split: NumBins=32 is a prefix of NumBins=64 at the
+// same frame size, so compare against feature[:32] instead.
+func ResampleFeature(feature []float64, n int) []float64 {
+	m := len(feature)
+	if m == 0 || n <= 0 {
+		return nil
+	}
+	out := make([]float64, n)
+	ratio := float64(m) / float64(n)
+	if n >= m {
+		for j := range out {
+			x := (float64(j)+0.5)*ratio - 0.5
+			if x <= 0 {
+				out[j] = feature[0]
+				continue
+			}
+			i := int(x)
+			if i >= m-1 {
+				out[j] = feature[m-1]
+				continue
+			}
+			frac := x - float64(i)
+			out[j] = feature[i]*(1-frac) + feature[i+1]*frac
+		}
+		return out
+	}
+	for j := range out {
+		lo, hi := float64(j)*ratio, float64(j+1)*ratio
+		var sum float64
+		for i := int(lo); i < m && float64(i) < hi; i++ {
+			overlap := math.Min(hi, float64(i+1)) - math.Max(lo, float64(i))
+			sum += feature[i] * overlap
+		}
+		out[j] = sum / ratio
+	}
+	return out
+}
+
+// TopK returns the indices of the k largest values of feature, largest first; equal
+// values keep index order. k is clamped to len(feature).
+func TopK(feature []float64, k int) []int {
+	if k > len(feature) {
+		k = len(feature)
+	}
+	if k <= 0 {
+		return nil
+	}
+	idx := make([]int, len(feature))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return feature[idx[a]] > feature[idx[b]] })
+	return idx[:k]
+}
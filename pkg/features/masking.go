@@ -0,0 +1,115 @@
+package features
+
+import "math"
+
+// MaskingOffsetDB is how far below the spread masker energy ApplyMasking puts the
+// masking threshold. Real thresholds depend on tonality (about 5 dB for noise-like
+// maskers, 15-25 dB for tones); a single mid value keeps the model simple.
+const MaskingOffsetDB = 10.0
+
+// HzToBark converts a frequency to the Bark critical-band scale (Zwicker & Terhardt:
+// 13*atan(0.00076 f) + 3.5*atan((f/7500)^2)).
+func HzToBark(hz float64) float64 {
+	return 13*math.Atan(0.00076*hz) + 3.5*math.Atan((hz/7500)*(hz/7500))
+}
+
+// spreadingDB is the Schroeder spreading function: the level in dB at which a
+// masker dz Bark away (maskee minus masker) still contributes. It is 0 at dz = 0 and
+// falls about 25 dB/Bark towards lower frequencies and 10 dB/Bark towards higher
+// ones, since masking spreads upward in frequency.
+func spreadingDB(dz float64) float64 {
+	x := dz + 0.474
+	return 15.81 + 7.5*x - 17.5*math.Sqrt(1+x*x)
+}
+
+// ApplyMasking returns a copy of the magnitude spectrum mags, bins 0..frameSize/2-1
+// of a frameSize-point FFT at sampleRate (frameSize is the FFT length, including
+// zero-padding), with simultaneously masked bins attenuated. It is a simplified
+// Johnston model: bin energy is summed into 1-Bark critical bands, spread across
+// bands with the Schroeder spreading function, lowered by MaskingOffsetDB and shared
+// evenly among each band's bins. A bin whose energy falls below its share keeps
+// only the fraction that is above it (magnitude scaled by sqrt(energy/threshold)),
+// so quiet partials next to loud ones stop moving bits. Audible bins are unchanged.
+//
+// The model is relative to the loudest content: the input has no SPL reference, so
+// the absolute threshold of hearing is not applied, and scaling mags scales the
+// result by the same factor. Returns mags unchanged for invalid parameters.
+func ApplyMasking(mags []float64, sampleRate, frameSize int) []float64 {
+	if len(mags) == 0 || sampleRate <= 0 || frameSize < 2 {
+		return mags
+	}
+	energy := make([]float64, len(mags))
+	for i, m := range mags {
+		energy[i] = m * m
+	}
+	threshold := maskingThresholds(energy, sampleRate, frameSize)
+	out := make([]float64, len(mags))
+	for i, m := range mags {
+		if e, t := energy[i], threshold[i]; e < t {
+			out[i] = m * math.Sqrt(e/t)
+			continue
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// ApplyMaskingPower is ApplyMasking for a power spectrum (squared magnitudes): the
+// result is the square of ApplyMasking applied to the magnitudes.
+func ApplyMaskingPower(power []float64, sampleRate, frameSize int) []float64 {
+	if len(power) == 0 || sampleRate <= 0 || frameSize < 2 {
+		return power
+	}
+	threshold := maskingThresholds(power, sampleRate, frameSize)
+	out := make([]float64, len(power))
+	for i, e := range power {
+		if t := threshold[i]; e < t {
+			out[i] = e * e / t
+			continue
+		}
+		out[i] = e
+	}
+	return out
+}
+
+// maskingThresholds returns the per-bin masking threshold, in energy, for the
+// bin energies of a frameSize-point FFT at sampleRate (see ApplyMasking).
+func maskingThresholds(energy []float64, sampleRate, frameSize int) []float64 {
+	binHz := float64(sampleRate) / float64(frameSize)
+
+	band := make([]int, len(energy))
+	numBands := 0
+	for i := range energy {
+		band[i] = int(HzToBark(float64(i) * binHz))
+		if band[i]+1 > numBands {
+			numBands = band[i] + 1
+		}
+	}
+	bandEnergy := make([]float64, numBands)
+	count := make([]int, numBands)
+	for i, e := range energy {
+		bandEnergy[band[i]] += e
+		count[band[i]]++
+	}
+
+	offset := math.Pow(10, -MaskingOffsetDB/10)
+	bandThreshold := make([]float64, numBands)
+	for b := range bandThreshold {
+		if count[b] == 0 {
+			continue
+		}
+		var spread float64
+		for k, e := range bandEnergy {
+			if e > 0 {
+				spread += e * math.Pow(10, spreadingDB(float64(b-k))/10)
+			}
+		}
+		bandThreshold[b] = spread * offset / float64(count[b])
+	}
+
+	threshold := make([]float64, len(energy))
+	for i := range threshold {
+		threshold[i] = bandThreshold[band[i]]
+	}
+	return threshold
+}
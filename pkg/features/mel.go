@@ -0,0 +1,119 @@
+package features
+
+import "math"
+
+// HzToMel converts a frequency to the mel scale (O'Shaughnessy: 2595*log10(1 + f/700)).
+func HzToMel(hz float64) float64 {
+	return 2595 * math.Log10(1+hz/700)
+}
+
+// MelToHz is the inverse of HzToMel.
+func MelToHz(mel float64) float64 {
+	return 700 * (math.Pow(10, mel/2595) - 1)
+}
+
+// MelFilterbank returns numBands triangular filters, equally spaced on the mel scale
+// over [minHz, maxHz], as weights over the fftSize/2 positive-frequency bins of an
+// FFT at sampleRate. Filter k rises from mel point k to a peak of 1 at point k+1 and
+// falls to zero at point k+2. A filter too narrow to contain any bin centre gets
+// weight 1 on the bin nearest its peak, so every band sees some energy. Returns nil
+// on invalid input.
+func MelFilterbank(numBands, fftSize, sampleRate int, minHz, maxHz float64) [][]float64 {
+	if numBands <= 0 || fftSize < 2 || sampleRate <= 0 || minHz < 0 || maxHz <= minHz {
+		return nil
+	}
+	bins := fftSize / 2
+	binHz := float64(sampleRate) / float64(fftSize)
+	lo, hi := HzToMel(minHz), HzToMel(maxHz)
+	edges := make([]float64, numBands+2)
+	for i := range edges {
+		edges[i] = MelToHz(lo + (hi-lo)*float64(i)/float64(numBands+1))
+	}
+
+	fb := make([][]float64, numBands)
+	for k := range fb {
+		w := make([]float64, bins)
+		left, centre, right := edges[k], edges[k+1], edges[k+2]
+		hit := false
+		for b := range w {
+			f := float64(b) * binHz
+			switch {
+			case f > left && f <= centre:
+				w[b] = (f - left) / (centre - left)
+			case f > centre && f < right:
+				w[b] = (right - f) / (right - centre)
+			}
+			hit = hit || w[b] > 0
+		}
+		if !hit {
+			c := int(math.Round(centre / binHz))
+			if c >= bins {
+				c = bins - 1
+			}
+			w[c] = 1
+		}
+		fb[k] = w
+	}
+	return fb
+}
+
+// ApplyFilterbank returns the weighted sum of spec under each filter of fb.
+// Bins beyond the shorter of spec and a filter are ignored.
+func ApplyFilterbank(spec []float64, fb [][]float64) []float64 {
+	out := make([]float64, len(fb))
+	for k, w := range fb {
+		n := min(len(w), len(spec))
+		var sum float64
+		for b := 0; b < n; b++ {
+			sum += w[b] * spec[b]
+		}
+		out[k] = sum
+	}
+	return out
+}
+
+// DCT2 returns the orthonormal DCT-II of x: X[k] = s(k) * sum x[n] cos(pi/N (n+1/2) k),
+// with s(0) = sqrt(1/N) and s(k) = sqrt(2/N), so the transform preserves energy.
+// It is O(N^2), meant for the small grids of perceptual hashing.
+func DCT2(x []float64) []float64 {
+	n := len(x)
+	out := make([]float64, n)
+	if n == 0 {
+		return out
+	}
+	for k := range out {
+		var sum float64
+		for i, v := range x {
+			sum += v * math.Cos(math.Pi/float64(n)*(float64(i)+0.5)*float64(k))
+		}
+		scale := math.Sqrt(2 / float64(n))
+		if k == 0 {
+			scale = math.Sqrt(1 / float64(n))
+		}
+		out[k] = sum * scale
+	}
+	return out
+}
+
+// DCT2D returns the separable 2D DCT-II of a rectangular grid: DCT2 along each row,
+// then along each column. Coefficient [0][0] is the (scaled) mean; low indices hold
+// the coarse structure an image-style perceptual hash keeps.
+func DCT2D(grid [][]float64) [][]float64 {
+	if len(grid) == 0 {
+		return nil
+	}
+	rows := make([][]float64, len(grid))
+	for i, r := range grid {
+		rows[i] = DCT2(r)
+	}
+	col := make([]float64, len(rows))
+	for j := range rows[0] {
+		for i := range rows {
+			col[i] = rows[i][j]
+		}
+		for i, v := range DCT2(col) {
+			rows[i][j] = v
+		}
+	}
+	return rows
+}
@@ -0,0 +1,71 @@
+package features
+
+import "math"
+
+// Quantize packs a feature into bitsPerBin bits per bin for compact storage.
+// Values are min-max normalized over the feature and rounded to 2^bitsPerBin levels,
+// then packed MSB-first; 64 bins at 2 bits take 16 bytes. The scale is not stored,
+// so Dequantize recovers the shape in [0, 1], not the original values.
+// Returns nil if feature is empty or bitsPerBin is outside 1..8.
+func Quantize(feature []float64, bitsPerBin int) []byte {
+	if len(feature) == 0 || bitsPerBin < 1 || bitsPerBin > 8 {
+		return nil
+	}
+	minv, maxv := feature[0], feature[0]
+	for _, v := range feature {
+		minv = math.Min(minv, v)
+		maxv = math.Max(maxv, v)
+	}
+	levels := float64(uint(1)<<uint(bitsPerBin) - 1)
+
+	out := make([]byte, (len(feature)*bitsPerBin+7)/8)
+	for i, v := range feature {
+		var q uint
+		if maxv > minv {
+			q = uint(math.Round((v - minv) / (maxv - minv) * levels))
+		}
+		for b := 0; b < bitsPerBin; b++ {
+			if q&(1<<uint(bitsPerBin-1-b)) != 0 {
+				pos := i*bitsPerBin + b
+				out[pos/8] |= 0x80 >> uint(pos%8)
+			}
+		}
+	}
+	return out
+}
+
+// Dequantize unpacks n bins written by Quantize with the same bitsPerBin, returning
+// values in [0, 1]. Returns nil if q is too short for n bins or bitsPerBin is outside 1..8.
+func Dequantize(q []byte, bitsPerBin, n int) []float64 {
+	if bitsPerBin < 1 || bitsPerBin > 8 || n < 0 || len(q)*8 < n*bitsPerBin {
+		return nil
+	}
+	levels := float64(uint(1)<<uint(bitsPerBin) - 1)
+	out := make([]float64, n)
+	for i := range out {
+		var v uint
+		for b := 0; b < bitsPerBin; b++ {
+			pos := i*bitsPerBin + b
+			v <<= 1
+			if q[pos/8]&(0x80>>uint(pos%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = float64(v) / levels
+	}
+	return out
+}
+
+// QuantizedDistance returns the Euclidean distance between two quantized features
+// of n bins each, computed on their dequantized [0, 1] values.
+func QuantizedDistance(a, b []byte, bitsPerBin, n int) (float64, error) {
+	if len(a) != len(b) {
+		return 0, ErrLengthMismatch
+	}
+	da := Dequantize(a, bitsPerBin, n)
+	db := Dequantize(b, bitsPerBin, n)
+	if da == nil || db == nil {
+		return 0, ErrLengthMismatch
+	}
+	return Distance(da, db)
+}
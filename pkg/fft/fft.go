@@ -1,12 +1,48 @@
 package fft
 
 import (
+	"errors"
+	"fmt"
 	"math"
+	"sync"
+)
+
+// ErrFrameLength is returned by Plan.Compute for a frame that is empty or longer than
+// the plan length, which would otherwise give a spectrum with a different bin count.
+var ErrFrameLength = errors.New("fft: frame length does not fit plan")
+
+// transform computes the magnitudes of bins 0..n/2-1 of an n-sample frame into
+// mags. Implementations keep work buffers and are not safe for concurrent use.
+type transform interface {
+	magnitudes(frame, mags []float64)
+}
+
+// Backend names an FFT implementation. Both give the same magnitudes up to float
+// rounding.
+type Backend string
 
-	"gonum.org/v1/gonum/dsp/fourier"
+const (
+	// BackendGonum is gonum's FFT, the default. Builds with the purego tag do not
+	// link gonum and use BackendPureGo instead.
+	BackendGonum Backend = "gonum"
+	// BackendPureGo is the self-contained radix-2 FFT.
+	BackendPureGo Backend = "purego"
 )
 
+// GonumAvailable reports whether BackendGonum is compiled in (false with the purego
+// build tag).
+func GonumAvailable() bool { return gonumAvailable }
+
+// newTransform returns a transform of length n for backend.
+func newTransform(n int, backend Backend) transform {
+	if backend == BackendPureGo {
+		return newPureFFT(n)
+	}
+	return newGonumFFT(n)
+}
+
 // ComputeMagnitude computes the FFT of a single frame and returns the magnitude spectrum.
+// The bin count follows len(frame); use a Plan to get a fixed bin count for every frame.
 // Input:
 //
 //	frame []float64 : time-domain samples (length N, typically power of 2)
@@ -15,23 +51,119 @@ import (
 //
 //	[]float64      : magnitudes of bins 0..N/2 (real, non-negative)
 func ComputeMagnitude(frame []float64) []float64 {
-	N := len(frame)
-	if N == 0 {
-		return nil
+	return ComputeMagnitudeN(frame, len(frame))
+}
+
+// ComputePower is ComputeMagnitude squared: the power (energy) spectrum |X[k]|^2 of
+// bins 0..N/2-1. Returns nil if frame is empty.
+func ComputePower(frame []float64) []float64 {
+	return square(ComputeMagnitude(frame))
+}
+
+// square squares mags in place and returns it.
+func square(mags []float64) []float64 {
+	for i, m := range mags {
+		mags[i] = m * m
 	}
+	return mags
+}
 
-	fft := fourier.NewFFT(N)
-	complexResult := fft.Coefficients(nil, frame)
+// ComputeMagnitudeN is like ComputeMagnitude but zero-pads frame to size samples
+// first, giving size/2 bins of finer frequency spacing at the same time resolution.
+// Returns nil if frame is empty or longer than size.
+func ComputeMagnitudeN(frame []float64, size int) []float64 {
+	N := size
+	if len(frame) == 0 || len(frame) > N {
+		return nil
+	}
+	if len(frame) < N {
+		frame = zeroPad(frame, N)
+	}
 
 	// Only need first N/2 bins (positive frequencies)
 	mags := make([]float64, N/2)
-	for i := 0; i < N/2; i++ {
-		mags[i] = cmplxAbs(complexResult[i])
+	newTransform(N, BackendGonum).magnitudes(frame, mags)
+	return mags
+}
+
+// Plan holds reusable FFT state for a fixed frame length and backend.
+// Transforms keep internal work buffers, so instances are pooled and a Plan is
+// safe for concurrent use.
+type Plan struct {
+	n       int
+	backend Backend
+	pool    sync.Pool
+}
+
+// NewPlan returns a Plan for frames of length n using BackendGonum.
+func NewPlan(n int) *Plan {
+	return NewPlanWith(n, BackendGonum)
+}
+
+// NewPlanWith returns a Plan for frames of length n using backend; an empty backend
+// means BackendGonum. In purego builds every backend is BackendPureGo.
+func NewPlanWith(n int, backend Backend) *Plan {
+	if backend == "" {
+		backend = BackendGonum
 	}
+	if !gonumAvailable {
+		backend = BackendPureGo
+	}
+	p := &Plan{n: n, backend: backend}
+	p.pool.New = func() any { return newTransform(n, backend) }
+	return p
+}
+
+// Len returns the frame length the plan was built for.
+func (p *Plan) Len() int { return p.n }
 
+// Backend returns the FFT implementation the plan uses.
+func (p *Plan) Backend() Backend { return p.backend }
+
+// Magnitude computes the magnitude spectrum like ComputeMagnitude, reusing the plan's FFT state.
+// Frames shorter than the plan length are zero-padded (see ComputeMagnitudeN).
+// Returns nil if frame is empty or longer than the plan length; see Compute.
+func (p *Plan) Magnitude(frame []float64) []float64 {
+	mags, _ := p.Compute(frame)
 	return mags
 }
 
+// Compute is Magnitude with an error: every successful call returns exactly Len()/2
+// bins, and a frame that is empty or longer than the plan length returns an error
+// wrapping ErrFrameLength instead of a spectrum of a different size.
+func (p *Plan) Compute(frame []float64) ([]float64, error) {
+	if p.n == 0 || len(frame) == 0 || len(frame) > p.n {
+		return nil, fmt.Errorf("%w: got %d samples, plan length %d", ErrFrameLength, len(frame), p.n)
+	}
+	if len(frame) < p.n {
+		frame = zeroPad(frame, p.n)
+	}
+
+	t := p.pool.Get().(transform)
+	mags := make([]float64, p.n/2)
+	t.magnitudes(frame, mags)
+	p.pool.Put(t)
+
+	return mags, nil
+}
+
+// ComputePower is Compute returning the power spectrum (squared magnitudes), see
+// the package-level ComputePower.
+func (p *Plan) ComputePower(frame []float64) ([]float64, error) {
+	mags, err := p.Compute(frame)
+	if err != nil {
+		return nil, err
+	}
+	return square(mags), nil
+}
+
+// zeroPad returns a copy of frame extended with zeros to n samples.
+func zeroPad(frame []float64, n int) []float64 {
+	padded := make([]float64, n)
+	copy(padded, frame)
+	return padded
+}
+
 // cmplxAbs returns the magnitude of a complex number.
 func cmplxAbs(c complex128) float64 {
 	return math.Hypot(real(c), imag(c))
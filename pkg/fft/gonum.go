@@ -0,0 +1,26 @@
+//go:build !purego
+
+package fft
+
+import "gonum.org/v1/gonum/dsp/fourier"
+
+// gonumAvailable reports whether the gonum FFT is compiled in; build with the
+// purego tag to drop the dependency and always use the pure-Go FFT.
+const gonumAvailable = true
+
+// gonumFFT adapts gonum's real FFT to the transform interface.
+type gonumFFT struct {
+	fft    *fourier.FFT
+	coeffs []complex128
+}
+
+func newGonumFFT(n int) transform {
+	return &gonumFFT{fft: fourier.NewFFT(n)}
+}
+
+func (g *gonumFFT) magnitudes(frame, mags []float64) {
+	g.coeffs = g.fft.Coefficients(g.coeffs, frame)
+	for k := range mags {
+		mags[k] = cmplxAbs(g.coeffs[k])
+	}
+}
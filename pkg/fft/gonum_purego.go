@@ -0,0 +1,11 @@
+//go:build purego
+
+package fft
+
+// gonumAvailable is false in purego builds: gonum is not linked and every
+// transform uses the pure-Go FFT.
+const gonumAvailable = false
+
+func newGonumFFT(n int) transform {
+	return newPureFFT(n)
+}
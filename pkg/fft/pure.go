@@ -0,0 +1,76 @@
+package fft
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// pureFFT is a self-contained iterative radix-2 Cooley-Tukey FFT for power-of-two
+// lengths, with a direct O(n^2) DFT for other lengths. It holds work buffers, so
+// like gonum's FFT an instance must not be shared between goroutines.
+type pureFFT struct {
+	n       int
+	twiddle []complex128 // exp(-2*pi*i*k/n), k < n/2
+	rev     []int        // bit-reversal permutation
+	buf     []complex128
+}
+
+func newPureFFT(n int) *pureFFT {
+	f := &pureFFT{n: n, buf: make([]complex128, n)}
+	if !isPow2(n) {
+		return f
+	}
+	f.twiddle = make([]complex128, n/2)
+	for k := range f.twiddle {
+		f.twiddle[k] = cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n))
+	}
+	shift := 64 - bits.Len(uint(n-1))
+	f.rev = make([]int, n)
+	for i := range f.rev {
+		if n > 1 {
+			f.rev[i] = int(bits.Reverse64(uint64(i)) >> uint(shift))
+		}
+	}
+	return f
+}
+
+// magnitudes writes |X[k]| for k < n/2 of the n-sample frame into mags.
+func (f *pureFFT) magnitudes(frame, mags []float64) {
+	if f.rev == nil {
+		f.dft(frame, mags)
+		return
+	}
+	x := f.buf
+	for i, r := range f.rev {
+		x[r] = complex(frame[i], 0)
+	}
+	for size := 2; size <= f.n; size <<= 1 {
+		half, step := size/2, f.n/size
+		for start := 0; start < f.n; start += size {
+			for k := 0; k < half; k++ {
+				t := f.twiddle[k*step] * x[start+k+half]
+				x[start+k+half] = x[start+k] - t
+				x[start+k] += t
+			}
+		}
+	}
+	for k := range mags {
+		mags[k] = cmplxAbs(x[k])
+	}
+}
+
+// dft is the direct transform for lengths that are not a power of two.
+func (f *pureFFT) dft(frame, mags []float64) {
+	for k := range mags {
+		var re, im float64
+		for i, v := range frame {
+			s, c := math.Sincos(-2 * math.Pi * float64(k*i%f.n) / float64(f.n))
+			re += v * c
+			im += v * s
+		}
+		mags[k] = math.Hypot(re, im)
+	}
+}
+
+func isPow2(n int) bool { return n > 0 && n&(n-1) == 0 }
@@ -0,0 +1,84 @@
+package hash
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Alternative text encodings of a HashBits-bit hash for URLs and QR codes. The
+// uint64 value is canonical; hex (FormatHex) stays the default.
+
+// crockford is Crockford's base32 alphabet: digits and upper-case letters without
+// I, L, O and U, so a hash survives being read aloud or retyped.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Base32Len is the length of EncodeBase32 output: 13 digits of 5 bits, the first
+// carrying only the top 4 bits of the hash.
+const Base32Len = (HashBits + 4) / 5
+
+// Base64Len is the length of EncodeBase64 output (unpadded).
+const Base64Len = (HashBits + 5) / 6
+
+// EncodeBase32 formats h as Base32Len Crockford base32 digits, most significant
+// first, so encodings sort like the hash values.
+func EncodeBase32(h uint64) string {
+	var buf [Base32Len]byte
+	for i := Base32Len - 1; i >= 0; i-- {
+		buf[i] = crockford[h&31]
+		h >>= 5
+	}
+	return string(buf[:])
+}
+
+// DecodeBase32 parses an EncodeBase32 string. Following Crockford, it is case
+// insensitive, reads I and L as 1 and O as 0, and ignores hyphens.
+func DecodeBase32(s string) (uint64, error) {
+	s = strings.ReplaceAll(s, "-", "")
+	if len(s) != Base32Len {
+		return 0, fmt.Errorf("base32 hash must be %d chars, got %d", Base32Len, len(s))
+	}
+	var h uint64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		switch c {
+		case 'I', 'L':
+			c = '1'
+		case 'O':
+			c = '0'
+		}
+		d := strings.IndexByte(crockford, c)
+		if d < 0 {
+			return 0, fmt.Errorf("invalid base32 character %q", s[i])
+		}
+		if i == 0 && d >= 1<<(HashBits-5*(Base32Len-1)) {
+			return 0, fmt.Errorf("base32 hash %q exceeds %d bits", s, HashBits)
+		}
+		h = h<<5 | uint64(d)
+	}
+	return h, nil
+}
+
+// EncodeBase64 formats h as Base64Len unpadded base64url characters of its
+// big-endian bytes, safe in URLs and file names.
+func EncodeBase64(h uint64) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], h)
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// DecodeBase64 parses an EncodeBase64 string.
+func DecodeBase64(s string) (uint64, error) {
+	if len(s) != Base64Len {
+		return 0, fmt.Errorf("base64 hash must be %d chars, got %d", Base64Len, len(s))
+	}
+	b, err := base64.RawURLEncoding.Strict().DecodeString(s)
+	if err != nil {
+		return 0, fmt.Errorf("base64 hash: %w", err)
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
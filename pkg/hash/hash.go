@@ -1,63 +1,266 @@
 package hash
 
 import (
-	"encoding/hex"
-	"errors"
 	"fmt"
+	"math"
+	"math/bits"
 	"sort"
+
+	"github.com/ast-jean/audiophash/pkg/features"
 )
 
-// AudioPHashFromFeature converts a global feature vector to 64-bit hex pHash.
+// HashBits is the length of a perceptual hash in bits. Hashes are held in a uint64,
+// so this is also the word size of the multi-word Hash type.
+const HashBits = 64
+
+// HashHexLen is the length of a hash in hex characters.
+const HashHexLen = HashBits / 4
+
+// FormatHex formats a hash as HashHexLen zero-padded lowercase hex characters.
+func FormatHex(h uint64) string {
+	return fmt.Sprintf("%0*x", HashHexLen, h)
+}
+
+// AudioPHashFromFeature converts a global feature vector to a HashBits-bit hex pHash.
+//
+// Bit layout (stable, stored hashes depend on it): bin i sets bit HashBits-1-i when it
+// is above the median, so bin 0 is the most significant bit of the first hex digit.
+// Features shorter than HashBits bins are padded with zeros; longer ones are truncated.
 func AudioPHashFromFeature(globalFeature []float64) string {
+	return AudioPHashFromFeatureDithered(globalFeature, 0)
+}
+
+// AudioPHashFromFeatureDithered is AudioPHashFromFeature with a deterministic
+// tie-breaking dither: before thresholding, bin i is offset by amount*(max-min)*d(i),
+// where d(i) in [-0.5, 0.5) is a fixed pseudo-random value derived only from i.
+// Bins sitting on (or within float noise of) the median then resolve the same way
+// on every run and for every copy of the content. amount <= 0 disables the dither;
+// small values such as 1e-6 only affect near-ties.
+func AudioPHashFromFeatureDithered(globalFeature []float64, amount float64) string {
+	return AudioPHashFromFeatureWith(globalFeature, Options{TieDither: amount})
+}
+
+// Options controls how AudioPHashFromFeatureWith thresholds a feature into bits.
+type Options struct {
+	// TieDither is the dither amount, see AudioPHashFromFeatureDithered (0 = disabled).
+	TieDither float64
+	// TrimFraction, when > 0, thresholds against the mean of the feature after
+	// dropping this fraction of bins from each end instead of the median. With a few
+	// dominant peaks the median sits low and most bins exceed it; a trimmed mean
+	// moves the threshold towards the body of the spectrum. Must be < 0.5.
+	TrimFraction float64
+}
+
+// threshold returns the value bins are compared against: the median of the
+// (padded, dithered) feature, or its trimmed mean when TrimFraction > 0.
+func (o Options) threshold(feature []float64) float64 {
+	if o.TrimFraction > 0 {
+		return TrimmedMean(feature, o.TrimFraction)
+	}
+	return features.Median(feature)
+}
+
+// Threshold returns the value AudioPHashFromFeatureWith compares bins against for
+// globalFeature, after the same padding to HashBits bins (dither is not applied).
+func Threshold(globalFeature []float64, opts Options) float64 {
+	feature := make([]float64, HashBits)
+	copy(feature, globalFeature)
+	return opts.threshold(feature)
+}
+
+// AudioPHashFromFeatureWith is AudioPHashFromFeature with explicit thresholding options.
+func AudioPHashFromFeatureWith(globalFeature []float64, opts Options) string {
+	h, _ := AudioPHashWithMargins(globalFeature, opts)
+	return h
+}
+
+// AudioPHashWithMargins is AudioPHashFromFeatureWith that also returns each bit's
+// margin: how far its (padded, dithered) bin sits from the threshold, as a fraction
+// of the feature's range, in [0, 1]. margins[i] belongs to bin i, hash bit
+// HashBits-1-i. Borderline bits, with margins near 0, are the ones that flip under
+// re-encoding; see ConfidentDistance. A flat feature has all margins 0, and so do
+// the zero bins padding a feature shorter than HashBits, which carry no information.
+// Returns "" and nil for an empty feature.
+func AudioPHashWithMargins(globalFeature []float64, opts Options) (string, []float64) {
+	amount := opts.TieDither
 	if len(globalFeature) == 0 {
-		return ""
+		return "", nil
 	}
 
-	// Ensure length is 64 for 64-bit hash
-	feature := make([]float64, 64)
+	// Pad or truncate to one value per hash bit
+	feature := make([]float64, HashBits)
 	copy(feature, globalFeature)
-	for i := len(globalFeature); i < 64; i++ {
+	for i := len(globalFeature); i < HashBits; i++ {
 		feature[i] = 0
 	}
 
-	// Compute median
-	medianVal := median(feature)
+	minv, maxv := feature[0], feature[0]
+	for _, v := range feature {
+		minv = math.Min(minv, v)
+		maxv = math.Max(maxv, v)
+	}
+	if amount > 0 {
+		scale := amount * (maxv - minv)
+		for i := range feature {
+			feature[i] += scale * tieDither(i)
+		}
+	}
+
+	threshold := opts.threshold(feature)
 
 	var hash uint64
+	margins := make([]float64, HashBits)
 	for i, val := range feature {
-		if val > medianVal {
-			hash |= 1 << uint(63-i) // MSB first
+		if val > threshold {
+			hash |= 1 << uint(HashBits-1-i) // MSB first
+		}
+		if maxv > minv && i < len(globalFeature) {
+			margins[i] = math.Min(1, math.Abs(val-threshold)/(maxv-minv))
+		}
+	}
+
+	return FormatHex(hash), margins
+}
+
+// ConfidentDistance is the Hamming distance between a and b counted only over bits
+// whose margins (from AudioPHashWithMargins, indexed by bin) are at least minMargin
+// in both hashes. It also returns how many bits were compared, so callers can
+// normalize or reject matches that rest on too few bits. Missing margins count as 0.
+func ConfidentDistance(a, b uint64, marginsA, marginsB []float64, minMargin float64) (dist, compared int) {
+	margin := func(m []float64, i int) float64 {
+		if i < len(m) {
+			return m[i]
+		}
+		return 0
+	}
+	for i := 0; i < HashBits; i++ {
+		if margin(marginsA, i) < minMargin || margin(marginsB, i) < minMargin {
+			continue
+		}
+		compared++
+		bit := uint64(1) << uint(HashBits-1-i)
+		if (a^b)&bit != 0 {
+			dist++
 		}
 	}
+	return dist, compared
+}
 
-	return fmt.Sprintf("%016x", hash)
+// tieDither returns a fixed value in [-0.5, 0.5) for bin i (splitmix64 of the index).
+func tieDither(i int) float64 {
+	z := uint64(i) + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return float64(z>>11)/(1<<53) - 0.5
 }
 
-// median computes median of a slice
-func median(arr []float64) float64 {
+// TrimmedMean returns the mean of arr after dropping floor(frac*len) values from each
+// end of its sorted order. frac is clamped to [0, 0.5); an empty slice yields 0.
+func TrimmedMean(arr []float64, frac float64) float64 {
 	n := len(arr)
+	if n == 0 {
+		return 0
+	}
 	sorted := make([]float64, n)
 	copy(sorted, arr)
 	sort.Float64s(sorted)
-	if n%2 == 0 {
-		return (sorted[n/2-1] + sorted[n/2]) / 2
+	k := int(math.Max(0, frac) * float64(n))
+	if 2*k >= n {
+		k = (n - 1) / 2
+	}
+	var sum float64
+	for _, v := range sorted[k : n-k] {
+		sum += v
 	}
-	return sorted[n/2]
+	return sum / float64(n-2*k)
 }
 
-// HexToUint64 decodes 16-char hex (64-bit) to uint64
+// HexToUint64 decodes HashHexLen-char hex (one hash) to uint64
 func HexToUint64(hexStr string) (uint64, error) {
-	if len(hexStr) != 16 {
-		// also allow leading 0s omitted? require 16 for strictness
-		return 0, errors.New("hex must be 16 chars")
+	if len(hexStr) != HashHexLen {
+		// also allow leading 0s omitted? require HashHexLen for strictness
+		return 0, fmt.Errorf("hex must be %d chars", HashHexLen)
 	}
-	b, err := hex.DecodeString(hexStr)
+	h, err := FromHex(hexStr)
 	if err != nil {
 		return 0, err
 	}
-	var v uint64
-	for i := 0; i < 8; i++ {
-		v = (v << 8) | uint64(b[i])
+	return h.Uint64()
+}
+
+// HammingDistance returns the number of differing bits between two hashes.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Equal reports whether a and b are within maxDistance differing bits: the
+// standard match predicate. maxDistance 0 is exact equality.
+func Equal(a, b uint64, maxDistance int) bool {
+	return HammingDistance(a, b) <= maxDistance
+}
+
+// Similar is Equal with the tolerance given as a percentage (0..100) of HashBits.
+func Similar(a, b uint64, maxPercent float64) bool {
+	return float64(HammingDistance(a, b))/HashBits*100 <= maxPercent
+}
+
+// ShiftTolerantDistance returns the minimum Hamming distance between a and
+// circular shifts of b by up to maxShift positions in either direction.
+// This approximates matching under a small global spectral translation
+// (slight time-stretch or pitch-shift). maxShift <= 0 is the plain distance.
+func ShiftTolerantDistance(a, b uint64, maxShift int) int {
+	best := HammingDistance(a, b)
+	if maxShift > HashBits-1 {
+		maxShift = HashBits - 1
+	}
+	for s := 1; s <= maxShift; s++ {
+		if d := HammingDistance(a, bits.RotateLeft64(b, s)); d < best {
+			best = d
+		}
+		if d := HammingDistance(a, bits.RotateLeft64(b, -s)); d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// EmbedLowBits replaces the n least significant bits of h with the low n bits of v.
+func EmbedLowBits(h, v uint64, n int) uint64 {
+	if n <= 0 {
+		return h
+	}
+	if n >= HashBits {
+		return v
+	}
+	mask := uint64(1)<<uint(n) - 1
+	return (h &^ mask) | (v & mask)
+}
+
+// Consensus returns the bitwise majority vote of hashes, e.g. a canonical hash for
+// several recordings of the same source. A bit that is set in exactly half of the
+// hashes (only possible for an even count) is taken from hashes[0], so the first
+// hash acts as the tie-breaking reference and the consensus of two hashes is the
+// first one. An empty slice yields 0.
+func Consensus(hashes []uint64) uint64 {
+	if len(hashes) == 0 {
+		return 0
+	}
+	var out uint64
+	for bit := 0; bit < HashBits; bit++ {
+		mask := uint64(1) << uint(bit)
+		votes := 0
+		for _, h := range hashes {
+			if h&mask != 0 {
+				votes++
+			}
+		}
+		switch {
+		case 2*votes > len(hashes):
+			out |= mask
+		case 2*votes == len(hashes):
+			out |= hashes[0] & mask
+		}
 	}
-	return v, nil
+	return out
 }
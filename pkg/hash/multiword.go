@@ -0,0 +1,74 @@
+package hash
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Hash is a perceptual hash of any multiple of HashBits bits, most significant word
+// first. The single-hash uint64 API is the one-word special case.
+type Hash []uint64
+
+// FromUint64 wraps a single-word hash.
+func FromUint64(v uint64) Hash {
+	return Hash{v}
+}
+
+// FromHex parses a hex string whose length is a non-zero multiple of HashHexLen.
+func FromHex(s string) (Hash, error) {
+	if len(s) == 0 || len(s)%HashHexLen != 0 {
+		return nil, fmt.Errorf("hex length must be a non-zero multiple of %d (got %d)", HashHexLen, len(s))
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	const wordBytes = HashBits / 8
+	h := make(Hash, len(b)/wordBytes)
+	for w := range h {
+		var v uint64
+		for i := 0; i < wordBytes; i++ {
+			v = (v << 8) | uint64(b[w*wordBytes+i])
+		}
+		h[w] = v
+	}
+	return h, nil
+}
+
+// Hex returns the hash as lowercase hex, HashHexLen characters per word.
+func (h Hash) Hex() string {
+	var sb strings.Builder
+	sb.Grow(HashHexLen * len(h))
+	for _, w := range h {
+		sb.WriteString(FormatHex(w))
+	}
+	return sb.String()
+}
+
+// Bits returns the hash length in bits.
+func (h Hash) Bits() int {
+	return HashBits * len(h)
+}
+
+// Uint64 returns the single word of a 64-bit hash.
+func (h Hash) Uint64() (uint64, error) {
+	if len(h) != 1 {
+		return 0, fmt.Errorf("hash is not %d bits", HashBits)
+	}
+	return h[0], nil
+}
+
+// Distance returns the Hamming distance to other. If the lengths differ, every bit
+// of the words only one side has counts as different.
+func (h Hash) Distance(other Hash) int {
+	a, b := h, other
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	d := HashBits * (len(a) - len(b))
+	for i := range b {
+		d += HammingDistance(a[i], b[i])
+	}
+	return d
+}
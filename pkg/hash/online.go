@@ -0,0 +1,166 @@
+package hash
+
+import "sort"
+
+// P2Quantile estimates a quantile of a stream in O(1) memory using the P² algorithm
+// (Jain & Chlamtac, 1985). It keeps five markers instead of all observations.
+//
+// Accuracy tradeoff: the batch path takes the exact median over every frame. P² is an
+// approximation whose error depends on the value distribution; for the smooth,
+// unimodal per-bin magnitude distributions seen in practice it is typically within a
+// few percent, which only affects bits whose bin sits very close to the hash threshold.
+// Strictly periodic sequences (e.g. steady tones beating at fixed bin offsets) converge
+// worse than the random-order streams P² assumes.
+type P2Quantile struct {
+	p   float64
+	n   int
+	q   [5]float64 // marker heights
+	pos [5]float64 // actual marker positions (1-based)
+	des [5]float64 // desired marker positions
+	inc [5]float64 // desired position increments
+}
+
+// NewP2Quantile returns an estimator for the p-quantile, p in (0, 1).
+func NewP2Quantile(p float64) *P2Quantile {
+	return &P2Quantile{
+		p:   p,
+		inc: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// NewP2Median returns an estimator for the median.
+func NewP2Median() *P2Quantile {
+	return NewP2Quantile(0.5)
+}
+
+// Count returns the number of observations added.
+func (e *P2Quantile) Count() int { return e.n }
+
+// Add adds one observation.
+func (e *P2Quantile) Add(x float64) {
+	if e.n < 5 {
+		e.q[e.n] = x
+		e.n++
+		if e.n == 5 {
+			sort.Float64s(e.q[:])
+			p := e.p
+			e.pos = [5]float64{1, 2, 3, 4, 5}
+			e.des = [5]float64{1, 1 + 2*p, 1 + 4*p, 3 + 2*p, 5}
+		}
+		return
+	}
+	e.n++
+
+	// find the cell k containing x, extending the extremes if needed
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+		k = 0
+	case x < e.q[1]:
+		k = 0
+	case x < e.q[2]:
+		k = 1
+	case x < e.q[3]:
+		k = 2
+	case x < e.q[4]:
+		k = 3
+	default:
+		e.q[4] = x
+		k = 3
+	}
+	for i := k + 1; i < 5; i++ {
+		e.pos[i]++
+	}
+	for i := range e.des {
+		e.des[i] += e.inc[i]
+	}
+
+	// adjust the three middle markers
+	for i := 1; i <= 3; i++ {
+		d := e.des[i] - e.pos[i]
+		if (d >= 1 && e.pos[i+1]-e.pos[i] > 1) || (d <= -1 && e.pos[i-1]-e.pos[i] < -1) {
+			s := 1.0
+			if d < 0 {
+				s = -1
+			}
+			qp := e.parabolic(i, s)
+			if e.q[i-1] < qp && qp < e.q[i+1] {
+				e.q[i] = qp
+			} else {
+				e.q[i] = e.linear(i, s)
+			}
+			e.pos[i] += s
+		}
+	}
+}
+
+// Value returns the current quantile estimate (exact while fewer than five observations).
+func (e *P2Quantile) Value() float64 {
+	if e.n == 0 {
+		return 0
+	}
+	if e.n < 5 {
+		tmp := make([]float64, e.n)
+		copy(tmp, e.q[:e.n])
+		sort.Float64s(tmp)
+		idx := int(e.p * float64(e.n-1))
+		if e.p == 0.5 && e.n%2 == 0 {
+			return (tmp[e.n/2-1] + tmp[e.n/2]) / 2
+		}
+		return tmp[idx]
+	}
+	return e.q[2]
+}
+
+func (e *P2Quantile) parabolic(i int, s float64) float64 {
+	q, n := e.q, e.pos
+	return q[i] + s/(n[i+1]-n[i-1])*((n[i]-n[i-1]+s)*(q[i+1]-q[i])/(n[i+1]-n[i])+
+		(n[i+1]-n[i]-s)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+func (e *P2Quantile) linear(i int, s float64) float64 {
+	j := i + int(s)
+	return e.q[i] + s*(e.q[j]-e.q[i])/(e.pos[j]-e.pos[i])
+}
+
+// OnlineMedianFeature aggregates per-frame magnitude spectra into a per-bin median
+// feature without buffering frames; it is the streaming counterpart of
+// features.AggregateGlobalFeatureMedian.
+type OnlineMedianFeature struct {
+	bins []*P2Quantile
+}
+
+// NewOnlineMedianFeature returns an aggregator for the first numBins bins of each frame.
+func NewOnlineMedianFeature(numBins int) *OnlineMedianFeature {
+	if numBins < 0 {
+		numBins = 0
+	}
+	bins := make([]*P2Quantile, numBins)
+	for i := range bins {
+		bins[i] = NewP2Median()
+	}
+	return &OnlineMedianFeature{bins: bins}
+}
+
+// AddFrame adds one frame's magnitude spectrum. Bins beyond len(mags) are ignored.
+func (o *OnlineMedianFeature) AddFrame(mags []float64) {
+	for i, b := range o.bins {
+		if i >= len(mags) {
+			break
+		}
+		b.Add(mags[i])
+	}
+}
+
+// Feature returns the current approximate median per bin, or nil if no frame was added.
+func (o *OnlineMedianFeature) Feature() []float64 {
+	if len(o.bins) == 0 || o.bins[0].Count() == 0 {
+		return nil
+	}
+	out := make([]float64, len(o.bins))
+	for i, b := range o.bins {
+		out[i] = b.Value()
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package hash
+
+// SequenceMatch slides query over reference (sub-fingerprint sequences, e.g. from
+// audiophash.FingerprintSequence) and returns the offset into reference where every
+// aligned pair is within maxBitErrors bits. When several offsets qualify, the one
+// with the lowest total distance wins, ties going to the earliest. Returns (-1, false)
+// if no offset qualifies, query is empty, or query is longer than reference.
+func SequenceMatch(query, reference []uint64, maxBitErrors int) (offset int, matched bool) {
+	if len(query) == 0 || len(query) > len(reference) {
+		return -1, false
+	}
+	offset, best := -1, 0
+	for off := 0; off+len(query) <= len(reference); off++ {
+		total := 0
+		ok := true
+		for i, q := range query {
+			d := HammingDistance(q, reference[off+i])
+			if d > maxBitErrors {
+				ok = false
+				break
+			}
+			total += d
+		}
+		if ok && (offset < 0 || total < best) {
+			offset, best = off, total
+		}
+	}
+	return offset, offset >= 0
+}
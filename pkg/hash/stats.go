@@ -0,0 +1,68 @@
+package hash
+
+import (
+	"math"
+	"math/rand"
+)
+
+// FalsePositiveRate returns the probability that two independent, uniformly random
+// hashes of the given bit length are within threshold bits of each other: the
+// binomial tail P(X <= threshold) for X ~ Binomial(bits, 1/2).
+//
+// Real hashes are not uniform (bits are correlated and balanced by the median
+// threshold), so treat this as a baseline for choosing a match threshold, not a
+// guarantee. Returns 0 for threshold < 0 and 1 for threshold >= bits.
+func FalsePositiveRate(threshold, bits int) float64 {
+	if bits <= 0 || threshold >= bits {
+		return 1
+	}
+	if threshold < 0 {
+		return 0
+	}
+	// sum C(bits, k) / 2^bits in log space so large bit counts do not overflow
+	lnBitsFact, _ := math.Lgamma(float64(bits + 1))
+	ln2 := float64(bits) * math.Ln2
+	var p float64
+	for k := 0; k <= threshold; k++ {
+		lk, _ := math.Lgamma(float64(k + 1))
+		lnk, _ := math.Lgamma(float64(bits - k + 1))
+		p += math.Exp(lnBitsFact - lk - lnk - ln2)
+	}
+	return math.Min(p, 1)
+}
+
+// DistanceHistogram returns the distribution of pairwise Hamming distances in a
+// corpus: counts[d] is the number of pairs i < j with HammingDistance d, for d in
+// 0..HashBits. Plotted, true matches pile up near 0 and unrelated pairs around
+// HashBits/2 (compare FalsePositiveRate); a threshold in the gap separates them.
+//
+// With more than maxPairs pairs (maxPairs > 0), maxPairs pairs are sampled
+// uniformly with replacement from a generator seeded with seed, so the histogram is
+// reproducible and its cost independent of corpus size. The counts always sum to
+// the number of pairs examined; fewer than two hashes give all zeros.
+func DistanceHistogram(hashes []uint64, maxPairs int, seed int64) []int {
+	counts := make([]int, HashBits+1)
+	n := len(hashes)
+	if n < 2 {
+		return counts
+	}
+	if total := int64(n) * int64(n-1) / 2; maxPairs <= 0 || total <= int64(maxPairs) {
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				counts[HammingDistance(hashes[i], hashes[j])]++
+			}
+		}
+		return counts
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	for k := 0; k < maxPairs; k++ {
+		i := rng.Intn(n)
+		j := rng.Intn(n - 1)
+		if j >= i {
+			j++ // uniform over j != i
+		}
+		counts[HammingDistance(hashes[i], hashes[j])]++
+	}
+	return counts
+}
@@ -0,0 +1,38 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ast-jean/audiophash/pkg/features"
+)
+
+func TestLogFrequencyBands(t *testing.T) {
+	// 1 Hz bins, so bin i sits at i Hz; mags[i] = i makes each band mean easy to read
+	const sr, frameSize = 16, 16
+	mags := []float64{0, 1, 2, 3, 4, 5, 6, 7}
+
+	// octaves from bin 1: [1,2) [2,4) [4,8)
+	if got, want := features.LogFrequencyBands(mags, sr, frameSize, 3), []float64{1, 2.5, 5.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("3 bands: %v, want %v", got, want)
+	}
+	// half octaves: [1.41,2) holds no bin centre and takes the bin nearest its
+	// geometric centre (1.68 Hz -> bin 2)
+	if got, want := features.LogFrequencyBandsRange(mags, sr, frameSize, 6, 1, 8), []float64{1, 2, 2, 3, 4.5, 6.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("6 bands: %v, want %v", got, want)
+	}
+	if got, want := features.LogFrequencyBandsRange(mags, sr, frameSize, 2, 2, 8), []float64{2.5, 5.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("2 bands from 2 Hz: %v, want %v", got, want)
+	}
+
+	for name, got := range map[string][]float64{
+		"no mags":       features.LogFrequencyBands(nil, sr, frameSize, 3),
+		"no bands":      features.LogFrequencyBands(mags, sr, frameSize, 0),
+		"minHz 0":       features.LogFrequencyBandsRange(mags, sr, frameSize, 3, 0, 8),
+		"maxHz < minHz": features.LogFrequencyBandsRange(mags, sr, frameSize, 3, 4, 2),
+	} {
+		if got != nil {
+			t.Errorf("%s: %v, want nil", name, got)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestHashBatch(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	inputs := make([][]byte, 6)
+	for i := range inputs {
+		inputs[i] = encodePCM16LE(genPartials(8000, 8000, 12, 100, 3000, int64(60+i)))
+	}
+
+	const n = 200
+	var loaded, inFlight, peak atomic.Int64
+	items := make(chan audiophash.BatchItem)
+	results, err := p.HashBatch(items, audiophash.BatchOptions{Workers: 3, QueueDepth: 2})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	go func() {
+		defer close(items)
+		for i := 0; i < n; i++ {
+			i := i
+			items <- audiophash.BatchItem{
+				ID:     fmt.Sprint(i),
+				Format: "pcm16le",
+				Load: func() ([]byte, error) {
+					loaded.Add(1)
+					if i == 7 {
+						return nil, errors.New("unreadable")
+					}
+					return inputs[i%len(inputs)], nil
+				},
+			}
+			// items received but not yet reported: the producer must block
+			// once the work queue, workers and result buffer are full
+			if v := inFlight.Add(1); v > peak.Load() {
+				peak.Store(v)
+			}
+		}
+	}()
+
+	want := make([]string, len(inputs))
+	for i, b := range inputs {
+		if want[i], err = p.HashBytes(b, "pcm16le"); err != nil {
+			t.Fatalf("hash %d: %v", i, err)
+		}
+	}
+	got := 0
+	for r := range results {
+		inFlight.Add(-1)
+		got++
+		var i int
+		fmt.Sscan(r.ID, &i)
+		if i == 7 {
+			if r.Err == nil {
+				t.Errorf("item 7: want load error")
+			}
+			continue
+		}
+		if r.Err != nil || r.Hash != want[i%len(inputs)] {
+			t.Errorf("item %d: hash %s err %v, want %s", i, r.Hash, r.Err, want[i%len(inputs)])
+		}
+	}
+	if got != n || loaded.Load() != n {
+		t.Errorf("got %d results after %d loads, want %d", got, loaded.Load(), n)
+	}
+	// work queue 2 + 3 workers + result buffer 2, plus the producer's hand-off
+	if p := peak.Load(); p > 2+3+2+2 {
+		t.Errorf("%d items in flight, want the queues to bound it", p)
+	}
+
+	if _, err := p.HashBatch(items, audiophash.BatchOptions{Workers: -1}); !errors.Is(err, audiophash.ErrInvalidConfig) {
+		t.Errorf("negative workers: err = %v, want ErrInvalidConfig", err)
+	}
+}
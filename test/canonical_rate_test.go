@@ -0,0 +1,100 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestCanonicalRate(t *testing.T) {
+	// the same content as raw PCM at 44.1kHz and 48kHz, each labelled correctly
+	pcm := func(sr int) []byte { return encodePCM16LE(genPartials(3*sr, sr, 12, 100, 3000, 17)) }
+	b44, b48 := pcm(44100), pcm(48000)
+
+	// each at its own rate: default bins differ, so the fingerprints refuse to compare
+	c44, c48 := config.DefaultConfig(44100), config.DefaultConfig(48000)
+	f44, err := audiophash.AudioPHashBytesFingerprint(b44, &c44, "pcm16le")
+	if err != nil {
+		t.Fatalf("44.1kHz: %v", err)
+	}
+	f48, err := audiophash.AudioPHashBytesFingerprint(b48, &c48, "pcm16le")
+	if err != nil {
+		t.Fatalf("48kHz: %v", err)
+	}
+	if err := f44.Compatible(f48); err == nil {
+		t.Errorf("native-rate fingerprints %+v and %+v compatible", f44, f48)
+	}
+
+	// at the canonical rate both live in the same frequency space
+	c44, c48 = config.CanonicalConfig(44100), config.CanonicalConfig(48000)
+	if f44, err = audiophash.AudioPHashBytesFingerprint(b44, &c44, "pcm16le"); err != nil {
+		t.Fatalf("canonical 44.1kHz: %v", err)
+	}
+	if f48, err = audiophash.AudioPHashBytesFingerprint(b48, &c48, "pcm16le"); err != nil {
+		t.Fatalf("canonical 48kHz: %v", err)
+	}
+	if f44.SampleRate != config.DefaultCanonicalRate {
+		t.Errorf("fingerprint sample rate %d, want %d", f44.SampleRate, config.DefaultCanonicalRate)
+	}
+	if f44.Duration != 3 || f48.Duration != 3 {
+		t.Errorf("durations %g, %g, want 3", f44.Duration, f48.Duration)
+	}
+	d, err := f44.Distance(f48)
+	if err != nil {
+		t.Fatalf("canonical distance: %v", err)
+	}
+	if d > 4 {
+		t.Errorf("canonical hashes %s and %s are %d bits apart", f44.Hash, f48.Hash, d)
+	}
+
+	// WAV carries its rate, so SampleRate does not matter for it
+	wav := encodeWAV([][]float64{genPartials(3*48000, 48000, 12, 100, 3000, 17)}, 48000, 1, 16)
+	hw, err := audiophash.AudioPHashBytes(wav, &c44, "wav")
+	if err != nil {
+		t.Fatalf("canonical wav: %v", err)
+	}
+	if hw != f48.Hash {
+		t.Errorf("canonical wav hash %s, raw PCM at the same rate %s", hw, f48.Hash)
+	}
+
+	// streaming resamples seekable raw PCM the same way
+	p, err := audiophash.NewPipeline(c48)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	hs, err := p.HashReaderStreaming(bytes.NewReader(b48), "pcm16le")
+	if err != nil {
+		t.Fatalf("streaming: %v", err)
+	}
+	us, err := HexToUint64(hs)
+	if err != nil {
+		t.Fatalf("streaming hash: %v", err)
+	}
+	u48, err := HexToUint64(f48.Hash)
+	if err != nil {
+		t.Fatalf("batch hash: %v", err)
+	}
+	// the stream resampler matches ResampleWith sample for sample (see
+	// TestStreamResamplerMatchesResample); the bits that differ are borderline bins
+	// where the online P² median estimate lands on the other side of the threshold
+	if d := HammingDistance(us, u48); d > 4 {
+		t.Errorf("streaming hash %s is %d bits from %s", hs, d, f48.Hash)
+	}
+
+	// Config keeps the raw PCM rate, so it rebuilds the same pipeline
+	if got := p.Config(); got.SampleRate != 48000 || got.CanonicalRate != config.DefaultCanonicalRate {
+		t.Errorf("Config() rates %d/%d, want 48000/%d", got.SampleRate, got.CanonicalRate, config.DefaultCanonicalRate)
+	}
+	p2, err := audiophash.NewPipeline(p.Config())
+	if err != nil {
+		t.Fatalf("pipeline from Config(): %v", err)
+	}
+	if h, err := p2.HashBytes(b48, "pcm16le"); err != nil || h != f48.Hash {
+		t.Errorf("rebuilt pipeline hash %s, %v; want %s", h, err, f48.Hash)
+	}
+	if _, err := p.HashReaderStreaming(bytes.NewBuffer(b48), "pcm16le"); err == nil {
+		t.Errorf("streaming unseekable raw PCM at a canonical rate succeeded")
+	}
+}
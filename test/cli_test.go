@@ -0,0 +1,186 @@
+package test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+// buildCLI builds cmd/cli/audiophash into a temporary directory and returns its path.
+func buildCLI(t *testing.T) string {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	bin := filepath.Join(t.TempDir(), "audiophash")
+	cmd := exec.Command(goBin, "build", "-o", bin, "../cmd/cli/audiophash")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build CLI: %v\n%s", err, out)
+	}
+	return bin
+}
+
+// runCLI runs the CLI binary with args and returns its stdout and stderr.
+func runCLI(t *testing.T, bin string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err = cmd.Run()
+	return out.String(), errOut.String(), err
+}
+
+// writeFile writes b to name in dir and returns the path.
+func writeFile(t *testing.T, dir, name string, b []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCLIFeatures(t *testing.T) {
+	bin := buildCLI(t)
+	const sr = 44100
+	wav := encodeWAV([][]float64{genPartials(sr, sr, 12, 50, 1200, 61)}, sr, 1, 16)
+	path := writeFile(t, t.TempDir(), "in.wav", wav)
+	cfg := config.DefaultConfig(sr)
+	want, err := audiophash.Analyze(wav, &cfg, "wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, stderr, err := runCLI(t, bin, "features", "-json", "-frames", path)
+	if err != nil {
+		t.Fatalf("features -json: %v\n%s", err, stderr)
+	}
+	var dump struct {
+		Hash    string      `json:"hash"`
+		Feature []float64   `json:"feature"`
+		Frames  [][]float64 `json:"frames"`
+	}
+	if err := json.Unmarshal([]byte(out), &dump); err != nil {
+		t.Fatalf("features -json output: %v\n%s", err, out)
+	}
+	if dump.Hash != want.Hash || len(dump.Feature) != len(want.Feature) {
+		t.Errorf("JSON dump: hash %s with %d features, want %s with %d", dump.Hash, len(dump.Feature), want.Hash, len(want.Feature))
+	}
+	if len(dump.Frames) == 0 || len(dump.Frames[0]) != len(want.Feature) {
+		t.Errorf("JSON dump: %d frames, want per-frame spectra of %d bins", len(dump.Frames), len(want.Feature))
+	}
+
+	out, stderr, err = runCLI(t, bin, "features", path)
+	if err != nil {
+		t.Fatalf("features: %v\n%s", err, stderr)
+	}
+	rows, err := csv.NewReader(bytes.NewBufferString(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("features CSV: %v", err)
+	}
+	if len(rows) != 1 || rows[0][0] != "feature" || len(rows[0]) != len(want.Feature)+1 {
+		t.Errorf("CSV without -frames: %d rows, want one labelled \"feature\" with %d values", len(rows), len(want.Feature))
+	}
+
+	if _, _, err := runCLI(t, bin, "features", "-format", "mp3", path); err == nil {
+		t.Error("unsupported -format: want a non-zero exit")
+	}
+}
+
+func TestCLIManifestVerify(t *testing.T) {
+	bin := buildCLI(t)
+	const sr = 8000
+	dir := t.TempDir()
+	writeFile(t, dir, "a.wav", encodeWAV([][]float64{genPartials(sr, sr, 12, 50, 1200, 71)}, sr, 1, 16))
+	writeFile(t, dir, "sub/b.raw", encodePCM16LE(genPartials(sr, sr, 12, 50, 1200, 72)))
+	writeFile(t, dir, "notes.txt", []byte("not audio"))
+
+	out, stderr, err := runCLI(t, bin, "manifest", dir)
+	if err != nil {
+		t.Fatalf("manifest: %v\n%s", err, stderr)
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal([]byte(out), &manifest); err != nil {
+		t.Fatalf("manifest output: %v\n%s", err, out)
+	}
+	if len(manifest) != 2 || manifest["a.wav"] == "" || manifest["sub/b.raw"] == "" {
+		t.Fatalf("manifest %v, want a.wav and sub/b.raw keyed by slash paths", manifest)
+	}
+	mpath := writeFile(t, dir, "manifest.json", []byte(out))
+
+	if out, stderr, err := runCLI(t, bin, "verify", mpath); err != nil || out != "ok 2 files\n" {
+		t.Fatalf("verify unchanged tree: %q, %v\n%s", out, err, stderr)
+	}
+
+	// replace one file with different audio: verify fails and names it
+	writeFile(t, dir, "sub/b.raw", encodePCM16LE(genPartials(sr, sr, 12, 50, 1200, 73)))
+	out, _, err = runCLI(t, bin, "verify", mpath)
+	if err == nil || !strings.Contains(out, "FAIL sub/b.raw: distance") || strings.Contains(out, "a.wav") {
+		t.Errorf("verify changed file: %q, %v; want only sub/b.raw to fail", out, err)
+	}
+	if out, _, err := runCLI(t, bin, "verify", "-threshold", "64", mpath); err != nil {
+		t.Errorf("verify -threshold 64: %q, %v; want every distance accepted", out, err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a.wav")); err != nil {
+		t.Fatal(err)
+	}
+	if out, _, err := runCLI(t, bin, "verify", "-threshold", "64", mpath); err == nil || !strings.Contains(out, "FAIL a.wav") {
+		t.Errorf("verify missing file: %q, %v; want a.wav reported", out, err)
+	}
+}
+
+func TestCLICompareSampleRate(t *testing.T) {
+	bin := buildCLI(t)
+	dir := t.TempDir()
+	// the same partials rendered at two native rates
+	a := writeFile(t, dir, "a.wav", encodeWAV([][]float64{genPartials(44100, 44100, 12, 50, 600, 81)}, 44100, 1, 16))
+	b := writeFile(t, dir, "b.wav", encodeWAV([][]float64{genPartials(22050, 22050, 12, 50, 600, 81)}, 22050, 1, 16))
+	other := writeFile(t, dir, "c.wav", encodeWAV([][]float64{genPartials(22050, 22050, 12, 50, 600, 82)}, 22050, 1, 16))
+
+	distance := func(args ...string) (int, string) {
+		t.Helper()
+		out, stderr, err := runCLI(t, bin, append([]string{"compare"}, args...)...)
+		if err != nil {
+			t.Fatalf("compare %v: %v\n%s", args, err, stderr)
+		}
+		d, err := strconv.Atoi(strings.TrimSpace(out))
+		if err != nil {
+			t.Fatalf("compare %v: output %q", args, out)
+		}
+		return d, stderr
+	}
+
+	same, stderr := distance("-sample-rate", "22050", a, b)
+	if same > 2 {
+		t.Errorf("same content at 44.1 and 22.05 kHz: distance %d, want <= 2", same)
+	}
+	if !strings.Contains(stderr, "a.wav: 44100 Hz") || !strings.Contains(stderr, "b.wav: 22050 Hz") || !strings.Contains(stderr, "hashed at 22050 Hz") {
+		t.Errorf("stderr %q, want both native rates and the target rate", stderr)
+	}
+	if diff, _ := distance("-sample-rate", "22050", b, other); diff <= same+8 {
+		t.Errorf("different content: distance %d, want well above the %d of the same content", diff, same)
+	}
+
+	raw := writeFile(t, dir, "b.raw", encodePCM16LE(genPartials(22050, 22050, 12, 50, 600, 81)))
+	if d, stderr := distance("-sample-rate", "22050", b, raw); d != 0 || !strings.Contains(stderr, "raw PCM") {
+		t.Errorf("raw PCM taken at the target rate: distance %d, stderr %q; want 0 and a raw PCM note", d, stderr)
+	}
+
+	if _, _, err := runCLI(t, bin, "compare", "-sample-rate", "0", a, b); err == nil {
+		t.Error("-sample-rate 0: want a non-zero exit")
+	}
+}
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// TestCompareLevelMatched compares a mix against a much quieter copy with a click
+// and a short loud burst: per-file peak and RMS normalization both leave the two at
+// different levels, the cross-file gain estimate does not.
+func TestCompareLevelMatched(t *testing.T) {
+	cfg := config.DefaultConfig(22050)
+	cfg.ThresholdTrim = 0.1 // gain-sensitive threshold, see CompareLevelMatched
+	s := genPartials(3*cfg.SampleRate, cfg.SampleRate, 24, 40, 1300, 31)
+	burst := genPartials(cfg.SampleRate/4, cfg.SampleRate, 4, 4000, 9000, 32)
+	master := make([]float64, len(s))
+	for i, v := range s {
+		master[i] = 0.02 * v
+	}
+	for i, v := range burst {
+		master[i] += 0.5 * v
+	}
+	master[len(master)/2] = 0.9
+	// float WAV so the quiet copy is not dominated by 16-bit quantization
+	a := encodeWAV([][]float64{s}, cfg.SampleRate, 3, 32)
+	b := encodeWAV([][]float64{master}, cfg.SampleRate, 3, 32)
+
+	distance := func(c config.Config) int {
+		t.Helper()
+		ha, err := audiophash.AudioPHashBytes(a, &c, "wav")
+		if err != nil {
+			t.Fatalf("hash a: %v", err)
+		}
+		hb, err := audiophash.AudioPHashBytes(b, &c, "wav")
+		if err != nil {
+			t.Fatalf("hash b: %v", err)
+		}
+		u1, err := hash.HexToUint64(ha)
+		if err != nil {
+			t.Fatalf("parse a: %v", err)
+		}
+		u2, err := hash.HexToUint64(hb)
+		if err != nil {
+			t.Fatalf("parse b: %v", err)
+		}
+		return hash.HammingDistance(u1, u2)
+	}
+	peak := distance(cfg)
+	rmsCfg := cfg
+	rmsCfg.Normalize = "rms"
+	rms := distance(rmsCfg)
+
+	matched, err := audiophash.CompareLevelMatched(a, "wav", b, "wav", &cfg)
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	t.Logf("Hamming distance: peak=%d rms=%d level-matched=%d", peak, rms, matched)
+	if matched != 0 || peak == 0 || rms == 0 {
+		t.Errorf("level-matched distance %d, want 0 where per-file peak (%d) and rms (%d) are not", matched, peak, rms)
+	}
+
+	melCfg := cfg
+	melCfg.Algorithm = config.AlgorithmMelDCT
+	if _, err := audiophash.CompareLevelMatched(a, "wav", b, "wav", &melCfg); !errors.Is(err, audiophash.ErrInvalidConfig) {
+		t.Errorf("mel-dct: err = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestCompareConcurrent(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	a := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 41))
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 42))
+
+	ha, _ := audiophash.AudioPHashBytes(a, &cfg, "pcm16le")
+	hb, _ := audiophash.AudioPHashBytes(b, &cfg, "pcm16le")
+	u1, _ := hash.HexToUint64(ha)
+	u2, _ := hash.HexToUint64(hb)
+
+	got, err := audiophash.Compare(a, "pcm16le", b, "pcm16le", &cfg)
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	if want := hash.HammingDistance(u1, u2); got != want {
+		t.Errorf("distance %d, want sequential %d", got, want)
+	}
+
+	_, err = audiophash.Compare(a, "pcm16le", nil, "pcm16le", &cfg)
+	if !errors.Is(err, audiophash.ErrEmptyInput) || !strings.HasPrefix(err.Error(), "input b:") {
+		t.Errorf("empty b: err = %v, want ErrEmptyInput naming input b", err)
+	}
+	_, err = audiophash.Compare([]byte{1}, "ogg", nil, "pcm16le", &cfg)
+	if !errors.Is(err, audiophash.ErrUnsupportedFormat) || !strings.HasPrefix(err.Error(), "input a:") {
+		t.Errorf("both bad: err = %v, want input a's ErrUnsupportedFormat", err)
+	}
+}
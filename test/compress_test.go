@@ -0,0 +1,61 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGzipInput(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	wav := encodeWAV([][]float64{genPartials(2*8000, 8000, 12, 100, 3000, 3)}, 8000, 1, 16)
+	want, err := audiophash.AudioPHashBytes(wav, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("wav: %v", err)
+	}
+	gz := gzipBytes(t, wav)
+	for _, format := range []string{"wav.gz", "wav"} {
+		got, err := audiophash.AudioPHashBytes(gz, &cfg, format)
+		if err != nil || got != want {
+			t.Errorf("gzipped as %q: %s, %v; want %s", format, got, err, want)
+		}
+	}
+
+	// headerless PCM is never sniffed, even when its first sample is bytes 1f 8b
+	pcm := encodePCM16LE(genPartials(2*8000, 8000, 12, 100, 3000, 3))
+	pcm[0], pcm[1] = 0x1f, 0x8b
+	if _, err := audiophash.AudioPHashBytes(pcm, &cfg, "pcm16le"); err != nil {
+		t.Errorf("pcm16le starting with the gzip magic: %v", err)
+	}
+	if _, err := audiophash.AudioPHashBytes(gzipBytes(t, pcm), &cfg, "pcm16le.gz"); err != nil {
+		t.Errorf("pcm16le.gz: %v", err)
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	gz := gzipBytes(t, make([]byte, 1<<20))
+	if _, err := audio.Decompress(gz, 1<<20); err != nil {
+		t.Errorf("at the limit: %v", err)
+	}
+	if _, err := audio.Decompress(gz, 1<<20-1); !errors.Is(err, audio.ErrDecompressedTooLarge) {
+		t.Errorf("over the limit: err = %v, want ErrDecompressedTooLarge", err)
+	}
+}
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+// TestConcurrentHashing hashes several inputs from many goroutines, both through
+// AudioPHashBytes and a shared Pipeline. Run with -race to check for data races.
+func TestConcurrentHashing(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+	cfg.NumBins = 0
+
+	type input struct {
+		b      []byte
+		format string
+	}
+	var inputs []input
+	for seed := int64(1); seed <= 4; seed++ {
+		s := genPartials(8000, cfg.SampleRate, 12, 100, 3000, seed)
+		inputs = append(inputs,
+			input{encodePCM16LE(s), "pcm16le"},
+			input{encodeWAV([][]float64{s, s}, cfg.SampleRate, 1, 16), "wav"},
+		)
+	}
+
+	want := make([]string, len(inputs))
+	for i, in := range inputs {
+		h, err := audiophash.AudioPHashBytes(in.b, &cfg, in.format)
+		if err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+		want[i] = h
+	}
+
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+
+	cache, err := p.NewCachedHasher(len(inputs))
+	if err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+
+	const rounds = 4
+	var wg sync.WaitGroup
+	errs := make(chan string, 3*rounds*len(inputs))
+	for r := 0; r < rounds; r++ {
+		for i, in := range inputs {
+			wg.Add(3)
+			go func(i int, in input) {
+				defer wg.Done()
+				if h, err := p.HashBytes(in.b, in.format); err != nil || h != want[i] {
+					errs <- "pipeline"
+				}
+			}(i, in)
+			go func(i int, in input) {
+				defer wg.Done()
+				if h, err := audiophash.AudioPHashBytes(in.b, &cfg, in.format); err != nil || h != want[i] {
+					errs <- "AudioPHashBytes"
+				}
+			}(i, in)
+			go func(i int, in input) {
+				defer wg.Done()
+				if h, err := cache.HashBytes(in.b, in.format); err != nil || h != want[i] {
+					errs <- "CachedHasher"
+				}
+			}(i, in)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Errorf("%s: concurrent hash differs from sequential result", e)
+	}
+}
+
+func TestCachedHasherLRU(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+	cfg.NumBins = 0
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	if _, err := p.NewCachedHasher(0); !errors.Is(err, audiophash.ErrInvalidConfig) {
+		t.Fatalf("size 0: got %v, want ErrInvalidConfig", err)
+	}
+	cache, err := p.NewCachedHasher(2)
+	if err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+
+	var in [3][]byte
+	for i := range in {
+		in[i] = encodePCM16LE(genPartials(8000, cfg.SampleRate, 12, 100, 3000, int64(i+1)))
+	}
+	// A, B, A (hit), C evicts B, B (miss again)
+	for _, i := range []int{0, 1, 0, 2, 1} {
+		if _, err := cache.HashBytes(in[i], "pcm16le"); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 4 {
+		t.Errorf("hits/misses = %d/%d, want 1/4", hits, misses)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("len = %d, want 2", cache.Len())
+	}
+	// the same bytes under another format are a separate entry
+	if _, err := cache.HashBytes(in[1], "pcm16be"); err != nil {
+		t.Fatalf("pcm16be: %v", err)
+	}
+	if hits, _ := cache.Stats(); hits != 1 {
+		t.Errorf("format is not part of the cache key: hits = %d", hits)
+	}
+}
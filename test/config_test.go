@@ -0,0 +1,197 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestConfigSaveLoadRoundTrip(t *testing.T) {
+	c := config.PresetTelephony(8000)
+	c.Window = "blackman-harris"
+	c.Hop = 0 // resolved by Save
+
+	path := filepath.Join(t.TempDir(), "cfg.json")
+	if err := config.Save(c, path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	want := c
+	if err := want.ValidateAndFill(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestConfigFrameSizeCap(t *testing.T) {
+	for _, tc := range []struct{ frame, fft int }{{1 << 28, 0}, {2048, 1 << 17}} {
+		c := config.DefaultConfig(44100)
+		c.FrameSize, c.FFTSize = tc.frame, tc.fft
+		if err := c.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("frame=%d fft=%d: got %v, want ErrInvalidConfig", tc.frame, tc.fft, err)
+		}
+	}
+
+	raised := config.Limits{MaxFrameSize: 1 << 17}
+	c := config.DefaultConfig(44100)
+	c.FFTSize = 1 << 17
+	if err := c.ValidateAndFillWith(raised); err != nil {
+		t.Errorf("raised cap: %v", err)
+	}
+	if _, err := audiophash.NewPipelineWith(c, raised); err != nil {
+		t.Errorf("pipeline with raised cap: %v", err)
+	}
+	if _, err := audiophash.NewPipeline(c); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("pipeline with default cap: got %v, want ErrInvalidConfig", err)
+	}
+
+	c = config.DefaultConfig(44100)
+	c.FrameSize, c.Hop = 4096, 2048
+	if err := c.ValidateAndFillWith(config.Limits{MaxFrameSize: 2048}); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("lowered cap: got %v, want ErrInvalidConfig", err)
+	}
+}
+
+// TestConfigRejectsNonFinite checks that NaN and infinite float options are rejected
+// instead of silently disabling the option or reaching the pipeline.
+func TestConfigRejectsNonFinite(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	wav := encodeWAV([][]float64{genPartials(8000, 8000, 8, 100, 3000, 1)}, 8000, 1, 16)
+	for name, set := range map[string]func(c *config.Config){
+		"maxHz NaN":          func(c *config.Config) { c.MinHz, c.MaxHz = 300, nan },
+		"maxHz NaN logBands": func(c *config.Config) { c.MinHz, c.MaxHz, c.LogBands = 300, nan, true },
+		"minHz NaN":          func(c *config.Config) { c.MinHz, c.MaxHz = nan, 3400 },
+		"minHz +Inf":         func(c *config.Config) { c.MinHz = inf },
+	} {
+		c := config.DefaultConfig(8000)
+		set(&c)
+		if err := c.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("%s: got %v, want ErrInvalidConfig", name, err)
+		}
+		if _, err := audiophash.AudioPHashBytes(wav, &c, "wav"); !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("%s: hashing got %v, want ErrInvalidConfig", name, err)
+		}
+	}
+}
+
+func TestDefaultNumBinsAcrossRates(t *testing.T) {
+	const frame = 2048
+	for _, tc := range []struct{ sr, want int }{
+		{8000, hash.HashBits},  // 0–1378Hz would need 353 bins: capped, covers 0–250Hz
+		{22050, hash.HashBits}, // 128 bins: capped
+		{44100, 64},
+		{48000, 59},
+		{96000, 29},
+	} {
+		got := config.DefaultNumBins(tc.sr, frame)
+		if got != tc.want {
+			t.Errorf("%d Hz: %d bins, want %d", tc.sr, got, tc.want)
+		}
+		if tc.want < hash.HashBits {
+			// uncapped: the bins reach DefaultBandHz to within one bin
+			if top := float64(got*tc.sr) / frame; math.Abs(top-config.DefaultBandHz) > float64(tc.sr)/frame {
+				t.Errorf("%d Hz: %d bins reach %.0f Hz, want about %.0f", tc.sr, got, top, config.DefaultBandHz)
+			}
+		}
+		if c := config.DefaultConfig(tc.sr); c.NumBins != got {
+			t.Errorf("%d Hz: DefaultConfig NumBins %d, want %d", tc.sr, c.NumBins, got)
+		}
+	}
+	if got := config.DefaultNumBins(2000, 16); got != 8 {
+		t.Errorf("16-sample frames: %d bins, want frameSize/2 = 8", got)
+	}
+}
+
+// TestDCBinDefault checks that a hand-built Config skips the DC bin like DefaultConfig,
+// so the two hash alike, and that KeepDCBin moves the features down to start at bin 0.
+func TestDCBinDefault(t *testing.T) {
+	const sr = 8000
+	x := genPartials(2*sr, sr, 12, 100, 3000, 31)
+	wav := encodeWAV([][]float64{x}, sr, 3, 32)
+
+	def := config.DefaultConfig(sr)
+	bare := config.Config{SampleRate: sr, NumBins: def.NumBins}
+	keep := bare
+	keep.KeepDCBin = true
+	res := make(map[string]*audiophash.Analysis)
+	for name, c := range map[string]*config.Config{"default": &def, "bare": &bare, "keep": &keep} {
+		a, err := audiophash.Analyze(wav, c, "wav")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		res[name] = a
+	}
+	if res["bare"].Hash != res["default"].Hash {
+		t.Errorf("bare Config hash %s, want DefaultConfig's %s", res["bare"].Hash, res["default"].Hash)
+	}
+	// KeepDCBin shifts the features up by one bin
+	if kf, df := res["keep"].Feature, res["default"].Feature; !reflect.DeepEqual(kf[1:], df[:len(df)-1]) {
+		t.Errorf("KeepDCBin feature is not the default one shifted by a bin:\n%v\n%v", kf[:4], df[:4])
+	}
+	if err := bare.ValidateAndFill(); err != nil {
+		t.Fatal(err)
+	}
+	if lo, _ := bare.BandBins(); lo != 1 {
+		t.Errorf("bare Config starts at bin %d, want 1", lo)
+	}
+	if lo, _ := keep.BandBins(); lo != 0 {
+		t.Errorf("KeepDCBin starts at bin %d, want 0", lo)
+	}
+}
+
+// TestPresetTelephonyIgnoresOutOfBand checks that the telephony preset hashes only the
+// 300–3400Hz speech band: mains hum and a tone above the band leave the hash alone,
+// while they move the default hash.
+func TestPresetTelephonyIgnoresOutOfBand(t *testing.T) {
+	const sr = 8000
+	tel := config.PresetTelephony(sr)
+	if err := tel.ValidateAndFill(); err != nil {
+		t.Fatal(err)
+	}
+	lo, hi := tel.BandBins()
+	binHz := float64(sr) / float64(tel.FFTLen())
+	if float64(lo)*binHz < 300 || float64(hi-1)*binHz > 3400 {
+		t.Errorf("band bins [%d, %d) span %.0f–%.0f Hz, want within 300–3400 Hz", lo, hi, float64(lo)*binHz, float64(hi-1)*binHz)
+	}
+
+	speech := genPartials(2*sr, sr, 64, 300, 3400, 51)
+	noisy := make([]float64, len(speech))
+	hum := genTones(len(speech), sr, []float64{50, 100, 3900}, []float64{1, 0.5, 0.5})
+	for i := range noisy {
+		noisy[i] = speech[i] + hum[i]
+	}
+	dist := func(cfg config.Config) int {
+		t.Helper()
+		var hs [2]uint64
+		for i, x := range [][]float64{speech, noisy} {
+			h, err := audiophash.AudioPHashBytes(encodeWAV([][]float64{x}, sr, 3, 32), &cfg, "wav")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hs[i], err = HexToUint64(h); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return HammingDistance(hs[0], hs[1])
+	}
+	dTel, dDef := dist(config.PresetTelephony(sr)), dist(config.DefaultConfig(sr))
+	t.Logf("out-of-band tones move %d bits with the telephony preset, %d with the default", dTel, dDef)
+	if dTel > 2 {
+		t.Errorf("telephony: out-of-band tones moved %d bits, want <= 2", dTel)
+	}
+	if dDef < 8 {
+		t.Errorf("default: out-of-band tones moved only %d bits, want >= 8", dDef)
+	}
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestDiffAnalysesMatchesHamming(t *testing.T) {
+	cfg := config.DefaultConfig(22050)
+	s := genPartials(2*cfg.SampleRate, cfg.SampleRate, 20, 40, 600, 12)
+	a, err := audiophash.Analyze(encodePCM16LE(s), &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze a: %v", err)
+	}
+	b, err := audiophash.Analyze(encodePCM16LE(addWhiteNoise(s, 5, 1)), &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze b: %v", err)
+	}
+
+	d, err := audiophash.DiffAnalyses(a, b)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	u1, _ := hash.HexToUint64(a.Hash)
+	u2, _ := hash.HexToUint64(b.Hash)
+	if want := hash.HammingDistance(u1, u2); d.Distance != want {
+		t.Errorf("diff distance %d, want Hamming %d", d.Distance, want)
+	}
+	for _, bin := range d.Bins {
+		// without dither, a bit is set exactly when the bin is above the threshold
+		if bin.BitA != (bin.A > d.ThresholdA) || bin.BitB != (bin.B > d.ThresholdB) {
+			t.Fatalf("bin %d: bits %v/%v inconsistent with values %g/%g and thresholds %g/%g",
+				bin.Bin, bin.BitA, bin.BitB, bin.A, bin.B, d.ThresholdA, d.ThresholdB)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/features"
+)
+
+func TestFeatureDistances(t *testing.T) {
+	a := []float64{1, 2, 3}
+	b := []float64{4, 6, 3}
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-12 }
+
+	if d, err := features.Distance(a, b); err != nil || !near(d, 5) {
+		t.Errorf("L2: %g, %v; want 5", d, err)
+	}
+	if d, err := features.ManhattanDistance(a, b); err != nil || !near(d, 7) {
+		t.Errorf("L1: %g, %v; want 7", d, err)
+	}
+	if c, err := features.CosineSimilarity(a, b); err != nil || !near(c, 25/math.Sqrt(14*61)) {
+		t.Errorf("cosine: %g, %v; want %g", c, err, 25/math.Sqrt(14*61))
+	}
+
+	// cosine ignores scale and sign flips it; the distances see scale
+	scaled := []float64{2, 4, 6}
+	if c, _ := features.CosineSimilarity(a, scaled); !near(c, 1) {
+		t.Errorf("cosine of a scaled copy: %g, want 1", c)
+	}
+	if c, _ := features.CosineSimilarity(a, []float64{-1, -2, -3}); !near(c, -1) {
+		t.Errorf("cosine of the negated vector: %g, want -1", c)
+	}
+	if d, _ := features.Distance(a, scaled); !near(d, math.Sqrt(14)) {
+		t.Errorf("L2 to a scaled copy: %g, want sqrt(14)", d)
+	}
+	if c, err := features.CosineSimilarity(a, []float64{0, 0, 0}); err != nil || c != 0 {
+		t.Errorf("cosine with a zero vector: %g, %v; want 0", c, err)
+	}
+	if d, _ := features.ManhattanDistance(a, a); d != 0 {
+		t.Errorf("L1 to itself: %g, want 0", d)
+	}
+
+	short := []float64{1, 2}
+	for name, f := range map[string]func(a, b []float64) (float64, error){
+		"L2":     features.Distance,
+		"L1":     features.ManhattanDistance,
+		"cosine": features.CosineSimilarity,
+	} {
+		if _, err := f(a, short); !errors.Is(err, features.ErrLengthMismatch) {
+			t.Errorf("%s of mismatched lengths: got %v, want ErrLengthMismatch", name, err)
+		}
+	}
+}
+
+func TestSimilarityScore(t *testing.T) {
+	an := func(h string, feature ...float64) *audiophash.Analysis {
+		return &audiophash.Analysis{Hash: h, Feature: feature, NumBins: 64}
+	}
+	const h0, h1 = "00000000000000ff", "00000000000000fe" // one bit apart
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-12 }
+
+	for _, tc := range []struct {
+		name string
+		a, b *audiophash.Analysis
+		want float64
+	}{
+		{"identical", an(h0, 1, 2), an(h0, 1, 2), 1},
+		{"one bit, same direction", an(h0, 1, 2), an(h1, 2, 4), 64.0 / 65},
+		{"same hash, orthogonal features", an(h0, 1, 0), an(h0, 0, 1), 64.0 / 65},
+		{"same hash, opposite features", an(h0, 1, 2), an(h0, -1, -2), 64.0 / 65}, // cosine clamped at 0
+		{"same hash, cosine 0.6", an(h0, 1, 0), an(h0, 0.6, 0.8), 64.6 / 65},
+		{"nil", an(h0, 1), nil, 0},
+		{"bad hex", an(h0, 1), an("zz", 1), 0},
+		{"different widths", an(h0, 1), an(h0+h0, 1), 0},
+		{"different NumBins", an(h0, 1), &audiophash.Analysis{Hash: h0, Feature: []float64{1}, NumBins: 32}, 0},
+	} {
+		if got := audiophash.SimilarityScore(tc.a, tc.b); !near(got, tc.want) {
+			t.Errorf("%s: score %g, want %g", tc.name, got, tc.want)
+		}
+	}
+
+	// the hash decides: one bit closer never ranks below a better cosine
+	closer := audiophash.SimilarityScore(an(h0, 1, 0), an(h0, 0, 1))
+	if farther := audiophash.SimilarityScore(an(h0, 1, 2), an(h1, 1, 2)); farther > closer {
+		t.Errorf("one bit off with cosine 1 scored %g above zero bits off with cosine 0 (%g)", farther, closer)
+	}
+}
@@ -0,0 +1,44 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	cfg := config.DefaultConfig(44100)
+	badCfg := config.DefaultConfig(44100)
+	badCfg.FrameSize = 1000
+	emptyWAV := encodeWAV([][]float64{{}}, 44100, 1, 16)
+	emptyWAV8k := encodeWAV([][]float64{{}}, 8000, 1, 16) // hashed at 44100: would need resampling
+
+	cases := []struct {
+		name   string
+		b      []byte
+		cfg    *config.Config
+		format string
+		want   error
+	}{
+		{"empty", nil, &cfg, "wav", audiophash.ErrEmptyInput},
+		{"format", []byte{0, 0}, &cfg, "ogg", audiophash.ErrUnsupportedFormat},
+		{"decode", []byte("not a wav file at all, just some bytes padding it out"), &cfg, "wav", audiophash.ErrDecodeFailed},
+		{"short", make([]byte, 200), &cfg, "pcm16le", audiophash.ErrAudioTooShort},
+		{"no samples", emptyWAV, &cfg, "wav", audiophash.ErrAudioTooShort},
+		{"no samples at another rate", emptyWAV8k, &cfg, "wav", audiophash.ErrAudioTooShort},
+		{"config", make([]byte, 200), &badCfg, "pcm16le", audiophash.ErrInvalidConfig},
+		{"silence", make([]byte, 2*44100), &cfg, "pcm16le", audiophash.ErrSilentAudio},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := audiophash.AudioPHashBytes(tc.b, tc.cfg, tc.format)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want errors.Is %v", err, tc.want)
+			}
+		})
+	}
+}
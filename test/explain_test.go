@@ -0,0 +1,50 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestTopBinsFindsDominantTones(t *testing.T) {
+	const sr = 8000
+	s := genTones(2*sr, sr, []float64{1000, 3000, 500}, []float64{0.5, 0.4, 0.35})
+	b := encodePCM16LE(s)
+
+	for _, tc := range []struct {
+		name string
+		mod  func(c *config.Config)
+		want []float64 // Hz, loudest first
+		tol  float64   // Hz
+	}{
+		// the default linear bins stop below 1.4kHz, so 3kHz is not hashed
+		{"linear", func(c *config.Config) { c.NumBins = 0 }, []float64{1000, 500}, sr / 512.0},
+		{"band", func(c *config.Config) { c.MinHz, c.MaxHz, c.NumBins = 300, 3400, 64 }, []float64{1000, 3000}, 2 * sr / 512.0},
+	} {
+		cfg := config.DefaultConfig(sr)
+		cfg.FrameSize = 512
+		cfg.Hop = 256
+		tc.mod(&cfg)
+		a, err := audiophash.Analyze(b, &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("%s: analyze: %v", tc.name, err)
+		}
+		peaks, err := audiophash.TopBins(a, &cfg, len(tc.want))
+		if err != nil {
+			t.Fatalf("%s: top bins: %v", tc.name, err)
+		}
+		if len(peaks) != len(tc.want) {
+			t.Fatalf("%s: got %d peaks, want %d", tc.name, len(peaks), len(tc.want))
+		}
+		for j, want := range tc.want {
+			if math.Abs(peaks[j].Hz-want) > tc.tol {
+				t.Errorf("%s: peak %d at %.0fHz (bin %d), want %.0fHz", tc.name, j, peaks[j].Hz, peaks[j].Bin, want)
+			}
+		}
+		if len(peaks) > 1 && peaks[0].Value < peaks[1].Value {
+			t.Errorf("%s: peaks not ordered loudest first: %+v", tc.name, peaks)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestNormalizeFeatureGainInvariant(t *testing.T) {
+	cfg := config.DefaultConfig(44100)
+	cfg.NormalizeFeature = true
+
+	quiet := genPartials(3*cfg.SampleRate, cfg.SampleRate, 50, 40, 1300, 11)
+	scalePeak(quiet, 0.4)
+	loud := make([]float64, len(quiet))
+	for i, v := range quiet {
+		loud[i] = v * 2 // +6dB
+	}
+
+	h1, err := audiophash.AudioPHashBytes(encodePCM16LE(quiet), &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("hash quiet: %v", err)
+	}
+	h2, err := audiophash.AudioPHashBytes(encodePCM16LE(loud), &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("hash loud: %v", err)
+	}
+	if h1 != h2 {
+		t.Fatalf("+6dB copy hashed differently: %s vs %s", h1, h2)
+	}
+}
+
+func TestToDB(t *testing.T) {
+	got := features.ToDB([]float64{1, 0.1, 0, 0.5}, 0.5)
+	want := []float64{20 * math.Log10(2), 20 * math.Log10(0.2), 20*math.Log10(features.DBEpsilon) - 20*math.Log10(0.5), 0}
+	for i := range want {
+		if math.IsInf(got[i], 0) || math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("ToDB[%d] = %g, want %g", i, got[i], want[i])
+		}
+	}
+}
+
+func TestQuantizeRoundTrip(t *testing.T) {
+	feature := make([]float64, 64)
+	for i := range feature {
+		feature[i] = math.Sin(float64(i)) * 3
+	}
+	q := features.Quantize(feature, 2)
+	if len(q) != 16 {
+		t.Fatalf("64 bins at 2 bits: got %d bytes, want 16", len(q))
+	}
+	for _, bits := range []int{1, 3, 8} {
+		deq := features.Dequantize(features.Quantize(feature, bits), bits, len(feature))
+		step := 1 / float64(int(1)<<bits-1)
+		for i, v := range feature {
+			norm := (v + 3) / 6 // approx min-max normalization
+			if math.Abs(deq[i]-norm) > step/2+0.01 {
+				t.Fatalf("bits=%d bin %d: got %g, want about %g", bits, i, deq[i], norm)
+			}
+		}
+	}
+	if d, err := features.QuantizedDistance(q, q, 2, 64); err != nil || d != 0 {
+		t.Errorf("self distance = %g, %v", d, err)
+	}
+}
+
+func TestMagnitudeFloorStabilizesQuietBins(t *testing.T) {
+	// 16 loud bins and 48 near-silent bins whose order is pure noise, so the median
+	// falls among the quiet bins
+	base := make([]float64, 64)
+	noisy := make([]float64, 64)
+	for i := range base {
+		if i%4 == 0 {
+			base[i], noisy[i] = 10+float64(i), 10+float64(i)
+		} else {
+			base[i], noisy[i] = 1e-6*float64(i), 1e-6*float64(64-i)
+		}
+	}
+	hashWith := func(f []float64, floor float64) string {
+		f = append([]float64{}, f...)
+		features.ApplyMagnitudeFloor(f, floor)
+		features.LogScaleFeature(f)
+		return hash.AudioPHashFromFeature(f)
+	}
+	if hashWith(base, 0) == hashWith(noisy, 0) {
+		t.Fatal("test setup: noise in quiet bins should move bits without a floor")
+	}
+	if a, b := hashWith(base, 1e-3), hashWith(noisy, 1e-3); a != b {
+		t.Errorf("with floor: %s != %s", a, b)
+	}
+}
+
+func TestRemoveTrendQuadratic(t *testing.T) {
+	f := make([]float64, 40)
+	for i := range f {
+		x := float64(i)
+		f[i] = 3 - 0.2*x + 0.01*x*x
+	}
+	features.RemoveTrend(f, 2)
+	for i, v := range f {
+		if math.Abs(v) > 1e-9 {
+			t.Fatalf("residual[%d] = %g, want 0", i, v)
+		}
+	}
+}
+
+func TestRemoveSpectralTiltCrossDevice(t *testing.T) {
+	sr := 44100
+	clean := genPartials(3*sr, sr, 50, 40, 1300, 23)
+	// first-order pre-emphasis: a smooth high-shelf tilt like a thin microphone
+	tilted := make([]float64, len(clean))
+	for i, v := range clean {
+		tilted[i] = v
+		if i > 0 {
+			tilted[i] -= 0.9 * clean[i-1]
+		}
+	}
+	scalePeak(clean, 0.5)
+	scalePeak(tilted, 0.5)
+
+	dist := func(removeTilt bool) int {
+		cfg := config.DefaultConfig(sr)
+		cfg.DBScale = true
+		cfg.RemoveSpectralTilt = removeTilt
+		h1, err := audiophash.AudioPHashBytes(encodePCM16LE(clean), &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("hash clean: %v", err)
+		}
+		h2, err := audiophash.AudioPHashBytes(encodePCM16LE(tilted), &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("hash tilted: %v", err)
+		}
+		u1, err := HexToUint64(h1)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		u2, err := HexToUint64(h2)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		return HammingDistance(u1, u2)
+	}
+
+	plain, flat := dist(false), dist(true)
+	t.Logf("tilted vs clean: %d bits plain, %d bits with RemoveSpectralTilt", plain, flat)
+	if flat > 4 || flat >= plain {
+		t.Fatalf("RemoveSpectralTilt distance %d, want <= 4 and below plain %d", flat, plain)
+	}
+}
+
+func TestExtractPerFrame(t *testing.T) {
+	frames := [][]float64{{1, 2, 3, 4}, {5, 6, 7, 8}}
+	got := features.ExtractPerFrame(frames, 3)
+	if len(got) != 2 || len(got[0]) != 3 || got[1][2] != 7 {
+		t.Fatalf("ExtractPerFrame = %v", got)
+	}
+	got[0][0] = 100
+	if frames[0][0] != 1 {
+		t.Fatal("ExtractPerFrame aliases its input")
+	}
+	if got := features.ExtractPerFrame(frames, 10); len(got[0]) != 4 {
+		t.Errorf("numBins beyond frame length: got %d bins, want 4", len(got[0]))
+	}
+	if got := features.ExtractPerFrameLog(frames, 2); got[1][1] != math.Log(7) {
+		t.Errorf("ExtractPerFrameLog[1][1] = %v, want log(7)", got[1][1])
+	}
+	if features.ExtractPerFrame(nil, 3) != nil || features.ExtractPerFrame(frames, 0) != nil {
+		t.Error("empty input should return nil")
+	}
+}
+
+func TestAggregateTrimmedByEnergy(t *testing.T) {
+	// 5 steady frames, 2 silent ones and 3 clicks that are loud overall but quiet in bin 0
+	var frames [][]float64
+	for i := 0; i < 5; i++ {
+		frames = append(frames, []float64{2, 2})
+	}
+	frames = append(frames, []float64{0, 0}, []float64{0, 0})
+	for i := 0; i < 3; i++ {
+		frames = append(frames, []float64{0.5, 30})
+	}
+
+	plain := features.AggregateGlobalFeatureMedian(frames, 2)
+	if plain[0] != 1.25 {
+		t.Fatalf("test setup: untrimmed bin 0 median = %v, want 1.25", plain[0])
+	}
+	got, err := features.AggregateTrimmedByEnergy(frames, 2, 30)
+	if err != nil {
+		t.Fatalf("trim: %v", err)
+	}
+	if got[0] != 2 || got[1] != 2 {
+		t.Errorf("trimmed median = %v, want [2 2]", got)
+	}
+
+	if got, err := features.AggregateTrimmedByEnergy(frames, 2, 0); err != nil || got[0] != plain[0] {
+		t.Errorf("trimPercent 0 = %v, %v; want plain median %v", got, err, plain)
+	}
+	// 49% of 3 frames drops one from each end, keeping the middle one
+	if got, err := features.AggregateTrimmedByEnergy(frames[6:9], 2, 49); err != nil || got[0] != 0.5 {
+		t.Errorf("near-50%% trim = %v, %v; want the middle frame", got, err)
+	}
+	for n := 1; n <= len(frames); n++ {
+		if got, err := features.AggregateTrimmedByEnergy(frames[:n], 2, 49.99); err != nil || len(got) != 2 {
+			t.Errorf("%d frames at 49.99%%: %v, %v; want a feature from at least one frame", n, got, err)
+		}
+	}
+	for _, bad := range []float64{-1, 50, math.NaN()} {
+		if _, err := features.AggregateTrimmedByEnergy(frames, 2, bad); err == nil {
+			t.Errorf("trimPercent %v accepted", bad)
+		}
+	}
+}
+
+func TestLogDBIsDBScale(t *testing.T) {
+	b := encodePCM16LE(genPartials(2*8000, 8000, 12, 100, 3000, 5))
+	legacy := config.DefaultConfig(8000)
+	legacy.LogDB = true
+	db := config.DefaultConfig(8000)
+	db.DBScale = true
+	a1, err := audiophash.Analyze(b, &legacy, "pcm16le")
+	if err != nil {
+		t.Fatalf("logDB: %v", err)
+	}
+	a2, err := audiophash.Analyze(b, &db, "pcm16le")
+	if err != nil {
+		t.Fatalf("dbScale: %v", err)
+	}
+	for i := range a2.Feature {
+		if a1.Feature[i] != a2.Feature[i] {
+			t.Fatalf("bin %d: logDB %g, dbScale %g", i, a1.Feature[i], a2.Feature[i])
+		}
+	}
+	if err := legacy.ValidateAndFill(); err != nil || legacy.LogDB || !legacy.DBScale {
+		t.Errorf("validated logDB config: LogDB %v, DBScale %v, err %v", legacy.LogDB, legacy.DBScale, err)
+	}
+}
+
+func TestLogScaleOptions(t *testing.T) {
+	f := []float64{0, 1, 9}
+	features.LogScaleFeatureWith(f, 1, 10)
+	for i, want := range []float64{0, math.Log10(2), 1} {
+		if math.Abs(f[i]-want) > 1e-12 {
+			t.Errorf("log10(1+x)[%d] = %g, want %g", i, f[i], want)
+		}
+	}
+
+	// a base is a constant factor, so the hash is unchanged; the offset is added
+	// before the log
+	b := encodePCM16LE(genPartials(2*8000, 8000, 12, 100, 3000, 5))
+	cfg := config.DefaultConfig(8000)
+	a, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("default: %v", err)
+	}
+	cfg.LogBase = 2
+	a2, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("base 2: %v", err)
+	}
+	if a2.Hash != a.Hash {
+		t.Errorf("base 2 hash %s, natural log %s: a base is a constant factor", a2.Hash, a.Hash)
+	}
+	for i := range a.Feature {
+		if math.Abs(a2.Feature[i]-a.Feature[i]/math.Ln2) > 1e-9 {
+			t.Fatalf("bin %d: base 2 %g, want %g", i, a2.Feature[i], a.Feature[i]/math.Ln2)
+		}
+	}
+	cfg.LogBase = 0
+	cfg.LogOffset = 1e-6
+	a3, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("offset 1e-6: %v", err)
+	}
+	if math.Abs(a3.Feature[0]-math.Log(1e-6+math.Exp(a.Feature[0])-1)) > 1e-6 {
+		t.Errorf("offset 1e-6: bin 0 = %g", a3.Feature[0])
+	}
+
+	for _, bad := range []config.Config{
+		{LogOffset: -1}, {LogOffset: math.NaN()}, {LogOffset: math.Inf(1)},
+		{LogBase: 1}, {LogBase: -2}, {LogBase: math.NaN()},
+		{DBRef: -1}, {DBRef: math.NaN()},
+	} {
+		c := config.DefaultConfig(8000)
+		c.LogOffset, c.LogBase, c.DBRef = bad.LogOffset, bad.LogBase, bad.DBRef
+		if err := c.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("logOffset %g, logBase %g, dbRef %g: err = %v, want ErrInvalidConfig", bad.LogOffset, bad.LogBase, bad.DBRef, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/fft"
+)
+
+func TestZeroPaddedFFTResolvesBetweenBins(t *testing.T) {
+	const (
+		sr        = 8000
+		frameSize = 512
+	)
+	// 10.25 bins at 512 samples -> 41 bins at 2048
+	hz := 10.25 * sr / frameSize
+	frame := genTones(frameSize, sr, []float64{hz}, []float64{1})
+	w := audio.HannWindow(frameSize)
+	for i := range frame {
+		frame[i] *= w[i]
+	}
+
+	argmax := func(m []float64) int {
+		best := 0
+		for i, v := range m {
+			if v > m[best] {
+				best = i
+			}
+		}
+		return best
+	}
+	padded := fft.ComputeMagnitudeN(frame, 4*frameSize)
+	if len(padded) != 2*frameSize {
+		t.Fatalf("bins = %d, want %d", len(padded), 2*frameSize)
+	}
+	if got := argmax(padded); got != 41 {
+		t.Errorf("zero-padded peak bin = %d, want 41", got)
+	}
+	if got := argmax(fft.NewPlan(4 * frameSize).Magnitude(frame)); got != 41 {
+		t.Errorf("plan peak bin = %d, want 41", got)
+	}
+
+	cfg := config.DefaultConfig(sr)
+	cfg.FrameSize = frameSize
+	cfg.Hop = frameSize / 2
+	cfg.FFTSize = frameSize / 2
+	if err := cfg.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("fftSize < frameSize: got %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestPlanComputeRejectsFrameLength(t *testing.T) {
+	p := fft.NewPlan(512)
+	for _, n := range []int{0, 513, 1024} {
+		if mags, err := p.Compute(make([]float64, n)); !errors.Is(err, fft.ErrFrameLength) || mags != nil {
+			t.Errorf("len %d: got %d bins, err %v, want ErrFrameLength", n, len(mags), err)
+		}
+	}
+	// short frames are zero-padded to the plan length, never a ragged spectrum
+	for _, n := range []int{100, 512} {
+		mags, err := p.Compute(make([]float64, n))
+		if err != nil || len(mags) != 256 {
+			t.Errorf("len %d: got %d bins, err %v, want 256", n, len(mags), err)
+		}
+	}
+}
+
+func TestPureGoFFTMatchesGonum(t *testing.T) {
+	if !fft.GonumAvailable() {
+		t.Skip("built with the purego tag: gonum is not linked")
+	}
+
+	rng := rand.New(rand.NewSource(3))
+	for _, n := range []int{1, 2, 8, 64, 1024, 4096, 1000} {
+		frame := make([]float64, n)
+		for i := range frame {
+			frame[i] = rng.Float64()*2 - 1
+		}
+		want := fft.ComputeMagnitude(frame)
+		wantPadded, err := fft.NewPlanWith(2*n, fft.BackendGonum).Compute(frame)
+		if err != nil {
+			t.Fatalf("n=%d: gonum plan: %v", n, err)
+		}
+
+		pure := fft.NewPlanWith(n, fft.BackendPureGo)
+		if pure.Backend() != fft.BackendPureGo {
+			t.Fatalf("plan backend %q, want %q", pure.Backend(), fft.BackendPureGo)
+		}
+		got, err := pure.Compute(frame)
+		if err != nil {
+			t.Fatalf("n=%d: pure-Go plan: %v", n, err)
+		}
+		gotPadded, err := fft.NewPlanWith(2*n, fft.BackendPureGo).Compute(frame)
+		if err != nil {
+			t.Fatalf("n=%d: pure-Go padded plan: %v", n, err)
+		}
+		for _, c := range []struct{ got, want []float64 }{{got, want}, {gotPadded, wantPadded}} {
+			if len(c.got) != len(c.want) {
+				t.Fatalf("n=%d: %d bins, gonum %d", n, len(c.got), len(c.want))
+			}
+			for k := range c.want {
+				if math.Abs(c.got[k]-c.want[k]) > 1e-9*(1+c.want[k]) {
+					t.Fatalf("n=%d bin %d: pure-Go %g, gonum %g", n, k, c.got[k], c.want[k])
+				}
+			}
+		}
+	}
+}
+
+// TestFFTBackendConfig hashes the same input with each Config.FFTBackend.
+func TestFFTBackendConfig(t *testing.T) {
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 5))
+	var hashes []string
+	for _, backend := range []string{"gonum", "purego"} {
+		cfg := config.DefaultConfig(8000)
+		cfg.FFTBackend = backend
+		h, err := audiophash.AudioPHashBytes(b, &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("%s: %v", backend, err)
+		}
+		hashes = append(hashes, h)
+	}
+	if hashes[0] != hashes[1] {
+		t.Errorf("gonum hash %s, purego hash %s", hashes[0], hashes[1])
+	}
+
+	cfg := config.DefaultConfig(8000)
+	cfg.FFTBackend = "fftw"
+	if err := cfg.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("unknown backend: err %v, want ErrInvalidConfig", err)
+	}
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestFingerprint(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 17))
+	fp, err := audiophash.AudioPHashBytesFingerprint(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+	h, _ := audiophash.AudioPHashBytes(b, &cfg, "pcm16le")
+	if fp.Hash != h || fp.SampleRate != 8000 || fp.Algo != config.AlgorithmSpectrum || fp.NumBins == 0 {
+		t.Errorf("fingerprint %+v, want hash %s at 8000 Hz", fp, h)
+	}
+	if math.Abs(fp.Duration-3) > 1e-9 {
+		t.Errorf("duration %g, want 3", fp.Duration)
+	}
+
+	data, err := json.Marshal(fp)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	back, err := audiophash.ParseFingerprint(data)
+	if err != nil || back != fp {
+		t.Fatalf("round trip: %+v, %v; want %+v", back, err, fp)
+	}
+	if d, err := fp.Distance(back); err != nil || d != 0 {
+		t.Errorf("self distance %d, %v", d, err)
+	}
+
+	other := fp
+	other.Hop *= 2
+	if _, err := fp.Distance(other); !errors.Is(err, audiophash.ErrIncompatibleFingerprints) {
+		t.Errorf("different hop: err %v, want ErrIncompatibleFingerprints", err)
+	}
+	if ok, err := fp.Match(other, 64); ok || err == nil {
+		t.Errorf("different hop matched")
+	}
+	if _, err := audiophash.ParseFingerprint([]byte(`{"hash":"xyz","algo":"spectrum"}`)); !errors.Is(err, audiophash.ErrInvalidFingerprint) {
+		t.Errorf("bad hash: err %v, want ErrInvalidFingerprint", err)
+	}
+	noDigest := fp
+	noDigest.ConfigDigest = ""
+	data, err = json.Marshal(noDigest)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if _, err := audiophash.ParseFingerprint(data); !errors.Is(err, audiophash.ErrInvalidFingerprint) {
+		t.Errorf("missing configDigest: err %v, want ErrInvalidFingerprint", err)
+	}
+}
+
+// TestFingerprintConfigDigest checks that options outside the readable Fingerprint
+// fields still make fingerprints incompatible, and that defaults left at zero do not.
+func TestFingerprintConfigDigest(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 17))
+	fp, err := audiophash.AudioPHashBytesFingerprint(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+
+	filled := cfg
+	if err := filled.ValidateAndFill(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	filled.MaxClippedFraction = 0.5 // screens input, does not change the hash
+	same, err := audiophash.AudioPHashBytesFingerprint(b, &filled, "pcm16le")
+	if err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+	if err := fp.Compatible(same); err != nil {
+		t.Errorf("filled defaults: %v", err)
+	}
+
+	for name, edit := range map[string]func(*config.Config){
+		"window":        func(c *config.Config) { c.Window = "hamming" },
+		"fftSize":       func(c *config.Config) { c.FFTSize = 4 * c.FrameSize },
+		"keepDCBin":     func(c *config.Config) { c.KeepDCBin = true },
+		"powerSpectrum": func(c *config.Config) { c.UsePowerSpectrum = true },
+		"logBands":      func(c *config.Config) { c.LogBands = true },
+		"stereoBits":    func(c *config.Config) { c.StereoBits = 2 },
+		"masking":       func(c *config.Config) { c.PsychoacousticMasking = true },
+	} {
+		c := cfg
+		edit(&c)
+		other, err := audiophash.AudioPHashBytesFingerprint(b, &c, "pcm16le")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := fp.Compatible(other); !errors.Is(err, audiophash.ErrIncompatibleFingerprints) {
+			t.Errorf("%s: err %v, want ErrIncompatibleFingerprints", name, err)
+		}
+	}
+}
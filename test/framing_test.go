@@ -0,0 +1,121 @@
+package test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestHannOverlapAddReconstructs(t *testing.T) {
+	cfg := config.DefaultConfig(44100)
+	signal := genPartials(cfg.SampleRate, cfg.SampleRate, 20, 50, 5000, 5)
+
+	frames := audio.Frame(signal, cfg.FrameSize, cfg.Hop)
+	out := audio.OverlapAdd(frames, cfg.Hop)
+
+	// only the interior is covered by two overlapping frames
+	var maxErr float64
+	for i := cfg.Hop; i < len(out)-cfg.Hop; i++ {
+		if e := math.Abs(out[i] - signal[i]); e > maxErr {
+			maxErr = e
+		}
+	}
+	t.Logf("frames=%d max reconstruction error=%.2e", len(frames), maxErr)
+	if maxErr > 1e-2 {
+		t.Fatalf("overlap-add error %.2e exceeds 1e-2: window/hop not COLA", maxErr)
+	}
+}
+
+func TestGateFrames(t *testing.T) {
+	frame := func(amp float64) []float64 { return []float64{amp, -amp, amp, -amp} }
+	frames := [][]float64{frame(0.01), frame(1), frame(0.1), frame(0)} // -40, 0, -20, -inf dB
+
+	if got := audio.GateFrames(frames, 30); len(got) != 2 || got[0][0] != 1 || got[1][0] != 0.1 {
+		t.Errorf("30 dB gate kept %v, want the 0 and -20 dB frames", got)
+	}
+	if got := audio.GateFrames(frames, 20); len(got) != 2 {
+		t.Errorf("20 dB gate kept %d frames, want 2 (the threshold is inclusive)", len(got))
+	}
+	if got := audio.GateFrames(frames, 0); len(got) != len(frames) {
+		t.Errorf("gate 0 kept %d frames, want all %d", len(got), len(frames))
+	}
+	silent := [][]float64{frame(0), frame(0)}
+	if got := audio.GateFrames(silent, 30); len(got) != 2 {
+		t.Errorf("all-silent input: kept %d frames, want it unchanged", len(got))
+	}
+
+	// a long quiet tail of other partials outvotes the music in the median unless gated
+	const sr = 8000
+	music := genPartials(sr, sr, 12, 20, 240, 41)
+	tail := scalePeak(genPartials(3*sr, sr, 12, 20, 240, 42), 0.03) // about -30 dB
+	hashOf := func(x []float64, gateDB float64) uint64 {
+		t.Helper()
+		cfg := config.DefaultConfig(sr)
+		cfg.FrameGateDB = gateDB
+		h, err := audiophash.AudioPHashBytes(encodeWAV([][]float64{x}, sr, 3, 32), &cfg, "wav")
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := HexToUint64(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	withTail := append(append([]float64(nil), music...), tail...)
+	ref := hashOf(music, 0)
+	ungated := HammingDistance(ref, hashOf(withTail, 0))
+	gated := HammingDistance(ref, hashOf(withTail, 20))
+	t.Logf("quiet tail moves %d bits ungated, %d gated at 20 dB", ungated, gated)
+	if ungated < 16 || gated > 2 {
+		t.Errorf("quiet tail: %d bits ungated (want >= 16), %d gated (want <= 2)", ungated, gated)
+	}
+}
+
+func TestDetectOnsets(t *testing.T) {
+	const sr, frameSize, hop = 8000, 512, 256
+	// plucked notes out of silence: instant attack at a known sample, then a decay
+	x := make([]float64, 3*sr)
+	starts := []int{4000, 11000, 18000}
+	for _, s := range starts {
+		note := genTones(4000, sr, []float64{440, 1320}, []float64{1, 0.5})
+		for i := range note {
+			x[s+i] = note[i] * math.Exp(-float64(i)/400)
+		}
+	}
+	onsets := audio.DetectOnsets(x, frameSize, hop)
+	if len(onsets) != len(starts) {
+		t.Fatalf("found onsets %v, want %d near %v", onsets, len(starts), starts)
+	}
+	for i, o := range onsets {
+		if d := o - starts[i]; d < -64 || d > 64 {
+			t.Errorf("onset %d at %d, want within one 64-sample block of %d", i, o, starts[i])
+		}
+	}
+}
+
+func TestAdaptiveFrameStarts(t *testing.T) {
+	const n, frameSize, hop = 4096, 512, 256
+	// the onset frame starts at 1000-256; fill frames within hop/2 of it are dropped
+	want := []int{0, 256, 512, 744, 1000, 1256, 1512, 1768, 2024, 2280, 2536, 2792, 3048, 3304, 3560}
+	if got := audio.AdaptiveFrameStarts(n, frameSize, hop, []int{1000}); !reflect.DeepEqual(got, want) {
+		t.Errorf("onset at 1000: starts %v, want %v", got, want)
+	}
+	// without onsets it is plain fixed-hop framing
+	got := audio.AdaptiveFrameStarts(n, frameSize, hop, nil)
+	if len(got) != len(audio.Frame(make([]float64, n), frameSize, hop)) || got[1] != hop {
+		t.Errorf("no onsets: starts %v, want every %d samples", got, hop)
+	}
+	// onsets near the edges are clamped so their frame fits
+	got = audio.AdaptiveFrameStarts(n, frameSize, hop, []int{10, n - 10})
+	if last := got[len(got)-1]; last != n-frameSize {
+		t.Errorf("onset near the end: last start %d, want %d", last, n-frameSize)
+	}
+	if got := audio.AdaptiveFrameStarts(frameSize-1, frameSize, hop, nil); got != nil {
+		t.Errorf("input shorter than a frame: starts %v, want none", got)
+	}
+}
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// TestHashPackingMSBFirst pins the on-disk bit layout: bin i maps to bit 63-i.
+func TestHashPackingMSBFirst(t *testing.T) {
+	ascending := make([]float64, 64)
+	descending := make([]float64, 64)
+	alternating := make([]float64, 64)
+	for i := range ascending {
+		ascending[i] = float64(i)
+		descending[i] = float64(63 - i)
+		alternating[i] = float64(i % 2)
+	}
+	firstOnly := make([]float64, 64)
+	firstOnly[0] = 1
+
+	cases := []struct {
+		name    string
+		feature []float64
+		want    string
+	}{
+		{"ascending", ascending, "00000000ffffffff"},
+		{"descending", descending, "ffffffff00000000"},
+		{"alternating", alternating, "5555555555555555"},
+		{"bin0_is_msb", firstOnly, "8000000000000000"},
+		// 4 bins, zero-padded to 64: bins 1 and 3 are above the (zero) median
+		{"short", []float64{0, 2, 0, 1}, "5000000000000000"},
+	}
+	for _, tc := range cases {
+		if got := hash.AudioPHashFromFeature(tc.feature); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTrimmedMeanThreshold(t *testing.T) {
+	if got := hash.TrimmedMean([]float64{100, 1, 2, 3, -50}, 0.2); got != 2 {
+		t.Errorf("TrimmedMean = %g, want 2", got)
+	}
+
+	// skewed feature: i^2. The median (992.5) sets bins 32..63; the 10% trimmed
+	// mean of bins 6..57 (1217.5) only sets bins 35..63.
+	squares := make([]float64, 64)
+	for i := range squares {
+		squares[i] = float64(i * i)
+	}
+	if got := hash.AudioPHashFromFeature(squares); got != "00000000ffffffff" {
+		t.Errorf("median threshold: got %s", got)
+	}
+	if got := hash.AudioPHashFromFeatureWith(squares, hash.Options{TrimFraction: 0.1}); got != "000000001fffffff" {
+		t.Errorf("trimmed-mean threshold: got %s, want 000000001fffffff", got)
+	}
+}
+
+func TestHashTextEncodings(t *testing.T) {
+	for _, h := range []uint64{0, 1, 0x8000000000000000, 0xffffffffffffffff, 0x0123456789abcdef} {
+		s32 := hash.EncodeBase32(h)
+		if len(s32) != hash.Base32Len {
+			t.Errorf("%016x: base32 %q has %d chars", h, s32, len(s32))
+		}
+		if got, err := hash.DecodeBase32(s32); err != nil || got != h {
+			t.Errorf("%016x: base32 %q decodes to %016x, %v", h, s32, got, err)
+		}
+		s64 := hash.EncodeBase64(h)
+		if len(s64) != hash.Base64Len {
+			t.Errorf("%016x: base64 %q has %d chars", h, s64, len(s64))
+		}
+		if got, err := hash.DecodeBase64(s64); err != nil || got != h {
+			t.Errorf("%016x: base64 %q decodes to %016x, %v", h, s64, got, err)
+		}
+	}
+
+	if s := hash.EncodeBase32(0xffffffffffffffff); s != "FZZZZZZZZZZZZ" {
+		t.Errorf("base32 max = %q", s)
+	}
+	// Crockford decoding: lower case, I/L/O aliases and hyphens
+	if got, err := hash.DecodeBase32("0o-0000-000000l"); err != nil || got != 1 {
+		t.Errorf("lenient base32 = %d, %v; want 1", got, err)
+	}
+	for _, bad := range []string{"G000000000000", "000000000000U", "00000000000"} {
+		if _, err := hash.DecodeBase32(bad); err == nil {
+			t.Errorf("DecodeBase32(%q) accepted", bad)
+		}
+	}
+	for _, bad := range []string{"AAAAAAAAAAA=", "AAAAAAAAAA", "AAAAAAAAAA+"} {
+		if _, err := hash.DecodeBase64(bad); err == nil {
+			t.Errorf("DecodeBase64(%q) accepted", bad)
+		}
+	}
+}
+
+func TestMultiWordHash(t *testing.T) {
+	const s = "0123456789abcdefffffffff00000000"
+	h, err := hash.FromHex(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 || h[0] != 0x0123456789abcdef || h[1] != 0xffffffff00000000 {
+		t.Errorf("FromHex(%q) = %x, want most significant word first", s, []uint64(h))
+	}
+	if h.Hex() != s || h.Bits() != 2*hash.HashBits {
+		t.Errorf("round trip: %q, %d bits", h.Hex(), h.Bits())
+	}
+	if _, err := h.Uint64(); err == nil {
+		t.Error("Uint64 of a two-word hash: want an error")
+	}
+	if v, err := hash.FromUint64(42).Uint64(); err != nil || v != 42 {
+		t.Errorf("one-word Uint64: %d, %v", v, err)
+	}
+
+	other := hash.Hash{h[0] ^ 0b11, h[1] ^ 1}
+	if d := h.Distance(other); d != 3 {
+		t.Errorf("distance %d, want 3 summed over both words", d)
+	}
+	short := hash.Hash{h[0]}
+	if d, e := h.Distance(short), short.Distance(h); d != hash.HashBits || e != d {
+		t.Errorf("distance to a one-word prefix: %d and %d, want %d both ways", d, e, hash.HashBits)
+	}
+
+	for _, bad := range []string{"", "0123", s + "0", "0123456789abcdeg"} {
+		if _, err := hash.FromHex(bad); err == nil {
+			t.Errorf("FromHex(%q): want an error", bad)
+		}
+	}
+}
+
+// TestTieDither builds a feature with half its bins tied at the median. Without
+// dither a 1e-12 nudge to one tied bin flips its bit; with dither the ties resolve
+// by bin index, so the nudge changes nothing and the clear bits are untouched.
+func TestTieDither(t *testing.T) {
+	feature := make([]float64, hash.HashBits)
+	for i := range feature {
+		switch {
+		case i < 16:
+			feature[i] = 0
+		case i < 48:
+			feature[i] = 5
+		default:
+			feature[i] = 10
+		}
+	}
+	nudged := append([]float64(nil), feature...)
+	nudged[20] += 1e-12
+
+	if hash.AudioPHashFromFeature(feature) == hash.AudioPHashFromFeature(nudged) {
+		t.Fatal("undithered: the nudge did not flip the tied bit; the test needs a tie")
+	}
+	const amount = 1e-6
+	dithered := hash.AudioPHashFromFeatureDithered(feature, amount)
+	if got := hash.AudioPHashFromFeatureDithered(nudged, amount); got != dithered {
+		t.Errorf("dithered: nudge moved the hash from %s to %s", dithered, got)
+	}
+	if again := hash.AudioPHashFromFeatureDithered(feature, amount); again != dithered {
+		t.Errorf("dither is not deterministic: %s then %s", dithered, again)
+	}
+	h, err := hash.HexToUint64(dithered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h>>48 != 0 || h&0xffff != 0xffff {
+		t.Errorf("dithered hash %016x: the 16 low and 16 high bins must keep bits 0 and 1", h)
+	}
+	if hash.AudioPHashFromFeatureDithered(feature, 0) != hash.AudioPHashFromFeature(feature) {
+		t.Error("amount 0 must disable the dither")
+	}
+
+	cfg := config.DefaultConfig(8000)
+	cfg.TieDither = -1
+	if err := cfg.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("negative TieDither: got %v, want ErrInvalidConfig", err)
+	}
+}
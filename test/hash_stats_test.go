@@ -0,0 +1,220 @@
+package test
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestFalsePositiveRate(t *testing.T) {
+	cases := []struct {
+		threshold, bits int
+		want            float64
+	}{
+		{0, 64, math.Pow(2, -64)},
+		{1, 4, 5.0 / 16},
+		{2, 4, 11.0 / 16},
+		{64, 64, 1},
+		{-1, 64, 0},
+	}
+	for _, tc := range cases {
+		if got := hash.FalsePositiveRate(tc.threshold, tc.bits); math.Abs(got-tc.want) > 1e-9*tc.want+1e-300 {
+			t.Errorf("FalsePositiveRate(%d, %d) = %g, want %g", tc.threshold, tc.bits, got, tc.want)
+		}
+	}
+	// symmetric around bits/2: P(X<=31) = P(X>=33), so P(X<=31)+P(X<=32) = 1
+	if got := hash.FalsePositiveRate(31, 64) + hash.FalsePositiveRate(32, 64); math.Abs(got-1) > 1e-9 {
+		t.Errorf("P(<=31)+P(<=32) = %g, want 1", got)
+	}
+}
+
+func TestSequenceMatch(t *testing.T) {
+	ref := []uint64{0x1111, 0xffff0000, 0x12345678, 0xdeadbeef, 0x0f0f0f0f, 0xcafe}
+	query := []uint64{0x12345678 ^ 1, 0xdeadbeef, 0x0f0f0f0f ^ 3}
+
+	if off, ok := hash.SequenceMatch(query, ref, 2); !ok || off != 2 {
+		t.Errorf("got offset %d matched %v, want 2 true", off, ok)
+	}
+	// one position is 2 bits off: a 1-bit budget rejects every alignment
+	if off, ok := hash.SequenceMatch(query, ref, 1); ok || off != -1 {
+		t.Errorf("1-bit budget: got offset %d matched %v, want -1 false", off, ok)
+	}
+	if _, ok := hash.SequenceMatch(ref, query, 64); ok {
+		t.Error("query longer than reference matched")
+	}
+}
+
+func TestConsensusMajorityVote(t *testing.T) {
+	cases := []struct {
+		name   string
+		hashes []uint64
+		want   uint64
+	}{
+		{"empty", nil, 0},
+		{"single", []uint64{0xdeadbeef}, 0xdeadbeef},
+		{"majority", []uint64{0b1100, 0b1010, 0b1001}, 0b1000},
+		// bits 0 and 1 tie 2-2 and follow the first hash
+		{"tie_first_wins", []uint64{0b01, 0b01, 0b10, 0b10}, 0b01},
+		{"pair", []uint64{0xf0f0, 0x0ff0}, 0xf0f0},
+	}
+	for _, tc := range cases {
+		if got := hash.Consensus(tc.hashes); got != tc.want {
+			t.Errorf("%s: got %#x, want %#x", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHashMargins(t *testing.T) {
+	feature := make([]float64, hash.HashBits)
+	for i := range feature {
+		feature[i] = float64(i)
+	}
+	h, margins := hash.AudioPHashWithMargins(feature, hash.Options{})
+	if h == "" || len(margins) != hash.HashBits {
+		t.Fatalf("hash %q with %d margins, want %d", h, len(margins), hash.HashBits)
+	}
+	for i, m := range margins {
+		if m < 0 || m > 1 {
+			t.Errorf("margin[%d] = %g, outside [0, 1]", i, m)
+		}
+	}
+	// bins near the threshold are less certain than the extremes
+	mid := hash.HashBits / 2
+	if margins[mid] >= margins[0] || margins[mid] >= margins[hash.HashBits-1] {
+		t.Errorf("borderline margin %g not below extremes %g, %g", margins[mid], margins[0], margins[hash.HashBits-1])
+	}
+
+	u, _ := hash.HexToUint64(h)
+	flipped := u ^ 1<<uint(hash.HashBits-1-mid) // flip the borderline bin
+	if d, n := hash.ConfidentDistance(u, flipped, margins, margins, 0); d != 1 || n != hash.HashBits {
+		t.Errorf("minMargin 0: dist %d over %d bits, want 1 over %d", d, n, hash.HashBits)
+	}
+	if d, n := hash.ConfidentDistance(u, flipped, margins, margins, margins[mid]+1e-9); d != 0 || n >= hash.HashBits {
+		t.Errorf("borderline bit still compared: dist %d over %d bits", d, n)
+	}
+}
+
+func TestHashMarginsPadding(t *testing.T) {
+	feature := []float64{5, 1, 4, 2, 3}
+	_, margins := hash.AudioPHashWithMargins(feature, hash.Options{})
+	for i, m := range margins {
+		if i < len(feature) && m == 0 {
+			t.Errorf("margin[%d] = 0 for a real bin", i)
+		}
+		if i >= len(feature) && m != 0 {
+			t.Errorf("margin[%d] = %g for a padding bin, want 0", i, m)
+		}
+	}
+}
+
+func TestAnalysisMargins(t *testing.T) {
+	b := encodePCM16LE(genPartials(2*8000, 8000, 12, 100, 3000, 4))
+	cfg := config.DefaultConfig(8000)
+	cfg.StereoBits = 0
+	a, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if len(a.Margins) != hash.HashBits {
+		t.Fatalf("got %d margins, want %d", len(a.Margins), hash.HashBits)
+	}
+
+	cfg.NumBins = 40
+	a, err = audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze %d bins: %v", cfg.NumBins, err)
+	}
+	for i, m := range a.Margins[cfg.NumBins:] {
+		if m != 0 {
+			t.Errorf("padding margin[%d] = %g, want 0", cfg.NumBins+i, m)
+		}
+	}
+
+	cfg.NumBins = 0
+	cfg.MultiResolution = []int{512, 2048}
+	m, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("multi-resolution analyze: %v", err)
+	}
+	if len(m.Margins) != 2*hash.HashBits {
+		t.Errorf("multi-resolution: got %d margins, want %d", len(m.Margins), 2*hash.HashBits)
+	}
+}
+
+func TestEqualAndSimilar(t *testing.T) {
+	a := uint64(0xffff0000ffff0000)
+	b := a ^ 0b111 // 3 bits off, 4.6875%
+	if !hash.Equal(a, a, 0) || hash.Equal(a, b, 2) || !hash.Equal(a, b, 3) {
+		t.Error("Equal: wrong decision around a 3-bit distance")
+	}
+	if hash.Similar(a, b, 4.6) || !hash.Similar(a, b, 4.6875) {
+		t.Error("Similar: wrong decision around 4.6875%")
+	}
+}
+
+func TestShiftTolerantDistance(t *testing.T) {
+	a := uint64(0x0123456789abcdef)
+	for _, tc := range []struct {
+		b        uint64
+		maxShift int
+		want     int
+	}{
+		{a, 0, 0},
+		{bits.RotateLeft64(a, 1), 0, hash.HammingDistance(a, bits.RotateLeft64(a, 1))},
+		{bits.RotateLeft64(a, 1), 1, 0},
+		{bits.RotateLeft64(a, -2), 2, 0},
+		{bits.RotateLeft64(a, 3) ^ 1, 3, 1}, // shift plus one flipped bit
+		{bits.RotateLeft64(a, 5), -1, hash.HammingDistance(a, bits.RotateLeft64(a, 5))},
+		{bits.RotateLeft64(a, 40), 1000, 0}, // maxShift is capped at HashBits-1
+	} {
+		if got := hash.ShiftTolerantDistance(a, tc.b, tc.maxShift); got != tc.want {
+			t.Errorf("b %016x maxShift %d: %d, want %d", tc.b, tc.maxShift, got, tc.want)
+		}
+	}
+	if hash.ShiftTolerantDistance(a, bits.RotateLeft64(a, -2), 1) == 0 {
+		t.Error("a 2-bit shift matched within a 1-bit window")
+	}
+}
+
+func TestDistanceHistogram(t *testing.T) {
+	hashes := []uint64{0, 1, 3, ^uint64(0)}
+	counts := hash.DistanceHistogram(hashes, 0, 0)
+	if len(counts) != hash.HashBits+1 {
+		t.Fatalf("%d buckets, want %d", len(counts), hash.HashBits+1)
+	}
+	// pairs: 0-1:1 0-3:2 0-~0:64 1-3:1 1-~0:63 3-~0:62
+	want := map[int]int{1: 2, 2: 1, 62: 1, 63: 1, 64: 1}
+	for d, c := range counts {
+		if c != want[d] {
+			t.Errorf("counts[%d] = %d, want %d", d, c, want[d])
+		}
+	}
+
+	rng := rand.New(rand.NewSource(5))
+	corpus := make([]uint64, 500)
+	for i := range corpus {
+		corpus[i] = rng.Uint64()
+	}
+	sampled := hash.DistanceHistogram(corpus, 20000, 1)
+	total, mean := 0, 0.0
+	for d, c := range sampled {
+		total += c
+		mean += float64(d * c)
+	}
+	mean /= float64(total)
+	if total != 20000 {
+		t.Errorf("sampled %d pairs, want 20000", total)
+	}
+	if math.Abs(mean-32) > 0.5 {
+		t.Errorf("mean distance of random hashes = %.2f, want ~32", mean)
+	}
+	if again := hash.DistanceHistogram(corpus, 20000, 1); !reflect.DeepEqual(again, sampled) {
+		t.Error("same seed gave a different histogram")
+	}
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestLoopRotationInvariant(t *testing.T) {
+	cfg := config.DefaultConfig(44100)
+	cfg.Loop = true
+
+	// seamless loop: every partial completes a whole number of cycles
+	n := 2 * cfg.SampleRate
+	period := float64(cfg.SampleRate) / float64(n)
+	var freqs, amps []float64
+	for k, cycles := range []int{90, 150, 230, 370, 610, 980, 1500, 2300} {
+		freqs = append(freqs, float64(cycles)*period)
+		amps = append(amps, 1/float64(k+1))
+	}
+	loop := scalePeak(genTones(n, cfg.SampleRate, freqs, amps), 0.9)
+
+	rotate := func(s []float64, r int) []float64 {
+		return append(append([]float64{}, s[r:]...), s[:r]...)
+	}
+
+	base, err := audiophash.AudioPHashBytes(encodePCM16LE(loop), &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	cases := []struct {
+		name    string
+		rotate  int
+		maxBits int
+	}{
+		{"hop_multiple", 7 * cfg.Hop, 0},
+		{"arbitrary", 12345, 3},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := audiophash.AudioPHashBytes(encodePCM16LE(rotate(loop, tc.rotate)), &cfg, "pcm16le")
+			if err != nil {
+				t.Fatalf("hash rotated: %v", err)
+			}
+			u1, _ := hash.HexToUint64(base)
+			u2, _ := hash.HexToUint64(h)
+			if d := hash.HammingDistance(u1, u2); d > tc.maxBits {
+				t.Fatalf("rotation by %d: Hamming=%d > %d (%s vs %s)", tc.rotate, d, tc.maxBits, base, h)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// TestLowHighSeesHighFrequencies compares two clips that share their low band and
+// differ only above 5kHz: the low-only default barely tells them apart, the
+// low+high descriptor does.
+func TestLowHighSeesHighFrequencies(t *testing.T) {
+	const sr = 22050
+	low := genPartials(sr, sr, 16, 60, 1300, 1)
+	mix := func(seed int64) []byte {
+		high := genPartials(sr, sr, 24, 5000, 10000, seed)
+		out := make([]float64, len(low))
+		for i := range out {
+			out[i] = 0.6*low[i] + 0.4*high[i]
+		}
+		return encodePCM16LE(out)
+	}
+	a, b := mix(2), mix(3)
+
+	dist := func(cfg config.Config) int {
+		ha, err := audiophash.AudioPHashBytes(a, &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("hash a: %v", err)
+		}
+		hb, err := audiophash.AudioPHashBytes(b, &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("hash b: %v", err)
+		}
+		ua, _ := hash.HexToUint64(ha)
+		ub, _ := hash.HexToUint64(hb)
+		return hash.HammingDistance(ua, ub)
+	}
+
+	cfg := config.DefaultConfig(sr)
+	cfg.FrameSize = 1024
+	cfg.Hop = 512
+	cfg.NumBins = 64
+	lowOnly := dist(cfg)
+	cfg.LowHigh = true
+	lowHigh := dist(cfg)
+
+	t.Logf("hamming distance: low-only=%d low+high=%d", lowOnly, lowHigh)
+	if lowHigh < lowOnly+4 {
+		t.Fatalf("low+high distance %d does not clearly exceed low-only %d", lowHigh, lowOnly)
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/fft"
+)
+
+func TestApplyMasking(t *testing.T) {
+	const (
+		sr = 44100
+		n  = 2048
+	)
+	binHz := float64(sr) / n
+	loud, near, far := 93, 100, 400 // ~2 kHz masker; +150 Hz and ~8.6 kHz probes
+	s := genTones(n, sr, []float64{float64(loud) * binHz, float64(near) * binHz, float64(far) * binHz}, []float64{1, 0.003, 0.003})
+	mags := fft.ComputeMagnitude(audio.FrameWithWindow(s, audio.HannWindow(n), n)[0])
+
+	out := features.ApplyMasking(mags, sr, n)
+	if out[loud] != mags[loud] {
+		t.Errorf("masker changed: %g -> %g", mags[loud], out[loud])
+	}
+	if out[near] > 0.5*mags[near] {
+		t.Errorf("probe 150 Hz above the masker kept %g of %g, want attenuated", out[near], mags[near])
+	}
+	if out[far] != mags[far] {
+		t.Errorf("distant probe changed: %g -> %g", mags[far], out[far])
+	}
+
+	scaled := make([]float64, len(mags))
+	for i, v := range mags {
+		scaled[i] = 4 * v
+	}
+	for i, v := range features.ApplyMasking(scaled, sr, n) {
+		if math.Abs(v-4*out[i]) > 1e-9*(1+v) {
+			t.Fatalf("bin %d: not scale invariant (%g vs %g)", i, v, 4*out[i])
+		}
+	}
+}
+
+func TestPsychoacousticMaskingHash(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	cfg.PsychoacousticMasking = true
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 17))
+	a, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+
+	// the streaming hasher applies the same per-frame masking
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	h, err := p.NewHasher()
+	if err != nil {
+		t.Fatalf("hasher: %v", err)
+	}
+	samples, _, _ := audio.DecodePCM16LEToFloat64(b)
+	h.Write(samples)
+	got, err := h.Sum()
+	if err != nil {
+		t.Fatalf("sum: %v", err)
+	}
+	ua, _ := HexToUint64(a.Hash)
+	ug, _ := HexToUint64(got)
+	if d := HammingDistance(ua, ug); d > 6 {
+		t.Errorf("streaming hash %s is %d bits from batch %s", got, d, a.Hash)
+	}
+}
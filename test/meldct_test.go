@@ -0,0 +1,107 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+)
+
+func TestDCT2Orthonormal(t *testing.T) {
+	x := []float64{1, -2, 3, 0.5, 4, -1, 0, 2}
+	X := features.DCT2(x)
+	var ex, eX float64
+	for i := range x {
+		ex += x[i] * x[i]
+		eX += X[i] * X[i]
+	}
+	if math.Abs(ex-eX) > 1e-9 {
+		t.Errorf("energy %g -> %g, want preserved", ex, eX)
+	}
+	// a constant maps to the DC coefficient only
+	for k, v := range features.DCT2([]float64{3, 3, 3, 3}) {
+		want := 0.0
+		if k == 0 {
+			want = 6 // 3 * sqrt(4)
+		}
+		if math.Abs(v-want) > 1e-12 {
+			t.Errorf("DCT2(const)[%d] = %g, want %g", k, v, want)
+		}
+	}
+}
+
+func TestMelFilterbankCoversBands(t *testing.T) {
+	fb := features.MelFilterbank(32, 2048, 44100, 0, 22050)
+	if len(fb) != 32 || len(fb[0]) != 1024 {
+		t.Fatalf("filterbank shape %dx%d, want 32x1024", len(fb), len(fb[0]))
+	}
+	for k, w := range fb {
+		var sum float64
+		for _, v := range w {
+			sum += v
+		}
+		if sum == 0 {
+			t.Errorf("filter %d has no weight", k)
+		}
+	}
+	if f := features.MelToHz(features.HzToMel(1000)); math.Abs(f-1000) > 1e-9 {
+		t.Errorf("mel round trip 1000Hz = %g", f)
+	}
+}
+
+func TestMelDCTHash(t *testing.T) {
+	sr := 22050
+	clean := genChord(sr, 6, 4, 1, 1, 31)
+	scalePeak(clean, 0.5)
+	noisy := addWhiteNoise(clean, 30, 32)
+	other := genChord(sr, 6, 4, 1, 1, 77)
+	scalePeak(other, 0.5)
+
+	cfg := config.DefaultConfig(sr)
+	cfg.Algorithm = config.AlgorithmMelDCT
+	hashOf := func(s []float64) uint64 {
+		t.Helper()
+		h, err := audiophash.AudioPHashBytes(encodePCM16LE(s), &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("hash: %v", err)
+		}
+		u, err := HexToUint64(h)
+		if err != nil {
+			t.Fatalf("parse %q: %v", h, err)
+		}
+		return u
+	}
+	base := hashOf(clean)
+	near, far := HammingDistance(base, hashOf(noisy)), HammingDistance(base, hashOf(other))
+	t.Logf("mel-dct: noisy copy %d bits, other content %d bits", near, far)
+	if near > 10 || far < 16 {
+		t.Errorf("noisy copy %d bits (want <= 10), other content %d bits (want >= 16)", near, far)
+	}
+
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	a, err := p.Analyze(encodePCM16LE(clean), "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if len(a.Feature) != 64 {
+		t.Errorf("feature has %d coefficients, want 64", len(a.Feature))
+	}
+	if _, err := p.TopBins(a, 3); !errors.Is(err, audiophash.ErrInvalidConfig) {
+		t.Errorf("TopBins on mel-dct: err = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := audiophash.AudioPHashBytes(make([]byte, 4*sr), &cfg, "pcm16le"); !errors.Is(err, audiophash.ErrSilentAudio) {
+		t.Errorf("silence: err = %v, want ErrSilentAudio", err)
+	}
+
+	bad := config.DefaultConfig(sr)
+	bad.Algorithm = "wavelet"
+	if err := bad.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("unknown algorithm: err = %v, want ErrInvalidConfig", err)
+	}
+}
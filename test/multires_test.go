@@ -0,0 +1,77 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestMultiResolutionConcatenatesHashes(t *testing.T) {
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 9))
+	sizes := []int{256, 1024, 2048}
+
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 1024
+	cfg.Hop = 512
+	cfg.MultiResolution = sizes
+	got, err := audiophash.AudioPHashBytes(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("multi-resolution hash: %v", err)
+	}
+	if len(got) != len(sizes)*hash.HashHexLen {
+		t.Fatalf("hash %q has %d hex digits, want %d", got, len(got), len(sizes)*hash.HashHexLen)
+	}
+
+	for i, n := range sizes {
+		single := config.DefaultConfig(8000)
+		single.FrameSize = n
+		single.Hop = n / 2
+		want, err := audiophash.AudioPHashBytes(b, &single, "pcm16le")
+		if err != nil {
+			t.Fatalf("frame size %d: %v", n, err)
+		}
+		if word := got[i*hash.HashHexLen : (i+1)*hash.HashHexLen]; word != want {
+			t.Errorf("word %d = %s, want single-resolution hash %s", i, word, want)
+		}
+	}
+}
+
+func TestMultiResolutionRejectsBadSizes(t *testing.T) {
+	for _, sizes := range [][]int{{1000}, {config.DefaultMaxFrameSize * 2}, make([]int, config.MaxMultiResolution+1)} {
+		cfg := config.DefaultConfig(8000)
+		cfg.MultiResolution = sizes
+		if err := cfg.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("sizes %v: err = %v, want ErrInvalidConfig", sizes, err)
+		}
+	}
+}
+
+func TestMultiResolutionOneWordAPIs(t *testing.T) {
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 9))
+	cfg := config.DefaultConfig(8000)
+	cfg.MultiResolution = []int{512, 2048}
+	a, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if a.NumBins != cfg.NumBins || len(a.Feature) != 2*cfg.NumBins {
+		t.Errorf("NumBins %d with %d feature values, want %d per word", a.NumBins, len(a.Feature), cfg.NumBins)
+	}
+	if _, err := audiophash.DiffAnalyses(a, a); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("DiffAnalyses: err = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := audiophash.TopBins(a, &cfg, 4); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("TopBins: err = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := audiophash.FingerprintSequence(b, &cfg, "pcm16le", 500); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("FingerprintSequence: err = %v, want ErrInvalidConfig", err)
+	}
+
+	r := a.WithNumBins(32)
+	if len(r.Hash) != len(a.Hash) || len(r.Feature) != 64 || r.NumBins != 32 {
+		t.Errorf("WithNumBins(32): hash %q, %d feature values, NumBins %d", r.Hash, len(r.Feature), r.NumBins)
+	}
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/pkg/audio"
+)
+
+// TestPercentileNormalizeIgnoresClick normalizes a quiet recording with and without a
+// single full-scale click. Percentile normalization keeps the gain set by the music.
+func TestPercentileNormalizeIgnoresClick(t *testing.T) {
+	const sr = 22050
+	clean := scalePeak(genPartials(2*sr, sr, 16, 60, 1300, 7), 0.05)
+	clicked := append([]float64{}, clean...)
+	clicked[sr] = 1
+
+	want := audio.NormalizeWith(clean, audio.NormalizePercentile, 0)
+	got := audio.NormalizeWith(clicked, audio.NormalizePercentile, 0)
+	if got[sr] != 1 {
+		t.Errorf("click = %g, want clamped to 1", got[sr])
+	}
+	for i := range got {
+		if i != sr && math.Abs(got[i]-want[i]) > 1e-3 {
+			t.Fatalf("sample %d = %g, want %g (click changed the gain)", i, got[i], want[i])
+		}
+	}
+
+	// peak normalization lets the click set the gain, leaving the music at 5%
+	if p := audio.AbsPercentile(audio.NormalizeWith(clicked, audio.NormalizePeak, 0), 99.5); p > 0.1 {
+		t.Errorf("peak-normalized 99.5th percentile = %g, expected the click to hold it down", p)
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/fft"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestOnlineMedianHashMatchesExact(t *testing.T) {
+	cfg := config.DefaultConfig(44100)
+	signal := addWhiteNoise(genPartials(5*cfg.SampleRate, cfg.SampleRate, 200, 20, 1400, 7), 15, 3)
+
+	frames := audio.Frame(audio.Normalize(signal), cfg.FrameSize, cfg.Hop)
+	frameMags := make([][]float64, len(frames))
+	online := hash.NewOnlineMedianFeature(cfg.NumBins)
+	for i, f := range frames {
+		frameMags[i] = fft.ComputeMagnitude(f)
+		online.AddFrame(frameMags[i])
+	}
+
+	exact := features.AggregateGlobalFeatureMedian(frameMags, cfg.NumBins)
+	approx := online.Feature()
+	features.LogScaleFeature(exact)
+	features.LogScaleFeature(approx)
+
+	u1, err := hash.HexToUint64(hash.AudioPHashFromFeature(exact))
+	if err != nil {
+		t.Fatalf("exact hash: %v", err)
+	}
+	u2, err := hash.HexToUint64(hash.AudioPHashFromFeature(approx))
+	if err != nil {
+		t.Fatalf("approx hash: %v", err)
+	}
+
+	d := hash.HammingDistance(u1, u2)
+	t.Logf("frames=%d exact=%016x approx=%016x Hamming=%d", len(frames), u1, u2, d)
+	if d > 4 {
+		t.Fatalf("online median hash differs by %d bits (> 4)", d)
+	}
+}
+
+func TestMedianTiePolicy(t *testing.T) {
+	cases := []struct {
+		in   []float64
+		want float64
+	}{
+		{nil, features.EmptyMedian},
+		{[]float64{3}, 3},
+		{[]float64{5, 1, 3}, 3},
+		{[]float64{4, 1, 3, 2}, 2.5}, // even length: mean of the middle pair
+		{[]float64{-2, -2, 7, 7}, 2.5},
+	}
+	for _, tc := range cases {
+		in := append([]float64(nil), tc.in...)
+		if got := features.Median(in); got != tc.want {
+			t.Errorf("Median(%v) = %g, want %g", tc.in, got, tc.want)
+		}
+		for i := range in {
+			if in[i] != tc.in[i] {
+				t.Fatalf("Median(%v) modified its input", tc.in)
+			}
+		}
+	}
+
+	// the hash threshold is the same helper over the padded feature
+	f := []float64{4, 1, 3, 2}
+	padded := make([]float64, hash.HashBits)
+	copy(padded, f)
+	if got, want := hash.Threshold(f, hash.Options{}), features.Median(padded); got != want {
+		t.Errorf("hash threshold %g, want features.Median %g", got, want)
+	}
+	// a flat feature has no bin strictly above its median
+	if h := hash.AudioPHashFromFeature(make([]float64, hash.HashBits)); h != "0000000000000000" {
+		t.Errorf("flat feature hash = %s, want all zero", h)
+	}
+}
@@ -0,0 +1,58 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+// assertNoiseStable hashes clean and noisy versions of s and fails if they differ by more than maxPercent.
+func assertNoiseStable(t *testing.T, cfg *config.Config, s []float64, snrDB, maxPercent float64) {
+	t.Helper()
+	h1, err := audiophash.AudioPHashBytes(encodePCM16LE(s), cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("hash clean: %v", err)
+	}
+	h2, err := audiophash.AudioPHashBytes(encodePCM16LE(addWhiteNoise(s, snrDB, 1)), cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("hash noisy: %v", err)
+	}
+	u1, err := HexToUint64(h1)
+	if err != nil {
+		t.Fatalf("hex decode h1: %v", err)
+	}
+	u2, err := HexToUint64(h2)
+	if err != nil {
+		t.Fatalf("hex decode h2: %v", err)
+	}
+
+	percent := HammingPercent(u1, u2)
+	t.Logf("SNR %.0fdB: Hamming=%d (%.2f%%)", snrDB, HammingDistance(u1, u2), percent)
+	if percent > maxPercent {
+		t.Fatalf("SNR %.0fdB: percent=%.2f > allowed %.2f (h1=%s h2=%s)", snrDB, percent, maxPercent, h1, h2)
+	}
+}
+
+func TestPHashNoiseRobustness(t *testing.T) {
+	cfg := config.DefaultConfig(44100)
+	signal := genComb(5*cfg.SampleRate, cfg.SampleRate, cfg.FrameSize, cfg.NumBins, 42)
+
+	cases := []struct {
+		name       string
+		snrDB      float64
+		maxPercent float64
+	}{
+		{"snr40", 40, 1.6},
+		{"snr30", 30, 3.2},
+		{"snr20", 20, 9.4},
+		{"snr10", 10, 12.5},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assertNoiseStable(t, &cfg, signal, tc.snrDB, tc.maxPercent)
+		})
+	}
+}
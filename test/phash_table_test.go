@@ -13,6 +13,7 @@ import (
 
 	"github.com/ast-jean/audiophash/cmd/audiophash"
 	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
 )
 
 type TestCase struct {
@@ -168,7 +169,7 @@ func TestPHashTable(t *testing.T) {
 
 			switch tc.ExpectOp {
 			case "<=":
-				if percent > tc.Percent {
+				if !hash.Similar(u1, u2, tc.Percent) {
 					t.Fatalf("FAILED %s: percent=%.2f > allowed %.2f (h1=%s h2=%s)", tc.ID, percent, tc.Percent, h1, h2)
 				}
 			case ">=":
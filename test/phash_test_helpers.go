@@ -2,24 +2,26 @@ package test
 
 import (
 	"encoding/hex"
-	"errors"
+	"fmt"
 	"io/ioutil"
 	"math/bits"
 	"testing"
+
+	"github.com/ast-jean/audiophash/pkg/hash"
 )
 
-// HexToUint64 decodes 16-char hex (64-bit) to uint64
+// HexToUint64 decodes HashHexLen-char hex (one hash) to uint64
 func HexToUint64(hexStr string) (uint64, error) {
-	if len(hexStr) != 16 {
-		// also allow leading 0s omitted? require 16 for strictness
-		return 0, errors.New("hex must be 16 chars")
+	if len(hexStr) != hash.HashHexLen {
+		// also allow leading 0s omitted? require HashHexLen for strictness
+		return 0, fmt.Errorf("hex must be %d chars", hash.HashHexLen)
 	}
 	b, err := hex.DecodeString(hexStr)
 	if err != nil {
 		return 0, err
 	}
 	var v uint64
-	for i := 0; i < 8; i++ {
+	for i := 0; i < hash.HashBits/8; i++ {
 		v = (v << 8) | uint64(b[i])
 	}
 	return v, nil
@@ -32,7 +34,7 @@ func HammingDistance(h1, h2 uint64) int {
 
 // HammingPercent (0..100)
 func HammingPercent(h1, h2 uint64) float64 {
-	return float64(HammingDistance(h1, h2)) / 64.0 * 100.0
+	return float64(HammingDistance(h1, h2)) / hash.HashBits * 100.0
 }
 
 // loadFile reads file bytes (helper)
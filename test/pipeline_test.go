@@ -0,0 +1,111 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+// TestPipelineReuse checks that a Pipeline validates once, keeps its own copy of the
+// config and hashes every input like the one-shot functions do.
+func TestPipelineReuse(t *testing.T) {
+	const sr = 8000
+	cfg := config.Config{SampleRate: sr, FrameSize: 512}
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	got := p.Config()
+	if got.Hop != 256 || got.FFTSize != 512 || got.NumBins == 0 || got.Window != "hann" {
+		t.Errorf("Config not filled: hop %d fftSize %d numBins %d window %q", got.Hop, got.FFTSize, got.NumBins, got.Window)
+	}
+
+	for seed := int64(1); seed <= 3; seed++ {
+		x := genPartials(2*sr, sr, 8, 100, 3000, seed)
+		for _, in := range []struct {
+			b      []byte
+			format string
+		}{
+			{encodeWAV([][]float64{x}, sr, 1, 16), "wav"},
+			{encodePCM16LE(x), "pcm16le"},
+		} {
+			want, err := audiophash.AudioPHashBytes(in.b, &cfg, in.format)
+			if err != nil {
+				t.Fatalf("seed %d %s: %v", seed, in.format, err)
+			}
+			h, err := p.HashBytes(in.b, in.format)
+			if err != nil {
+				t.Fatalf("seed %d %s: %v", seed, in.format, err)
+			}
+			if h != want {
+				t.Errorf("seed %d %s: pipeline hash %s, want %s", seed, in.format, h, want)
+			}
+		}
+	}
+
+	multi := config.DefaultConfig(sr)
+	multi.MultiResolution = []int{512, 1024}
+	pm, err := audiophash.NewPipeline(multi)
+	if err != nil {
+		t.Fatalf("multi pipeline: %v", err)
+	}
+	multi.MultiResolution[0] = 256
+	if got := pm.Config().MultiResolution; got[0] != 512 {
+		t.Errorf("caller's edit reached the pipeline: MultiResolution %v", got)
+	}
+
+	cfg.Hop = cfg.FrameSize + 1
+	if _, err := audiophash.NewPipeline(cfg); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("hop > frame size: got %v, want ErrInvalidConfig", err)
+	}
+}
+
+// TestDebugTrace checks that AUDIOPHASH_DEBUG=1 traces a hash on stderr, through the
+// optional steps too, and that nothing is printed without it.
+func TestDebugTrace(t *testing.T) {
+	const sr = 8000
+	x := genPartials(2*sr, sr, 8, 100, 3000, 5)
+	wav := encodeWAV([][]float64{x, x}, sr, 1, 16)
+	cfg := config.DefaultConfig(sr)
+	cfg.StereoBits = 2
+	cfg.FrameGateDB = 40
+	cfg.AdaptiveFraming = true
+	cfg.MinHz, cfg.MaxHz = 100, 3000
+
+	trace := func(env string) string {
+		t.Helper()
+		t.Setenv("AUDIOPHASH_DEBUG", env)
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderr := os.Stderr
+		os.Stderr = w
+		out := make(chan string)
+		go func() {
+			b, _ := io.ReadAll(r)
+			out <- string(b)
+		}()
+		_, hashErr := audiophash.AudioPHashBytes(wav, &cfg, "wav")
+		os.Stderr = stderr
+		w.Close()
+		if hashErr != nil {
+			t.Fatalf("AUDIOPHASH_DEBUG=%q: %v", env, hashErr)
+		}
+		return <-out
+	}
+	if got := trace(""); got != "" {
+		t.Errorf("debug off: printed %q", got)
+	}
+	got := trace("1")
+	for _, step := range []string{"[phash] start", "[phash] stereo", "[phash] adaptive", "[phash] gating", "[phash] band", "[phash] result"} {
+		if !strings.Contains(got, step) {
+			t.Errorf("debug trace lacks %q:\n%s", step, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/fft"
+)
+
+func TestComputePower(t *testing.T) {
+	frame := genTones(512, 8000, []float64{440, 1250}, []float64{1, 0.3})
+	mags := fft.ComputeMagnitude(frame)
+	power, err := fft.NewPlan(512).ComputePower(frame)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	for i, m := range mags {
+		if math.Abs(power[i]-m*m) > 1e-9*(1+m*m) {
+			t.Fatalf("bin %d: power %g, want %g", i, power[i], m*m)
+		}
+	}
+	if fft.ComputePower(nil) != nil {
+		t.Errorf("ComputePower(nil) != nil")
+	}
+}
+
+func TestUsePowerSpectrumHash(t *testing.T) {
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 17))
+	hashes := func(cfg config.Config) (string, string) {
+		t.Helper()
+		mag, err := audiophash.AudioPHashBytes(b, &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("magnitude: %v", err)
+		}
+		cfg.UsePowerSpectrum = true
+		pow, err := audiophash.AudioPHashBytes(b, &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("power: %v", err)
+		}
+		return mag, pow
+	}
+
+	// squaring keeps bin order, so a median threshold on a log feature barely moves
+	mag, pow := hashes(config.DefaultConfig(8000))
+	um, _ := HexToUint64(mag)
+	up, _ := HexToUint64(pow)
+	if d := HammingDistance(um, up); d > 2 {
+		t.Errorf("default config: power hash %s is %d bits from magnitude %s", pow, d, mag)
+	}
+
+	// tilt removal fits the log feature, whose shape power doubles around the offset
+	cfg := config.DefaultConfig(8000)
+	cfg.RemoveSpectralTilt = true
+	if mag, pow := hashes(cfg); mag == pow {
+		t.Errorf("removeSpectralTilt: power and magnitude hashes both %s", mag)
+	}
+}
+
+func TestPowerSpectrumDBScale(t *testing.T) {
+	mags := []float64{1, 0.1, 0, 0.5}
+	power := make([]float64, len(mags))
+	for i, m := range mags {
+		power[i] = m * m
+	}
+	want := features.ToDB(mags, 0.5)
+	for i, v := range features.PowerToDB(power, 0.5) {
+		if math.Abs(v-want[i]) > 1e-9 {
+			t.Errorf("PowerToDB[%d] = %g, want ToDB of the magnitude %g", i, v, want[i])
+		}
+	}
+
+	// the dB feature and the magnitude floor do not depend on the spectrum type
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 17))
+	cfg := config.DefaultConfig(8000)
+	cfg.DBScale = true
+	cfg.MagnitudeFloor = 0.5
+	mag, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("magnitude: %v", err)
+	}
+	cfg.UsePowerSpectrum = true
+	pow, err := audiophash.Analyze(b, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("power: %v", err)
+	}
+	floorDB := 20 * math.Log10(0.5)
+	floored := 0
+	for i := range mag.Feature {
+		if math.Abs(pow.Feature[i]-mag.Feature[i]) > 1 {
+			t.Errorf("bin %d: power %.2f dB, magnitude %.2f dB", i, pow.Feature[i], mag.Feature[i])
+		}
+		if pow.Feature[i] < floorDB-1e-9 {
+			t.Errorf("bin %d: power %.2f dB below the %.2f dB floor", i, pow.Feature[i], floorDB)
+		}
+		if math.Abs(pow.Feature[i]-floorDB) < 1e-9 {
+			floored++
+		}
+	}
+	if floored == 0 {
+		t.Errorf("no bin at the magnitude floor")
+	}
+}
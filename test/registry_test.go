@@ -0,0 +1,50 @@
+package test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestRegisterDecoder(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	samples := genPartials(8000, cfg.SampleRate, 12, 100, 3000, 5)
+
+	// a toy container: 4-byte magic followed by PCM16LE
+	audio.RegisterDecoder("test-box", audio.DecoderFunc(func(b []byte) ([]float64, int, error) {
+		if len(b) < 4 || string(b[:4]) != "BOX1" {
+			return nil, 0, errors.New("bad magic")
+		}
+		return audio.DecodePCM16LEToFloat64(b[4:])
+	}))
+
+	pcm := encodePCM16LE(samples)
+	want, err := audiophash.AudioPHashBytes(pcm, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("pcm16le: %v", err)
+	}
+	got, err := audiophash.AudioPHashBytes(append([]byte("BOX1"), pcm...), &cfg, "test-box")
+	if err != nil {
+		t.Fatalf("custom decoder: %v", err)
+	}
+	if got != want {
+		t.Errorf("custom decoder hash %s, want %s", got, want)
+	}
+	if _, err := audiophash.AudioPHashBytes(pcm, &cfg, "test-box"); !errors.Is(err, audiophash.ErrDecodeFailed) {
+		t.Errorf("bad payload: got %v, want ErrDecodeFailed", err)
+	}
+
+	formats := audiophash.SupportedFormats()
+	for _, want := range []string{"wav", "pcm16le", "test-box"} {
+		if !slices.Contains(formats, want) {
+			t.Errorf("SupportedFormats() = %v, missing %q", formats, want)
+		}
+	}
+	if !slices.IsSorted(formats) {
+		t.Errorf("SupportedFormats() = %v, want sorted", formats)
+	}
+}
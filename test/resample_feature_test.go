@@ -0,0 +1,66 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+)
+
+func TestResampleFeature(t *testing.T) {
+	ramp := []float64{0, 1, 2, 3}
+	up := features.ResampleFeature(ramp, 8)
+	want := []float64{0, 0.25, 0.75, 1.25, 1.75, 2.25, 2.75, 3}
+	for i := range want {
+		if math.Abs(up[i]-want[i]) > 1e-12 {
+			t.Fatalf("up[%d] = %g, want %g", i, up[i], want[i])
+		}
+	}
+	down := features.ResampleFeature(up, 4)
+	for i, v := range []float64{0.125, 1, 2, 2.875} {
+		if math.Abs(down[i]-v) > 1e-12 {
+			t.Fatalf("down[%d] = %g, want %g", i, down[i], v)
+		}
+	}
+}
+
+func TestAnalysisAcrossNumBins(t *testing.T) {
+	cfg := config.DefaultConfig(22050)
+	cfg.LogBands = true
+	cfg.MinHz, cfg.MaxHz = 100, 8000
+	b := encodePCM16LE(scalePeak(addWhiteNoise(genPartials(2*cfg.SampleRate, cfg.SampleRate, 40, 100, 8000, 11), 0, 3), 0.9))
+
+	analyze := func(numBins int) *audiophash.Analysis {
+		c := cfg
+		c.NumBins = numBins
+		a, err := audiophash.Analyze(b, &c, "pcm16le")
+		if err != nil {
+			t.Fatalf("analyze %d bins: %v", numBins, err)
+		}
+		return a
+	}
+	old, cur := analyze(32), analyze(64)
+	if old.NumBins != 32 || cur.NumBins != 64 {
+		t.Fatalf("NumBins = %d/%d, want 32/64", old.NumBins, cur.NumBins)
+	}
+	if s := audiophash.SimilarityScore(old, cur); s != 0 {
+		t.Errorf("mismatched NumBins scored %g, want 0", s)
+	}
+
+	migrated := cur.WithNumBins(32)
+	if migrated.NumBins != 32 || len(migrated.Feature) != 32 {
+		t.Fatalf("migrated to %d bins, feature length %d", migrated.NumBins, len(migrated.Feature))
+	}
+	if s := audiophash.SimilarityScore(old, migrated); s == 0 {
+		t.Errorf("migrated analysis still not comparable")
+	}
+	// log-scaled features: the mean of logs over two bands is close to the log of the pooled band
+	dist, _ := features.Distance(old.Feature, migrated.Feature)
+	norm, _ := features.Distance(old.Feature, make([]float64, 32))
+	t.Logf("32-bin=%s 64->32=%s relative feature distance=%.3f", old.Hash, migrated.Hash, dist/norm)
+	if dist/norm > 0.1 {
+		t.Errorf("migrated feature relative distance %.3f (> 0.1)", dist/norm)
+	}
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/fft"
+)
+
+// toneSpectrum returns the Hann-windowed magnitude spectrum of a frameSize slice
+// taken from the middle of s.
+func toneSpectrum(s []float64, frameSize int) []float64 {
+	start := (len(s) - frameSize) / 2
+	frame := make([]float64, frameSize)
+	w := audio.HannWindow(frameSize)
+	for i := range frame {
+		frame[i] = s[start+i] * w[i]
+	}
+	return fft.ComputeMagnitude(frame)
+}
+
+func TestResamplePreservesToneFrequency(t *testing.T) {
+	const frameSize = 8192
+	cases := []struct {
+		name     string
+		from, to int
+		hz       float64
+	}{
+		{"44k1_to_48k_1k", 44100, 48000, 1000},
+		{"44k1_to_48k_5k", 44100, 48000, 5000},
+		{"48k_to_44k1_3k", 48000, 44100, 3000},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			in := genTones(tc.from, tc.from, []float64{tc.hz}, []float64{0.9})
+			out, err := audio.Resample(in, tc.from, tc.to)
+			if err != nil {
+				t.Fatalf("resample: %v", err)
+			}
+			back, err := audio.Resample(out, tc.to, tc.from)
+			if err != nil {
+				t.Fatalf("resample back: %v", err)
+			}
+
+			for _, r := range []struct {
+				s  []float64
+				sr int
+			}{{out, tc.to}, {back, tc.from}} {
+				mags := toneSpectrum(r.s, frameSize)
+				binHz := float64(r.sr) / frameSize
+				peak, total, far := 0, 0.0, 0.0
+				for i, m := range mags {
+					if m > mags[peak] {
+						peak = i
+					}
+					total += m * m
+				}
+				want := tc.hz / binHz
+				for i, m := range mags {
+					if math.Abs(float64(i)-want) > 4 {
+						far += m * m
+					}
+				}
+				if math.Abs(float64(peak)-want) > 1 {
+					t.Errorf("@%dHz: peak bin %d (%.0fHz), want %.1f (%.0fHz)", r.sr, peak, float64(peak)*binHz, want, tc.hz)
+				}
+				// linear interpolation leaves images around -30dB for a 5kHz round trip
+				if ratio := far / total; ratio > 2e-3 {
+					t.Errorf("@%dHz: %.2g of energy away from the tone, want <= 2e-3", r.sr, ratio)
+				} else {
+					t.Logf("@%dHz: %.2g of energy away from the tone", r.sr, ratio)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleIntegerRatioDecimates(t *testing.T) {
+	const from, to, frameSize = 48000, 16000, 4096
+	// 2kHz stays; 10kHz is above the 8kHz output Nyquist and would alias to 6kHz
+	in := genTones(from, from, []float64{2000, 10000}, []float64{0.4, 0.4})
+
+	out, err := audio.Resample(in, from, to)
+	if err != nil {
+		t.Fatalf("resample: %v", err)
+	}
+	want := audio.Decimate(in, 3)
+	if len(out) != len(want) || len(out) != len(in)/3 {
+		t.Fatalf("len = %d, want %d", len(out), len(in)/3)
+	}
+	for i := range want {
+		if out[i] != want[i] {
+			t.Fatalf("Resample did not dispatch to Decimate: sample %d = %v, want %v", i, out[i], want[i])
+		}
+	}
+
+	mags := toneSpectrum(out, frameSize)
+	binHz := float64(to) / frameSize
+	keep, alias := mags[int(math.Round(2000/binHz))], mags[int(math.Round(6000/binHz))]
+	if ratio := 20 * math.Log10(alias/keep); ratio > -60 {
+		t.Errorf("6kHz alias at %.1f dB relative to the 2kHz tone, want <= -60 dB", ratio)
+	}
+
+	rs, err := audio.NewStreamResampler(from, to, len(in))
+	if err != nil {
+		t.Fatalf("new stream resampler: %v", err)
+	}
+	var got []float64
+	for off := 0; off < len(in); off += 777 {
+		end := off + 777
+		if end > len(in) {
+			end = len(in)
+		}
+		got = rs.Process(in[off:end], got)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("stream len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("stream sample %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResampleTapsTradeFidelity(t *testing.T) {
+	const from, to, frameSize = 48000, 16000, 4096
+	// 8.6kHz sits just above the 8kHz output Nyquist and folds to 7.4kHz
+	in := genTones(from, from, []float64{2000, 8600}, []float64{0.4, 0.4})
+
+	aliasDB := func(taps int) float64 {
+		out, err := audio.ResampleWith(in, from, to, taps)
+		if err != nil {
+			t.Fatalf("taps=%d: %v", taps, err)
+		}
+		mags := toneSpectrum(out, frameSize)
+		binHz := float64(to) / frameSize
+		return 20 * math.Log10(mags[int(math.Round(7400/binHz))]/mags[int(math.Round(2000/binHz))])
+	}
+	short, def, long := aliasDB(31), aliasDB(0), aliasDB(501)
+	t.Logf("7.4kHz alias: 31 taps %.1f dB, default %.1f dB, 501 taps %.1f dB", short, def, long)
+	if !(short > def && def > long) {
+		t.Errorf("aliasing should fall with filter length: %.1f, %.1f, %.1f dB", short, def, long)
+	}
+
+	for _, taps := range []int{-1, 64} {
+		cfg := config.DefaultConfig(16000)
+		cfg.ResampleTaps = taps
+		if err := cfg.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("taps=%d: got %v, want ErrInvalidConfig", taps, err)
+		}
+	}
+}
+
+func TestResampleLongBufferNoDrift(t *testing.T) {
+	// a ramp interpolates to exactly its source position
+	const n = 4_000_000
+	ramp := make([]float64, n)
+	for i := range ramp {
+		ramp[i] = float64(i)
+	}
+	for _, r := range []struct{ from, to int }{{44100, 48000}, {48000, 44100}, {22050, 16000}} {
+		out, err := audio.Resample(ramp, r.from, r.to)
+		if err != nil {
+			t.Fatalf("%d->%d: %v", r.from, r.to, err)
+		}
+		if want := n * r.to / r.from; len(out) != want {
+			t.Fatalf("%d->%d: %d samples, want %d", r.from, r.to, len(out), want)
+		}
+		last := len(out) - 1
+		want := float64(last) * float64(r.from) / float64(r.to)
+		if want > n-1 {
+			want = n - 1
+		}
+		if d := math.Abs(out[last] - want); d > 1e-6 {
+			t.Errorf("%d->%d: last output at source position %.9f, want %.9f", r.from, r.to, out[last], want)
+		}
+	}
+}
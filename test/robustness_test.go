@@ -0,0 +1,177 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+// genChord returns a deterministic chord of numNotes notes on the semitone grid above
+// A2 (110Hz), three harmonics each, lasting sec seconds. Each note swells and fades
+// at its own rate, stretched by tempo (tempo 1.1 plays the chord 10% slower); every
+// frequency is multiplied by pitch.
+func genChord(sr, numNotes int, sec, tempo, pitch float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	n := int(sec * tempo * float64(sr))
+	out := make([]float64, n)
+	for k := 0; k < numNotes; k++ {
+		f0 := 110 * math.Pow(2, float64(rng.Intn(36))/12) * pitch
+		rate := (0.5 + rng.Float64()) / tempo // swells per second
+		phase := rng.Float64() * 2 * math.Pi
+		amp := 0.3 + rng.Float64()
+		for h, ha := range []float64{1, 0.5, 0.25} {
+			w := 2 * math.Pi * f0 * float64(h+1) / float64(sr)
+			for i := range out {
+				env := 0.6 + 0.4*math.Sin(2*math.Pi*rate*float64(i)/float64(sr)+phase)
+				out[i] += amp * ha * env * math.Sin(w*float64(i))
+			}
+		}
+	}
+	return scalePeak(out, 0.9)
+}
+
+// TestRobustnessTimeStretchPitchShift pins the distance behaviour of each feature mode
+// under tempo, pitch and speed changes. The median feature ignores note order and
+// duration, so a tempo change barely moves any hash. Linear bins are not pitch
+// robust. Semitone-wide log bands turn a semitone pitch shift into a one-bit rotation
+// of the hash, which hash.ShiftTolerantDistance undoes.
+func TestRobustnessTimeStretchPitchShift(t *testing.T) {
+	const sr = 16000
+	semitone := math.Pow(2, 1.0/12)
+
+	orig := genChord(sr, 8, 4, 1, 1, 3)
+	// speed change: resample without pitch correction (3% faster and higher)
+	fast, err := audio.Resample(orig, sr*103/100, sr)
+	if err != nil {
+		t.Fatalf("resample: %v", err)
+	}
+	variants := map[string][]float64{
+		"stretch_10pct": genChord(sr, 8, 4, 1.1, 1, 3),      // 10% slower, same pitch
+		"pitch_up_1st":  genChord(sr, 8, 4, 1, semitone, 3), // a semitone higher, same tempo
+		"speed_3pct":    fast,
+	}
+
+	modes := map[string]func(c *config.Config){
+		"linear": func(c *config.Config) {},
+		// 64 log bands one semitone wide
+		"semitone_bands": func(c *config.Config) {
+			c.LogBands = true
+			c.NumBins = 64
+			c.MinHz = 110
+			c.MaxHz = 110 * math.Pow(2, 64.0/12)
+		},
+	}
+
+	cases := []struct {
+		mode, variant string
+		shift         int    // ShiftTolerantDistance window (0 = plain Hamming)
+		op            string // "<=" or ">="
+		bits          int
+	}{
+		{"linear", "stretch_10pct", 0, "<=", 2},
+		{"semitone_bands", "stretch_10pct", 0, "<=", 2},
+		{"linear", "pitch_up_1st", 1, ">=", 8},
+		{"semitone_bands", "pitch_up_1st", 0, ">=", 8},
+		{"semitone_bands", "pitch_up_1st", 1, "<=", 4},
+		{"linear", "speed_3pct", 0, ">=", 10},
+		{"semitone_bands", "speed_3pct", 0, "<=", 8},
+	}
+
+	hashes := map[string]uint64{}
+	hashOf := func(mode, variant string, s []float64) uint64 {
+		key := mode + "/" + variant
+		if u, ok := hashes[key]; ok {
+			return u
+		}
+		cfg := config.DefaultConfig(sr)
+		cfg.FrameSize = 2048
+		cfg.Hop = 1024
+		cfg.NumBins = 0
+		modes[mode](&cfg)
+		h, err := audiophash.AudioPHashBytes(encodePCM16LE(s), &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		u, _ := hash.HexToUint64(h)
+		hashes[key] = u
+		return u
+	}
+
+	for _, tc := range cases {
+		a := hashOf(tc.mode, "orig", orig)
+		b := hashOf(tc.mode, tc.variant, variants[tc.variant])
+		d := hash.ShiftTolerantDistance(a, b, tc.shift)
+		t.Logf("%s/%s shift=%d: %d bits", tc.mode, tc.variant, tc.shift, d)
+		if (tc.op == "<=" && d > tc.bits) || (tc.op == ">=" && d < tc.bits) {
+			t.Errorf("%s/%s shift=%d: distance %d, want %s %d", tc.mode, tc.variant, tc.shift, d, tc.op, tc.bits)
+		}
+	}
+}
+
+func TestDegenerateFramesSkipped(t *testing.T) {
+	const sr = 8000
+	s := genPartials(3*sr, sr, 12, 100, 3000, 21)
+	cfg := config.DefaultConfig(sr)
+	clean, err := audiophash.AudioPHashBytes(encodeWAV([][]float64{s}, sr, 3, 32), &cfg, "wav")
+	if err != nil {
+		t.Fatalf("clean: %v", err)
+	}
+
+	bad := append([]float64(nil), s...)
+	bad[len(bad)/2] = math.NaN()
+	got, err := audiophash.AudioPHashBytes(encodeWAV([][]float64{bad}, sr, 3, 32), &cfg, "wav")
+	if err != nil {
+		t.Fatalf("one NaN sample should only drop its frames: %v", err)
+	}
+	hc, _ := HexToUint64(clean)
+	hg, _ := HexToUint64(got)
+	if d := HammingDistance(hc, hg); d > 4 {
+		t.Errorf("distance %d after dropping the NaN frames, want <= 4", d)
+	}
+
+	for i := range bad {
+		bad[i] = math.NaN()
+	}
+	bad[0] = 0.5
+	if _, err := audiophash.AudioPHashBytes(encodeWAV([][]float64{bad}, sr, 3, 32), &cfg, "wav"); !errors.Is(err, audiophash.ErrNoValidFrames) {
+		t.Errorf("all frames NaN: err = %v, want ErrNoValidFrames", err)
+	}
+}
+
+func TestDetectClipping(t *testing.T) {
+	const sr = 8000
+	clean := genTones(sr, sr, []float64{440}, []float64{0.9})
+	if n, frac := audio.DetectClipping(clean); n != 0 || frac != 0 {
+		t.Errorf("clean sine: %d clipped (%g), want 0", n, frac)
+	}
+
+	// drive the sine 2x into a hard clip, then turn the result down
+	clipped := make([]float64, len(clean))
+	for i, v := range clean {
+		clipped[i] = 0.5 * math.Max(-0.9, math.Min(0.9, 2*v))
+	}
+	n, frac := audio.DetectClipping(clipped)
+	if frac < 0.3 || frac > 0.8 {
+		t.Errorf("2x overdriven sine: %d clipped (%g), want a large fraction", n, frac)
+	}
+
+	cfg := config.DefaultConfig(sr)
+	b := encodeWAV([][]float64{clipped}, sr, 3, 32)
+	a, err := audiophash.Analyze(b, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if a.ClippedFraction != frac || a.ClippedFraction <= audio.ClipWarnFraction {
+		t.Errorf("Analysis.ClippedFraction = %g, want %g above the warning level", a.ClippedFraction, frac)
+	}
+	cfg.MaxClippedFraction = 0.01
+	if _, err := audiophash.Analyze(b, &cfg, "wav"); !errors.Is(err, audiophash.ErrClippedAudio) {
+		t.Errorf("MaxClippedFraction: err = %v, want ErrClippedAudio", err)
+	}
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestHashSegmentsOffsets(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+	cfg.NumBins = 0
+	b := encodePCM16LE(genPartials(8000, cfg.SampleRate, 12, 100, 3000, 3))
+
+	segs, err := audiophash.HashSegments(b, &cfg, "pcm16le", 4000, 1500)
+	if err != nil {
+		t.Fatalf("segments: %v", err)
+	}
+	// starts 0, 1500, 3000 fit; 4500+4000 > 8000 does not
+	if len(segs) != 3 {
+		t.Fatalf("got %d segments, want 3", len(segs))
+	}
+	for k, s := range segs {
+		if s.Start != k*1500 || s.End != s.Start+4000 {
+			t.Errorf("segment %d: [%d, %d), want [%d, %d)", k, s.Start, s.End, k*1500, k*1500+4000)
+		}
+		if s.SourceStart != s.Start || len(s.Hash) != 16 {
+			t.Errorf("segment %d: source start %d, hash %q", k, s.SourceStart, s.Hash)
+		}
+	}
+
+	for _, tc := range []struct{ segLen, stride int }{{4000, 0}, {256, 100}} {
+		if _, err := audiophash.HashSegments(b, &cfg, "pcm16le", tc.segLen, tc.stride); !errors.Is(err, audiophash.ErrInvalidConfig) {
+			t.Errorf("segLen=%d stride=%d: got %v, want ErrInvalidConfig", tc.segLen, tc.stride, err)
+		}
+	}
+}
+
+func TestFingerprintSequence(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+	cfg.NumBins = 0
+	s := genPartials(16000, cfg.SampleRate, 12, 100, 3000, 8)
+	for i := 6000; i < 8000; i++ {
+		s[i] = 0 // window 3 is silent
+	}
+
+	seq, err := audiophash.FingerprintSequence(encodePCM16LE(s), &cfg, "pcm16le", 250)
+	if err != nil {
+		t.Fatalf("sequence: %v", err)
+	}
+	if len(seq) != 8 {
+		t.Fatalf("got %d sub-fingerprints, want 8", len(seq))
+	}
+	if seq[3] != 0 {
+		t.Errorf("silent window = %016x, want 0", seq[3])
+	}
+
+	// a clip cut on a window boundary reproduces the tail of the sequence
+	clip, err := audiophash.FingerprintSequence(encodePCM16LE(s[4000:]), &cfg, "pcm16le", 250)
+	if err != nil {
+		t.Fatalf("clip sequence: %v", err)
+	}
+	for i, h := range clip {
+		if h != seq[i+2] {
+			t.Errorf("clip[%d] = %016x, want %016x", i, h, seq[i+2])
+		}
+	}
+
+	if _, err := audiophash.FingerprintSequence(encodePCM16LE(s), &cfg, "pcm16le", 10); !errors.Is(err, audiophash.ErrInvalidConfig) {
+		t.Errorf("window shorter than a frame: got %v, want ErrInvalidConfig", err)
+	}
+}
+
+// appendCue appends a "cue " chunk with the given sample offsets to a WAV file.
+func appendCue(b []byte, offsets ...uint32) []byte {
+	chunk := make([]byte, 12+24*len(offsets))
+	copy(chunk, "cue ")
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(4+24*len(offsets)))
+	binary.LittleEndian.PutUint32(chunk[8:], uint32(len(offsets)))
+	for i, off := range offsets {
+		p := chunk[12+24*i:]
+		binary.LittleEndian.PutUint32(p, uint32(i+1))
+		binary.LittleEndian.PutUint32(p[4:], off)
+		copy(p[8:], "data")
+		binary.LittleEndian.PutUint32(p[20:], off)
+	}
+	return append(append([]byte{}, b...), chunk...)
+}
+
+func TestHashCueSegments(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+	cfg.NumBins = 0
+	s := genPartials(9000, cfg.SampleRate, 12, 100, 3000, 5)
+	// out-of-range and duplicate markers are ignored
+	b := appendCue(encodeWAV([][]float64{s}, cfg.SampleRate, 1, 16), 6000, 3000, 99999, 3000)
+
+	cues, err := audio.WAVCuePoints(b)
+	if err != nil {
+		t.Fatalf("cue points: %v", err)
+	}
+	if len(cues) != 2 || cues[0] != 3000 || cues[1] != 6000 {
+		t.Fatalf("cue points = %v, want [3000 6000]", cues)
+	}
+
+	segs, err := audiophash.HashCueSegments(b, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("cue segments: %v", err)
+	}
+	bounds := []int{0, 3000, 6000, 9000}
+	if len(segs) != 3 {
+		t.Fatalf("got %d segments, want 3", len(segs))
+	}
+	for k, seg := range segs {
+		if seg.Start != bounds[k] || seg.End != bounds[k+1] {
+			t.Errorf("segment %d: [%d, %d), want [%d, %d)", k, seg.Start, seg.End, bounds[k], bounds[k+1])
+		}
+		want, err := audiophash.AudioPHashBytes(encodePCM16LE(s[seg.Start:seg.End]), &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("segment %d reference: %v", k, err)
+		}
+		if seg.Hash != want {
+			t.Errorf("segment %d hash = %s, want %s (same as hashing the region alone)", k, seg.Hash, want)
+		}
+	}
+
+	if _, err := audiophash.HashCueSegments(encodePCM16LE(s), &cfg, "pcm16le"); !errors.Is(err, audiophash.ErrUnsupportedFormat) {
+		t.Errorf("pcm16le input: got %v, want ErrUnsupportedFormat", err)
+	}
+}
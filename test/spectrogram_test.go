@@ -0,0 +1,54 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/fft"
+)
+
+func TestHashFromSpectrogramMatchesPipeline(t *testing.T) {
+	b := encodePCM16LE(genPartials(16000, 8000, 12, 100, 3000, 4))
+	samples, _, err := audio.DecodePCM16LEToFloat64(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	for _, logBands := range []bool{false, true} {
+		cfg := config.DefaultConfig(8000)
+		cfg.FrameSize = 512
+		cfg.Hop = 256
+		cfg.NumBins = 0
+		cfg.LogBands = logBands
+
+		want, err := audiophash.AudioPHashBytes(b, &cfg, "pcm16le")
+		if err != nil {
+			t.Fatalf("logBands=%v: hash: %v", logBands, err)
+		}
+
+		frames := audio.FrameWithWindow(audio.Normalize(samples), audio.HannWindow(cfg.FrameSize), cfg.Hop)
+		spec := make([][]float64, len(frames))
+		for i, f := range frames {
+			// append a Nyquist bin, as STFT tools usually do
+			spec[i] = append(fft.ComputeMagnitude(f), 0)
+		}
+		got, err := audiophash.HashFromSpectrogram(spec, &cfg)
+		if err != nil {
+			t.Fatalf("logBands=%v: spectrogram hash: %v", logBands, err)
+		}
+		if got != want {
+			t.Errorf("logBands=%v: spectrogram hash %s, want %s", logBands, got, want)
+		}
+	}
+
+	cfg := config.DefaultConfig(8000)
+	if _, err := audiophash.HashFromSpectrogram([][]float64{make([]float64, 100)}, &cfg); !errors.Is(err, audiophash.ErrInvalidConfig) {
+		t.Errorf("short rows: got %v, want ErrInvalidConfig", err)
+	}
+	if _, err := audiophash.HashFromSpectrogram(nil, &cfg); !errors.Is(err, audiophash.ErrEmptyInput) {
+		t.Errorf("empty: got %v, want ErrEmptyInput", err)
+	}
+}
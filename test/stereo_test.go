@@ -0,0 +1,102 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestQuantizeCorrelation(t *testing.T) {
+	cases := []struct {
+		corr float64
+		mono bool
+		bits int
+		want uint64
+	}{
+		{1, true, 3, 0}, // mono is always code 0
+		{-1, false, 3, 1},
+		{1, false, 3, 7},
+		{0, false, 3, 4},
+		{0.99, false, 1, 1}, // one bit only says "stereo"
+		{0.5, false, 0, 0},
+	}
+	for _, tc := range cases {
+		if got := features.QuantizeCorrelation(tc.corr, tc.mono, tc.bits); got != tc.want {
+			t.Errorf("corr %g mono %v bits %d: code %d, want %d", tc.corr, tc.mono, tc.bits, got, tc.want)
+		}
+	}
+}
+
+func TestEmbedLowBits(t *testing.T) {
+	h := uint64(0xffffffffffffffff)
+	if got := hash.EmbedLowBits(h, 0b101, 3); got != 0xfffffffffffffffd {
+		t.Errorf("embed 3 bits: %#x", got)
+	}
+	if got := hash.EmbedLowBits(h, 0xff, 2); got != h {
+		t.Errorf("value wider than n: %#x, want only its low 2 bits used", got)
+	}
+	if got := hash.EmbedLowBits(h, 0, 0); got != h {
+		t.Errorf("n 0: %#x, want unchanged", got)
+	}
+}
+
+// TestStereoBitsHash checks where the stereo code lands and what moves it: a stereo
+// file differs from its mono fold-down only in the StereoBits low bits, swapping the
+// channels changes nothing, and raw PCM (always mono) gets the mono code 0.
+func TestStereoBitsHash(t *testing.T) {
+	const sr, bits = 8000, 3
+	cfg := config.DefaultConfig(sr)
+	cfg.StereoBits = bits
+	left := genPartials(3*sr, sr, 12, 100, 3000, 21)
+	right := addWhiteNoise(left, 6, 22) // correlated, but not a copy
+	mid := make([]float64, len(left))
+	for i := range mid {
+		mid[i] = (left[i] + right[i]) / 2
+	}
+
+	hashOf := func(b []byte, format string) uint64 {
+		t.Helper()
+		a, err := audiophash.Analyze(b, &cfg, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		for j := 0; j < bits; j++ {
+			if m := a.Margins[hash.HashBits-1-j]; m != 1 {
+				t.Errorf("%s: stereo bit %d margin %g, want 1", format, j, m)
+			}
+		}
+		u, err := hash.HexToUint64(a.Hash)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		return u
+	}
+	low := uint64(1)<<bits - 1
+
+	stereo := hashOf(encodeWAV([][]float64{left, right}, sr, 3, 32), "wav")
+	mono := hashOf(encodeWAV([][]float64{mid}, sr, 3, 32), "wav")
+	if mono&low != 0 {
+		t.Errorf("mono code %d, want 0", mono&low)
+	}
+	if stereo&low == 0 {
+		t.Error("stereo file got the mono code")
+	}
+	if stereo&^low != mono&^low {
+		t.Errorf("stereo %016x and its mono fold %016x differ outside the stereo bits", stereo, mono)
+	}
+	if d := hash.HammingDistance(stereo, mono); d == 0 || d > bits {
+		t.Errorf("stereo vs mono fold: %d bits apart, want 1..%d", d, bits)
+	}
+
+	if swapped := hashOf(encodeWAV([][]float64{right, left}, sr, 3, 32), "wav"); swapped != stereo {
+		t.Errorf("swapped channels hash %016x, want %016x", swapped, stereo)
+	}
+
+	pcm := hashOf(encodePCM16LE(mid), "pcm16le")
+	if pcm&low != 0 {
+		t.Errorf("pcm code %d, want the mono code 0", pcm&low)
+	}
+}
@@ -0,0 +1,265 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/hash"
+)
+
+func TestStreamResamplerMatchesResample(t *testing.T) {
+	in := genPartials(48000, 48000, 10, 50, 5000, 9)
+	want, err := audio.Resample(in, 48000, 44100)
+	if err != nil {
+		t.Fatalf("resample: %v", err)
+	}
+
+	rs, err := audio.NewStreamResampler(48000, 44100, len(in))
+	if err != nil {
+		t.Fatalf("new stream resampler: %v", err)
+	}
+	var got []float64
+	for off := 0; off < len(in); off += 1000 {
+		end := off + 1000
+		if end > len(in) {
+			end = len(in)
+		}
+		got = rs.Process(in[off:end], got)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAudioPHashFileStreamingMatchesInMemory(t *testing.T) {
+	const sr = 48000 // differs from the config rate so the streaming resampler is exercised
+	left := addWhiteNoise(genPartials(20*sr, sr, 20, 40, 1300, 21), 20, 1)
+	right := addWhiteNoise(genPartials(20*sr, sr, 20, 40, 1300, 22), 20, 2)
+	b := encodeWAV([][]float64{left, right}, sr, 1, 16)
+
+	path := filepath.Join(t.TempDir(), "long.wav")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cfg := config.DefaultConfig(44100)
+	want, err := audiophash.AudioPHashBytes(b, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("in-memory hash: %v", err)
+	}
+	got, err := audiophash.AudioPHashFileStreaming(path, &cfg)
+	if err != nil {
+		t.Fatalf("streaming hash: %v", err)
+	}
+
+	u1, err := hash.HexToUint64(want)
+	if err != nil {
+		t.Fatalf("parse in-memory hash: %v", err)
+	}
+	u2, err := hash.HexToUint64(got)
+	if err != nil {
+		t.Fatalf("parse streaming hash: %v", err)
+	}
+	d := hash.HammingDistance(u1, u2)
+	t.Logf("in-memory=%s streaming=%s Hamming=%d", want, got, d)
+	if d > 4 {
+		t.Fatalf("streaming hash differs by %d bits (> 4)", d)
+	}
+}
+
+func TestAudioPHashFileStreamingMemoryBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes and hashes a two-minute file")
+	}
+	const (
+		sr  = 48000
+		sec = 120
+	)
+	path := filepath.Join(t.TempDir(), "long.wav")
+	s := genTones(sec*sr, sr, []float64{220, 440, 1250}, []float64{1, 0.5, 0.25})
+	if err := os.WriteFile(path, encodeWAV([][]float64{s, s}, sr, 1, 16), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	s = nil
+
+	// collect often, so HeapAlloc tracks live memory rather than garbage
+	defer debug.SetGCPercent(debug.SetGCPercent(10))
+	runtime.GC()
+	var base runtime.MemStats
+	runtime.ReadMemStats(&base)
+
+	done := make(chan struct{})
+	peak := make(chan uint64)
+	go func() {
+		var m runtime.MemStats
+		var max uint64
+		tick := time.NewTicker(time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-done:
+				peak <- max
+				return
+			case <-tick.C:
+				runtime.ReadMemStats(&m)
+				if m.HeapAlloc > max {
+					max = m.HeapAlloc
+				}
+			}
+		}
+	}()
+	cfg := config.DefaultConfig(44100)
+	_, err := audiophash.AudioPHashFileStreaming(path, &cfg)
+	close(done)
+	growth := int64(<-peak) - int64(base.HeapAlloc)
+	if err != nil {
+		t.Fatalf("streaming hash: %v", err)
+	}
+
+	// the decoded signal alone would be sec*sr float64s; streaming holds chunks
+	decoded := int64(sec * sr * 8)
+	t.Logf("peak heap growth %d KiB, decoded signal %d KiB", growth>>10, decoded>>10)
+	if growth > decoded/4 {
+		t.Errorf("peak heap grew by %d KiB while streaming, want < %d KiB (1/4 of the decoded signal)", growth>>10, decoded>>12)
+	}
+}
+
+func TestHasherIntermediateSum(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+	cfg.NumBins = 0
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	s := genPartials(4*cfg.SampleRate, cfg.SampleRate, 12, 100, 3000, 4)
+	half := len(s) / 2
+
+	// the running sum after half the stream equals a fresh hash of that half
+	live, err := p.NewHasher()
+	if err != nil {
+		t.Fatalf("hasher: %v", err)
+	}
+	for off := 0; off < half; off += 1000 {
+		live.Write(s[off:min(off+1000, half)])
+	}
+	mid, err := live.Sum()
+	if err != nil {
+		t.Fatalf("intermediate sum: %v", err)
+	}
+	prefix, _ := p.NewHasher()
+	prefix.Write(s[:half])
+	want, err := prefix.Sum()
+	if err != nil {
+		t.Fatalf("prefix sum: %v", err)
+	}
+	if mid != want {
+		t.Errorf("intermediate sum %s, want %s", mid, want)
+	}
+
+	// Sum does not finalize: writing continues and tracks the whole stream
+	live.Write(s[half:])
+	full, err := live.Sum()
+	if err != nil {
+		t.Fatalf("final sum: %v", err)
+	}
+	batch, err := audiophash.AudioPHashBytes(encodePCM16LE(s), &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("batch hash: %v", err)
+	}
+	u1, _ := hash.HexToUint64(full)
+	u2, _ := hash.HexToUint64(batch)
+	if d := hash.HammingDistance(u1, u2); d > 4 {
+		t.Errorf("final sum %s differs from batch %s by %d bits (> 4)", full, batch, d)
+	}
+}
+
+// oneByteReader returns at most one byte per Read, like a slow pipe.
+type oneByteReader struct{ b []byte }
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.b) == 0 {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	p[0] = r.b[0]
+	r.b = r.b[1:]
+	return 1, nil
+}
+
+func TestPCM16Stream(t *testing.T) {
+	const sr = 8000
+	b := encodePCM16LE(genPartials(5*sr, sr, 12, 100, 3000, 71))
+	want, _, _ := audio.DecodePCM16LEToFloat64(b)
+
+	st := audio.NewPCM16Stream(&oneByteReader{b}, binary.LittleEndian)
+	buf := make([]float64, 1000)
+	var got []float64
+	for {
+		n, err := st.ReadMono(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("streamed %d samples differ from DecodePCM16LEToFloat64 (%d)", len(got), len(want))
+	}
+
+	st = audio.NewPCM16Stream(bytes.NewReader(b[:7]), binary.LittleEndian)
+	if n, err := st.ReadMono(buf); n != 3 || !errors.Is(err, audio.ErrTruncatedSample) {
+		t.Errorf("odd length: n=%d err=%v, want 3 samples and ErrTruncatedSample", n, err)
+	}
+
+	cfg := config.DefaultConfig(sr)
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	h, err := p.NewHasher()
+	if err != nil {
+		t.Fatalf("hasher: %v", err)
+	}
+	h.Write(want)
+	whole, err := h.Sum()
+	if err != nil {
+		t.Fatalf("hasher sum: %v", err)
+	}
+	streamed, err := p.HashReaderStreaming(bytes.NewReader(b), "pcm16le")
+	if err != nil {
+		t.Fatalf("streaming hash: %v", err)
+	}
+	if streamed != whole {
+		t.Errorf("streaming hash %s, want Hasher over the decoded samples %s", streamed, whole)
+	}
+	if _, err := p.HashReaderStreaming(bytes.NewReader(b[:len(b)-1]), "pcm16le"); !errors.Is(err, audiophash.ErrDecodeFailed) || !errors.Is(err, audio.ErrTruncatedSample) {
+		t.Errorf("odd length: err = %v, want ErrDecodeFailed wrapping ErrTruncatedSample", err)
+	}
+	if _, err := p.HashReaderStreaming(bytes.NewReader(nil), "pcm16le"); !errors.Is(err, audiophash.ErrEmptyInput) {
+		t.Errorf("empty: err = %v, want ErrEmptyInput", err)
+	}
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+)
+
+// genTones returns a mono signal of n samples at sr Hz summing sines at freqs with the given amplitudes.
+func genTones(n, sr int, freqs, amps []float64) []float64 {
+	out := make([]float64, n)
+	for k, f := range freqs {
+		w := 2 * math.Pi * f / float64(sr)
+		for i := range out {
+			out[i] += amps[k] * math.Sin(w*float64(i))
+		}
+	}
+	return out
+}
+
+// genComb returns a signal with one tone per FFT bin center below maxBin, using
+// deterministic pseudo-random amplitudes so roughly half the bins sit above the median.
+func genComb(n, sr, frameSize, maxBin int, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	freqs := make([]float64, 0, maxBin)
+	amps := make([]float64, 0, maxBin)
+	for bin := 1; bin < maxBin; bin++ {
+		freqs = append(freqs, float64(bin)*float64(sr)/float64(frameSize))
+		amps = append(amps, 0.05+rng.Float64())
+	}
+	return scalePeak(genTones(n, sr, freqs, amps), 0.9)
+}
+
+// genPartials returns a signal of count sines at deterministic pseudo-random
+// frequencies in [minHz, maxHz) and amplitudes, normalized to a 0.9 peak.
+func genPartials(n, sr, count int, minHz, maxHz float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	freqs := make([]float64, count)
+	amps := make([]float64, count)
+	for k := range freqs {
+		amps[k] = 0.05 + rng.Float64()
+		freqs[k] = minHz + rng.Float64()*(maxHz-minHz)
+	}
+	return scalePeak(genTones(n, sr, freqs, amps), 0.9)
+}
+
+// addWhiteNoise returns a copy of s with Gaussian white noise at the given SNR in dB.
+func addWhiteNoise(s []float64, snrDB float64, seed int64) []float64 {
+	var power float64
+	for _, v := range s {
+		power += v * v
+	}
+	power /= float64(len(s))
+	sigma := math.Sqrt(power / math.Pow(10, snrDB/10))
+
+	rng := rand.New(rand.NewSource(seed))
+	out := make([]float64, len(s))
+	for i, v := range s {
+		out[i] = v + sigma*rng.NormFloat64()
+	}
+	return out
+}
+
+// scalePeak scales s in place so its absolute peak equals peak.
+func scalePeak(s []float64, peak float64) []float64 {
+	var m float64
+	for _, v := range s {
+		if a := math.Abs(v); a > m {
+			m = a
+		}
+	}
+	if m == 0 {
+		return s
+	}
+	for i := range s {
+		s[i] *= peak / m
+	}
+	return s
+}
+
+// encodePCM16LE encodes samples in [-1, +1] as raw 16-bit little-endian PCM, clipping out-of-range values.
+func encodePCM16LE(s []float64) []byte {
+	b := make([]byte, 2*len(s))
+	for i, v := range s {
+		v = math.Max(-1, math.Min(1, v))
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(int16(math.Round(v*32767))))
+	}
+	return b
+}
+
+// encodeWAV builds a WAV file from per-channel samples. format is 1 (integer PCM,
+// bits 16/24/32) or 3 (IEEE float, bits 32/64).
+func encodeWAV(channels [][]float64, sr, format, bits int) []byte {
+	numCh := len(channels)
+	n := len(channels[0])
+	bytesPer := bits / 8
+	data := make([]byte, 0, n*numCh*bytesPer)
+	for i := 0; i < n; i++ {
+		for _, ch := range channels {
+			v := math.Max(-1, math.Min(1, ch[i]))
+			var buf [8]byte
+			switch {
+			case format == 3 && bits == 32:
+				binary.LittleEndian.PutUint32(buf[:], math.Float32bits(float32(v)))
+			case format == 3 && bits == 64:
+				binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+			case bits == 16:
+				binary.LittleEndian.PutUint16(buf[:], uint16(int16(math.Round(v*32767))))
+			case bits == 24:
+				x := int32(math.Round(v * 8388607))
+				buf[0], buf[1], buf[2] = byte(x), byte(x>>8), byte(x>>16)
+			case bits == 32:
+				binary.LittleEndian.PutUint32(buf[:], uint32(int32(math.Round(v*2147483647))))
+			}
+			data = append(data, buf[:bytesPer]...)
+		}
+	}
+
+	b := make([]byte, 0, 44+len(data))
+	b = append(b, "RIFF"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(36+len(data)))
+	b = append(b, "WAVEfmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 16)
+	b = binary.LittleEndian.AppendUint16(b, uint16(format))
+	b = binary.LittleEndian.AppendUint16(b, uint16(numCh))
+	b = binary.LittleEndian.AppendUint32(b, uint32(sr))
+	b = binary.LittleEndian.AppendUint32(b, uint32(sr*numCh*bytesPer))
+	b = binary.LittleEndian.AppendUint16(b, uint16(numCh*bytesPer))
+	b = binary.LittleEndian.AppendUint16(b, uint16(bits))
+	b = append(b, "data"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,616 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+)
+
+func TestDecodeWAVMultipleDataChunks(t *testing.T) {
+	b := loadFile(t, "fixtures/base/two_data_chunks.wav")
+
+	samples, sr, err := audio.DecodeWAVToFloat64(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sr != 8000 {
+		t.Fatalf("sample rate = %d, want 8000", sr)
+	}
+	// fixture holds 1000 + 600 mono samples split across two data chunks
+	if len(samples) != 1600 {
+		t.Fatalf("samples = %d, want 1600", len(samples))
+	}
+}
+
+func TestDecodeWAVFloat64(t *testing.T) {
+	want := genTones(1000, 48000, []float64{1000}, []float64{0.123456789})
+	b := encodeWAV([][]float64{want}, 48000, 3, 64)
+
+	got, sr, err := audio.DecodeWAVToFloat64(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sr != 48000 || len(got) != len(want) {
+		t.Fatalf("sr=%d samples=%d, want 48000/%d", sr, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %v, want %v (double samples must round-trip exactly)", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecodeWAVFmtAfterData(t *testing.T) {
+	want := genTones(1000, 8000, []float64{440}, []float64{0.5})
+	b := encodeWAV([][]float64{want}, 8000, 1, 16)
+
+	// move the 24-byte fmt chunk behind the data chunk
+	header, fmtChunk, data := b[:12], b[12:36], b[36:]
+	reordered := append(append([]byte{}, header...), data...)
+	reordered = append(reordered, fmtChunk...)
+
+	got, sr, err := audio.DecodeWAVToFloat64(reordered)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sr != 8000 || len(got) != len(want) {
+		t.Fatalf("sr=%d samples=%d, want 8000/%d", sr, len(got), len(want))
+	}
+}
+
+// riffChunk returns a RIFF chunk with the given ID and payload, plus the pad
+// byte an odd-sized payload needs.
+func riffChunk(id string, payload []byte) []byte {
+	chunk := make([]byte, 8, 8+len(payload)+1)
+	copy(chunk, id)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func TestDecodeWAVBroadcastChunks(t *testing.T) {
+	want := genTones(4000, 8000, []float64{440, 1250}, []float64{0.4, 0.3})
+	b := encodeWAV([][]float64{want}, 8000, 1, 16)
+
+	// BWF layout: bext (602-byte fixed part plus an odd-length coding history) and
+	// odd-sized JUNK padding ahead of fmt and data, each followed by a pad byte
+	bext := make([]byte, 602, 602+17)
+	copy(bext, "audiophash test")
+	bext = append(bext, "A=PCM,F=8000,M=mo"...)
+	bwf := append([]byte{}, b[:12]...)
+	bwf = append(bwf, riffChunk("bext", bext)...)
+	bwf = append(bwf, riffChunk("JUNK", make([]byte, 27))...)
+	bwf = append(bwf, b[12:]...)
+	binary.LittleEndian.PutUint32(bwf[4:], uint32(len(bwf)-8))
+
+	got, sr, err := audio.DecodeWAVToFloat64(bwf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	plain, _, err := audio.DecodeWAVToFloat64(b)
+	if err != nil {
+		t.Fatalf("decode plain: %v", err)
+	}
+	if sr != 8000 || len(got) != len(plain) {
+		t.Fatalf("sr=%d samples=%d, want 8000/%d", sr, len(got), len(plain))
+	}
+	for i := range plain {
+		if got[i] != plain[i] {
+			t.Fatalf("sample %d = %v, want %v", i, got[i], plain[i])
+		}
+	}
+
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+	h1, err := audiophash.AudioPHashBytes(bwf, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("hash bwf: %v", err)
+	}
+	h2, err := audiophash.AudioPHashBytes(b, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("hash plain: %v", err)
+	}
+	if h1 != h2 {
+		t.Fatalf("BWF hash %s != plain hash %s", h1, h2)
+	}
+}
+
+func TestDecodeWAVExtensibleValidBits(t *testing.T) {
+	// 12-bit samples in 16-bit containers, MSB-aligned as the spec requires
+	raw := []int16{0, 1024, -2048, 2047}
+	data := make([]byte, 0, 2*len(raw))
+	for _, v := range raw {
+		data = binary.LittleEndian.AppendUint16(data, uint16(v<<4))
+	}
+
+	b := []byte("RIFF")
+	b = binary.LittleEndian.AppendUint32(b, uint32(4+8+40+8+len(data)))
+	b = append(b, "WAVEfmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 40)
+	b = binary.LittleEndian.AppendUint16(b, 0xFFFE) // WAVE_FORMAT_EXTENSIBLE
+	b = binary.LittleEndian.AppendUint16(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, 8000)
+	b = binary.LittleEndian.AppendUint32(b, 16000)
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = binary.LittleEndian.AppendUint16(b, 16)
+	b = binary.LittleEndian.AppendUint16(b, 22) // cbSize
+	b = binary.LittleEndian.AppendUint16(b, 12) // validBitsPerSample
+	b = binary.LittleEndian.AppendUint32(b, 4)  // channel mask: front centre
+	// KSDATAFORMAT_SUBTYPE_PCM
+	b = append(b, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71)
+	b = append(b, "data"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	b = append(b, data...)
+
+	got, sr, err := audio.DecodeWAVToFloat64(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sr != 8000 {
+		t.Fatalf("sample rate = %d, want 8000", sr)
+	}
+	for i, want := range []float64{0, 0.5, -1, 2047.0 / 2048} {
+		if got[i] != want {
+			t.Errorf("sample %d = %v, want %v (12-bit value / 2^11)", i, got[i], want)
+		}
+	}
+}
+
+func TestDecodeWAVValid20In24(t *testing.T) {
+	// 440Hz at half scale, 20 valid bits MSB-aligned in 24-bit containers
+	b := loadFile(t, "fixtures/base/valid20in24.wav")
+	got, sr, err := audio.DecodeWAVToFloat64(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sr != 8000 || len(got) != 2000 {
+		t.Fatalf("decoded %d samples at %dHz, want 2000 at 8000Hz", len(got), sr)
+	}
+	var peak float64
+	for i, v := range got {
+		want := math.Round(0.5*math.Sin(2*math.Pi*440*float64(i)/8000)*(1<<19)) / (1 << 19)
+		if v != want {
+			t.Fatalf("sample %d = %v, want %v", i, v, want)
+		}
+		peak = math.Max(peak, math.Abs(v))
+	}
+	if peak < 0.49 || peak > 0.5 {
+		t.Errorf("peak %g, want about 0.5", peak)
+	}
+	info, err := audio.ScanWAV(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("info: %v", err)
+	}
+	if bits := info.AudioInfo().Bits; bits != 20 {
+		t.Errorf("Bits = %d, want 20", bits)
+	}
+}
+
+func TestChannelSelect(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	left := genPartials(8000, 8000, 12, 100, 3000, 1)
+	right := genPartials(8000, 8000, 12, 100, 3000, 2)
+	stereo := encodeWAV([][]float64{left, right}, 8000, 1, 16)
+
+	got, _, err := audio.DecodeWAVChannel(stereo, 2)
+	if err != nil {
+		t.Fatalf("decode right: %v", err)
+	}
+	want, _, _ := audio.DecodeWAVToFloat64(encodeWAV([][]float64{right}, 8000, 1, 16))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %v, want right channel %v", i, got[i], want[i])
+		}
+	}
+	if _, _, err := audio.DecodeWAVChannel(stereo, 3); !errors.Is(err, audio.ErrChannelOutOfRange) {
+		t.Errorf("channel 3 of 2: got %v, want ErrChannelOutOfRange", err)
+	}
+	down, _, err := audio.DecodeWAVChannel(stereo, audio.ChannelDownmix)
+	if err != nil {
+		t.Fatalf("decode downmix: %v", err)
+	}
+	mixed, _, err := audio.DecodeWAVToFloat64(stereo)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(down, mixed) {
+		t.Error("ChannelDownmix differs from DecodeWAVToFloat64")
+	}
+
+	cfg.Channel = 1
+	h, err := audiophash.AudioPHashBytes(stereo, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("hash left: %v", err)
+	}
+	cfg.Channel = 0
+	hLeft, _ := audiophash.AudioPHashBytes(encodeWAV([][]float64{left}, 8000, 1, 16), &cfg, "wav")
+	if h != hLeft {
+		t.Errorf("Channel=1 hash %s, want left-only %s", h, hLeft)
+	}
+	cfg.Channel = 3
+	if _, err := audiophash.AudioPHashBytes(stereo, &cfg, "wav"); !errors.Is(err, audiophash.ErrDecodeFailed) || !errors.Is(err, audio.ErrChannelOutOfRange) {
+		t.Errorf("Channel=3: got %v, want ErrDecodeFailed wrapping ErrChannelOutOfRange", err)
+	}
+}
+
+func TestDecodeFloat32LE(t *testing.T) {
+	want := genPartials(8000, 8000, 12, 100, 3000, 6)
+	b := make([]byte, 0, 4*len(want))
+	for _, v := range want {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(v)))
+	}
+	got, sr, err := audio.DecodeFloat32LEToFloat64(b)
+	if err != nil || sr != 0 || len(got) != len(want) {
+		t.Fatalf("decode: %d samples, sr=%d, err=%v", len(got), sr, err)
+	}
+	for i := range want {
+		if got[i] != float64(float32(want[i])) {
+			t.Fatalf("sample %d = %v, want %v", i, got[i], float32(want[i]))
+		}
+	}
+	if _, _, err := audio.DecodeFloat32LEToFloat64(b[:len(b)-1]); err == nil {
+		t.Error("odd length: want error")
+	}
+
+	cfg := config.DefaultConfig(8000)
+	if _, err := audiophash.AudioPHashBytes(b, &cfg, "f32le"); err != nil {
+		t.Errorf("f32le hash: %v", err)
+	}
+}
+
+func TestSpeakerSelectCenter(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	var chans [][]float64
+	for seed := int64(1); seed <= 6; seed++ {
+		chans = append(chans, genPartials(8000, 8000, 12, 100, 3000, seed))
+	}
+	b := encodeWAV(chans, 8000, 1, 16)
+
+	cfg.Speaker = "FC"
+	h, err := audiophash.AudioPHashBytes(b, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("hash FC: %v", err)
+	}
+	cfg.Speaker = ""
+	want, _ := audiophash.AudioPHashBytes(encodeWAV(chans[2:3], 8000, 1, 16), &cfg, "wav")
+	if h != want {
+		t.Errorf("Speaker=FC hash %s, want channel 3 only %s", h, want)
+	}
+
+	// 5.1 (side) layout: FL FR FC LFE SL SR
+	info := &audio.WAVInfo{NumChannels: 6, ChannelMask: 0x60F}
+	if got, err := info.AudioInfo().SpeakerChannel(audio.SpeakerSideRight); err != nil || got != 6 {
+		t.Errorf("AudioInfo side right: channel %d, err %v, want 6", got, err)
+	}
+	for _, tc := range []struct {
+		bit  uint32
+		want int
+	}{{audio.SpeakerFrontCenter, 3}, {audio.SpeakerSideRight, 6}} {
+		if got, err := info.SpeakerChannel(tc.bit); err != nil || got != tc.want {
+			t.Errorf("speaker %#x: channel %d, err %v, want %d", tc.bit, got, err, tc.want)
+		}
+	}
+	if _, err := info.SpeakerChannel(audio.SpeakerBackLeft); !errors.Is(err, audio.ErrChannelOutOfRange) {
+		t.Errorf("missing speaker: got %v, want ErrChannelOutOfRange", err)
+	}
+
+	cfg.Speaker = "FC"
+	mono := encodeWAV(chans[:1], 8000, 1, 16)
+	if _, err := audiophash.AudioPHashBytes(mono, &cfg, "wav"); !errors.Is(err, audio.ErrChannelOutOfRange) {
+		t.Errorf("FC of mono: got %v, want ErrChannelOutOfRange", err)
+	}
+	cfg.Speaker = "XX"
+	if _, err := audiophash.AudioPHashBytes(b, &cfg, "wav"); !errors.Is(err, audiophash.ErrInvalidConfig) {
+		t.Errorf("unknown speaker: got %v, want ErrInvalidConfig", err)
+	}
+	if err := cfg.ValidateAndFill(); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("unknown speaker: ValidateAndFill got %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestDecodeWAVPlanar(t *testing.T) {
+	left := genPartials(8000, 8000, 12, 100, 3000, 1)
+	right := genPartials(8000, 8000, 12, 100, 3000, 2)
+
+	// a planar stereo file is the mono file of left then right, relabelled as 2 channels
+	b := encodeWAV([][]float64{append(append([]float64{}, left...), right...)}, 8000, 1, 16)
+	binary.LittleEndian.PutUint16(b[22:], 2)        // channels
+	binary.LittleEndian.PutUint32(b[28:], 8000*2*2) // byte rate
+	binary.LittleEndian.PutUint16(b[32:], 2*2)      // block align
+
+	channels, _, err := audio.DecodeWAVPlanarChannels(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want, _, _ := audio.DecodeWAVChannels(encodeWAV([][]float64{left, right}, 8000, 1, 16))
+	for ch := range want {
+		for i := range want[ch] {
+			if channels[ch][i] != want[ch][i] {
+				t.Fatalf("channel %d sample %d = %v, want %v", ch, i, channels[ch][i], want[ch][i])
+			}
+		}
+	}
+
+	cfg := config.DefaultConfig(8000)
+	wantHash, _ := audiophash.AudioPHashBytes(encodeWAV([][]float64{left, right}, 8000, 1, 16), &cfg, "wav")
+	cfg.PlanarWAV = true
+	got, err := audiophash.AudioPHashBytes(b, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if got != wantHash {
+		t.Errorf("planar hash %s, want interleaved %s", got, wantHash)
+	}
+}
+
+func TestDecodeWAVWithInfo(t *testing.T) {
+	left := genTones(24000, 48000, []float64{440}, []float64{0.5})
+	right := genTones(24000, 48000, []float64{660}, []float64{0.5})
+	b := encodeWAV([][]float64{left, right}, 48000, 1, 24)
+
+	samples, info, err := audio.DecodeWAVWithInfo(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := audio.AudioInfo{Channels: 2, Bits: 24, SampleRate: 48000, DurationSamples: 24000}
+	if info != want || len(samples) != 24000 {
+		t.Fatalf("info = %+v, %d samples; want %+v", info, len(samples), want)
+	}
+	if d := info.Duration(); d != 500*time.Millisecond {
+		t.Errorf("duration = %v, want 500ms", d)
+	}
+
+	cfg := config.DefaultConfig(16000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+	a, err := audiophash.Analyze(b, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if a.Input != want {
+		t.Errorf("Analysis.Input = %+v, want %+v", a.Input, want)
+	}
+	a, err = audiophash.Analyze(encodePCM16LE(left), &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze pcm: %v", err)
+	}
+	if want := (audio.AudioInfo{Channels: 1, DurationSamples: 24000}); a.Input != want {
+		t.Errorf("raw PCM Analysis.Input = %+v, want %+v", a.Input, want)
+	}
+}
+
+func TestSampleChecksum(t *testing.T) {
+	samples := genPartials(8000, 8000, 12, 100, 3000, 8)
+	scalePeak(samples, 0.5)
+	cfg := config.DefaultConfig(8000)
+	cfg.FrameSize = 512
+	cfg.Hop = 256
+
+	wav, err := audiophash.Analyze(encodeWAV([][]float64{samples}, 8000, 1, 16), &cfg, "wav")
+	if err != nil {
+		t.Fatalf("analyze wav: %v", err)
+	}
+	pcm := encodePCM16LE(samples)
+	raw, err := audiophash.Analyze(pcm, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze pcm: %v", err)
+	}
+	if wav.Checksum != raw.Checksum {
+		t.Errorf("same PCM in WAV and raw: checksums %x != %x", wav.Checksum, raw.Checksum)
+	}
+
+	// one LSB in one sample: perceptually identical, not byte-identical
+	pcm[1000] ^= 1
+	nudged, err := audiophash.Analyze(pcm, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("analyze nudged: %v", err)
+	}
+	if nudged.Checksum == raw.Checksum {
+		t.Error("one-LSB change kept the checksum")
+	}
+	if nudged.Hash != raw.Hash {
+		t.Errorf("one-LSB change moved the hash: %s vs %s", nudged.Hash, raw.Hash)
+	}
+}
+
+func TestDecodeWAVUnsupportedFormatErrors(t *testing.T) {
+	b := encodeWAV([][]float64{genTones(1000, 8000, []float64{440}, []float64{0.5})}, 8000, 1, 16)
+
+	eight := append([]byte{}, b...)
+	binary.LittleEndian.PutUint16(eight[34:], 8) // bitsPerSample
+	_, _, err := audio.DecodeWAVToFloat64(eight)
+	if !errors.Is(err, audio.ErrUnsupportedBitDepth) || !strings.Contains(err.Error(), "bitsPerSample 8") {
+		t.Errorf("8-bit WAV: err = %v, want ErrUnsupportedBitDepth naming 8", err)
+	}
+	cfg := config.DefaultConfig(8000)
+	if _, err := audiophash.AudioPHashBytes(eight, &cfg, "wav"); !errors.Is(err, audiophash.ErrDecodeFailed) || !errors.Is(err, audio.ErrUnsupportedBitDepth) {
+		t.Errorf("pipeline: err = %v, want ErrDecodeFailed and ErrUnsupportedBitDepth", err)
+	}
+
+	adpcm := append([]byte{}, b...)
+	binary.LittleEndian.PutUint16(adpcm[20:], 2) // audioFormat: MS ADPCM
+	_, _, err = audio.DecodeWAVToFloat64(adpcm)
+	if !errors.Is(err, audio.ErrUnsupportedWAVFormat) || !strings.Contains(err.Error(), "0x2") {
+		t.Errorf("ADPCM WAV: err = %v, want ErrUnsupportedWAVFormat naming 0x2", err)
+	}
+}
+
+func TestDecodePCM24LE(t *testing.T) {
+	wav := encodeWAV([][]float64{genPartials(8000, 8000, 12, 100, 3000, 8)}, 8000, 1, 24)
+	raw := wav[44:] // strip the canonical header
+
+	want, _, err := audio.DecodeWAVToFloat64(wav)
+	if err != nil {
+		t.Fatalf("wav decode: %v", err)
+	}
+	got, sr, err := audio.DecodePCM24LEToFloat64(raw)
+	if err != nil || sr != 0 || len(got) != len(want) {
+		t.Fatalf("decode: %d samples, sr=%d, err=%v", len(got), sr, err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %v, want %v (same as the WAV path)", i, got[i], want[i])
+		}
+	}
+	if s, _, _ := audio.DecodePCM24LEToFloat64([]byte{0x00, 0x00, 0x80}); s[0] != -1 {
+		t.Errorf("0x800000 = %v, want -1", s[0])
+	}
+	if _, _, err := audio.DecodePCM24LEToFloat64(raw[:len(raw)-1]); err == nil {
+		t.Error("length not a multiple of 3: want error")
+	}
+
+	cfg := config.DefaultConfig(8000)
+	hw, err := audiophash.AudioPHashBytes(wav, &cfg, "wav")
+	if err != nil {
+		t.Fatalf("wav hash: %v", err)
+	}
+	if hr, err := audiophash.AudioPHashBytes(raw, &cfg, "pcm24le"); err != nil || hr != hw {
+		t.Errorf("pcm24le hash %s (err %v), want wav hash %s", hr, err, hw)
+	}
+}
+
+func TestDecodePCM16BE(t *testing.T) {
+	got, sr, err := audio.DecodePCM16BEToFloat64([]byte{0x40, 0x00, 0x80, 0x00, 0xff, 0xff})
+	if err != nil || sr != 0 {
+		t.Fatalf("decode: sr=%d err=%v", sr, err)
+	}
+	if want := []float64{0.5, -1, -1.0 / 32768}; !reflect.DeepEqual(got, want) {
+		t.Errorf("samples %v, want %v", got, want)
+	}
+	if _, _, err := audio.DecodePCM16BEToFloat64([]byte{0x40, 0x00, 0x80}); err == nil {
+		t.Error("odd length: want error")
+	}
+
+	le := encodePCM16LE(genPartials(8000, 8000, 12, 100, 3000, 9))
+	be := make([]byte, len(le))
+	for i := 0; i < len(le); i += 2 {
+		be[i], be[i+1] = le[i+1], le[i]
+	}
+	cfg := config.DefaultConfig(8000)
+	hl, err := audiophash.AudioPHashBytes(le, &cfg, "pcm16le")
+	if err != nil {
+		t.Fatalf("pcm16le hash: %v", err)
+	}
+	if hb, err := audiophash.AudioPHashBytes(be, &cfg, "pcm16be"); err != nil || hb != hl {
+		t.Errorf("pcm16be hash %s (err %v), want the pcm16le hash %s", hb, err, hl)
+	}
+	p, err := audiophash.NewPipeline(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hs, err := p.HashReaderStreaming(bytes.NewReader(be), "pcm16be"); err != nil || hs != hl {
+		t.Errorf("streamed pcm16be hash %s (err %v), want %s", hs, err, hl)
+	}
+	// the wrong byte order decodes to noise
+	if hw, _ := audiophash.AudioPHashBytes(le, &cfg, "pcm16be"); hw == hl {
+		t.Error("little-endian bytes read as pcm16be hash like the original")
+	}
+}
+
+func TestDecodeWAVFmtCbSize(t *testing.T) {
+	b := loadFile(t, "fixtures/base/fmt18.wav") // 18-byte fmt chunk, cbSize 0
+
+	samples, sr, err := audio.DecodeWAVToFloat64(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sr != 8000 || len(samples) != 1000 {
+		t.Fatalf("sr=%d samples=%d, want 8000/1000", sr, len(samples))
+	}
+	if samples[0] != 0 || math.Abs(samples[5]-0.5*math.Sin(2*math.Pi*440*5/8000)) > 1e-4 {
+		t.Errorf("samples misaligned: %v", samples[:6])
+	}
+
+	// cbSize claiming more extension bytes than the chunk holds
+	bad := append([]byte(nil), b...)
+	binary.LittleEndian.PutUint16(bad[36:], 4)
+	if _, _, err := audio.DecodeWAVToFloat64(bad); err == nil || !strings.Contains(err.Error(), "cbSize") {
+		t.Errorf("oversized cbSize: err = %v, want cbSize error", err)
+	}
+}
+
+func TestMonoCompatibility(t *testing.T) {
+	const sr = 8000
+	l := genPartials(2*sr, sr, 12, 100, 3000, 51)
+	inv := make([]float64, len(l))
+	for i, v := range l {
+		inv[i] = -v
+	}
+	other := genPartials(2*sr, sr, 12, 100, 3000, 52)
+
+	cfg := config.DefaultConfig(sr)
+	cases := []struct {
+		name     string
+		channels [][]float64
+		lo, hi   float64
+	}{
+		{"identical", [][]float64{l, l}, 1, 1},
+		{"unrelated", [][]float64{l, other}, 0.35, 0.65},
+		{"inverted", [][]float64{l, inv}, 0, 0},
+	}
+	for _, tc := range cases {
+		if got := audio.MonoCompatibility(tc.channels); got < tc.lo-1e-9 || got > tc.hi+1e-9 {
+			t.Errorf("%s: MonoCompatibility = %g, want in [%g, %g]", tc.name, got, tc.lo, tc.hi)
+		}
+	}
+
+	// half-cancelling stereo: R = L inverted at half level keeps (0.5^2)/(2*1.25) = 0.1
+	half := make([]float64, len(l))
+	for i, v := range l {
+		half[i] = -0.5 * v
+	}
+	a, err := audiophash.Analyze(encodeWAV([][]float64{l, half}, sr, 3, 32), &cfg, "wav")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if math.Abs(a.MonoCompatibility-0.1) > 1e-3 {
+		t.Errorf("Analysis.MonoCompatibility = %g, want 0.1", a.MonoCompatibility)
+	}
+	m, err := audiophash.Analyze(encodePCM16LE(l), &cfg, "pcm16le")
+	if err != nil || m.MonoCompatibility != 1 {
+		t.Errorf("mono input: MonoCompatibility = %g (err %v), want 1", m.MonoCompatibility, err)
+	}
+}
+
+func TestDownmixEnergyLevel(t *testing.T) {
+	l := genTones(4000, 8000, []float64{440}, []float64{0.5})
+	r := genTones(4000, 8000, []float64{660}, []float64{0.5})
+	wav := encodeWAV([][]float64{l, r}, 8000, 1, 16)
+	avg, _, err := audio.DecodeWAVToFloat64Mode(wav, audio.DownmixAverage)
+	if err != nil {
+		t.Fatalf("average: %v", err)
+	}
+	energy, _, err := audio.DecodeWAVToFloat64Mode(wav, audio.DownmixEnergy)
+	if err != nil {
+		t.Fatalf("energy: %v", err)
+	}
+	// uncorrelated channels: energy keeps the per-channel RMS, average loses 3dB
+	rms := func(s []float64) float64 {
+		var e float64
+		for _, v := range s {
+			e += v * v
+		}
+		return math.Sqrt(e / float64(len(s)))
+	}
+	if got, want := rms(energy), rms(l); math.Abs(got-want) > 0.01*want {
+		t.Errorf("energy downmix RMS %g, want channel RMS %g", got, want)
+	}
+	for i := range avg {
+		if math.Abs(energy[i]-math.Sqrt2*avg[i]) > 1e-12 {
+			t.Fatalf("sample %d: energy %g, want sqrt(2) * average %g", i, energy[i], avg[i])
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ast-jean/audiophash/cmd/audiophash"
+	"github.com/ast-jean/audiophash/pkg/audio"
+	"github.com/ast-jean/audiophash/pkg/config"
+	"github.com/ast-jean/audiophash/pkg/features"
+	"github.com/ast-jean/audiophash/pkg/fft"
+)
+
+// leakageRatio returns the fraction of feature energy more than guard bins away from every tone bin.
+func leakageRatio(feature []float64, toneBins []float64, guard float64) float64 {
+	var total, far float64
+	for i, v := range feature {
+		e := v * v
+		total += e
+		near := false
+		for _, tb := range toneBins {
+			if math.Abs(float64(i)-tb) <= guard {
+				near = true
+			}
+		}
+		if !near {
+			far += e
+		}
+	}
+	return far / total
+}
+
+func TestBlackmanHarrisConcentratesTwoTones(t *testing.T) {
+	const (
+		sr        = 44100
+		frameSize = 2048
+		hop       = 1024
+		numBins   = 64
+	)
+	// two closely spaced partials, deliberately off bin centres
+	freqs := []float64{440, 523.25}
+	signal := genTones(3*sr, sr, freqs, []float64{1, 0.5})
+	toneBins := []float64{freqs[0] * frameSize / sr, freqs[1] * frameSize / sr}
+
+	feature := func(window []float64) []float64 {
+		frames := audio.FrameWithWindow(signal, window, hop)
+		mags := make([][]float64, len(frames))
+		for i, f := range frames {
+			mags[i] = fft.ComputeMagnitude(f)
+		}
+		return features.AggregateGlobalFeatureMedian(mags, numBins)
+	}
+
+	hann := leakageRatio(feature(audio.HannWindow(frameSize)), toneBins, 5)
+	bh := leakageRatio(feature(audio.BlackmanHarrisWindow(frameSize)), toneBins, 5)
+	t.Logf("energy outside ±5 bins of the tones: hann=%.3g blackman-harris=%.3g", hann, bh)
+	if bh >= hann {
+		t.Fatalf("blackman-harris leakage %.3g not below hann %.3g", bh, hann)
+	}
+}
+
+func TestKaiserWindow(t *testing.T) {
+	for i, v := range audio.KaiserWindow(16, 0) {
+		if math.Abs(v-1) > 1e-12 {
+			t.Fatalf("beta=0 coefficient %d = %g, want 1 (rectangular)", i, v)
+		}
+	}
+
+	w := audio.KaiserWindow(65, 8.6)
+	if math.Abs(w[32]-1) > 1e-12 {
+		t.Errorf("centre = %g, want 1", w[32])
+	}
+	// I0(8.6) ~= 750.46
+	if edge := 1 / w[0]; math.Abs(edge-750.46) > 0.01 {
+		t.Errorf("1/edge = %g, want I0(8.6) ~= 750.46", edge)
+	}
+	for i := range w {
+		if math.Abs(w[i]-w[len(w)-1-i]) > 1e-12 {
+			t.Fatalf("not symmetric at %d", i)
+		}
+	}
+}
+
+func TestWindowGainCompensationMatchesRectangular(t *testing.T) {
+	const (
+		sr        = 8000
+		frameSize = 512
+		bin       = 20
+	)
+	b := encodePCM16LE(genTones(sr, sr, []float64{bin * sr / frameSize}, []float64{0.9}))
+
+	toneMag := func(window string) float64 {
+		cfg := config.DefaultConfig(sr)
+		cfg.KaiserBeta = 1e-9 // "kaiser" with beta near 0 is rectangular
+		cfg.FrameSize = frameSize
+		cfg.Hop = frameSize / 2
+		cfg.NumBins = 0
+		cfg.Window = window
+		cfg.WindowGainCompensation = true
+		p, err := audiophash.NewPipeline(cfg)
+		if err != nil {
+			t.Fatalf("%s: pipeline: %v", window, err)
+		}
+		a, err := p.AnalyzeFrames(b, "pcm16le")
+		if err != nil {
+			t.Fatalf("%s: analyze: %v", window, err)
+		}
+		return a.Frames[len(a.Frames)/2][bin-1] // bin 0 (DC) is not hashed
+	}
+
+	hann, rect := toneMag("hann"), toneMag("kaiser")
+	t.Logf("compensated tone magnitude: hann=%.5f rectangular=%.5f", hann, rect)
+	// peak normalization scales the tone to about amplitude 1, which reads 1/2 in its
+	// bin; uncompensated, Hann would read half of rectangular
+	if math.Abs(hann-rect) > 5e-3*rect || math.Abs(rect-0.5) > 1e-2 {
+		t.Errorf("hann=%.5f rectangular=%.5f, want both 0.5", hann, rect)
+	}
+}
+
+func TestWindowCoefficients(t *testing.T) {
+	const n = 64
+	formulas := map[audio.WindowType]func(x float64) float64{
+		audio.WindowHann:    func(x float64) float64 { return 0.5 - 0.5*math.Cos(x) },
+		audio.WindowHamming: func(x float64) float64 { return 0.54 - 0.46*math.Cos(x) },
+		audio.WindowBlackmanHarris: func(x float64) float64 {
+			return 0.35875 - 0.48829*math.Cos(x) + 0.14128*math.Cos(2*x) - 0.01168*math.Cos(3*x)
+		},
+	}
+	for kind, f := range formulas {
+		w := audio.WindowCoefficients(kind, n, 0)
+		if len(w) != n {
+			t.Fatalf("%s: %d coefficients, want %d", kind, len(w), n)
+		}
+		for i, v := range w {
+			if want := f(2 * math.Pi * float64(i) / (n - 1)); math.Abs(v-want) > 1e-12 {
+				t.Fatalf("%s[%d] = %g, want %g", kind, i, v, want)
+			}
+		}
+	}
+	if w := audio.WindowCoefficients("triangle", n, 0); w != nil {
+		t.Errorf("unknown window returned %d coefficients", len(w))
+	}
+	for _, beta := range []float64{0, 5, audio.DefaultKaiserBeta} {
+		w := audio.WindowCoefficients(audio.WindowKaiser, n, beta)
+		want := audio.KaiserWindow(n, beta)
+		if !reflect.DeepEqual(w, want) {
+			t.Errorf("kaiser beta %g: coefficients differ from KaiserWindow", beta)
+		}
+	}
+}
+
+func TestConstantOverlapHop(t *testing.T) {
+	const size = 1024
+	for _, kind := range []audio.WindowType{audio.WindowHann, audio.WindowHamming, audio.WindowBlackmanHarris} {
+		hop, gain, ok := audio.ConstantOverlapHop(kind, size)
+		if !ok {
+			t.Fatalf("%s: no COLA hop", kind)
+		}
+		w := audio.WindowCoefficients(kind, size, 0)
+		frames := make([][]float64, 16)
+		for i := range frames {
+			frames[i] = w
+		}
+		out := audio.OverlapAdd(frames, hop)
+		// fully covered interior only
+		for i := size; i < len(out)-size; i++ {
+			if math.Abs(out[i]-gain) > 0.01*gain {
+				t.Fatalf("%s hop %d: sum[%d] = %g, want %g", kind, hop, i, out[i], gain)
+			}
+		}
+	}
+	if _, _, ok := audio.ConstantOverlapHop(audio.WindowKaiser, size); ok {
+		t.Error("kaiser reported a COLA hop")
+	}
+}
+
+func TestKaiserBetaDefault(t *testing.T) {
+	cfg := config.DefaultConfig(8000)
+	cfg.Window = audio.WindowKaiser
+	if err := cfg.ValidateAndFill(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if cfg.KaiserBeta != audio.DefaultKaiserBeta {
+		t.Errorf("KaiserBeta filled to %g, want %g", cfg.KaiserBeta, audio.DefaultKaiserBeta)
+	}
+
+	b := encodePCM16LE(genPartials(3*8000, 8000, 12, 100, 3000, 8))
+	hashWith := func(beta float64) string {
+		c := config.DefaultConfig(8000)
+		c.Window = audio.WindowKaiser
+		c.KaiserBeta = beta
+		h, err := audiophash.AudioPHashBytes(b, &c, "pcm16le")
+		if err != nil {
+			t.Fatalf("beta %g: %v", beta, err)
+		}
+		return h
+	}
+	if h0, h := hashWith(0), hashWith(audio.DefaultKaiserBeta); h0 != h {
+		t.Errorf("beta 0 hash %s, default beta hash %s", h0, h)
+	}
+}